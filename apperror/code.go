@@ -0,0 +1,132 @@
+package apperror
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Code is a stable, machine readable error code shared by the gRPC and REST
+// APIs. Codes are part of the API contract: add new ones, never rename.
+type Code string
+
+const (
+	CodeInvalidArgument      Code = "INVALID_ARGUMENT"
+	CodeUnauthenticated      Code = "UNAUTHENTICATED"
+	CodePermissionDenied     Code = "PERMISSION_DENIED"
+	CodeNotFound             Code = "NOT_FOUND"
+	CodeAlreadyExists        Code = "ALREADY_EXISTS"
+	CodeFailedPrecondition   Code = "FAILED_PRECONDITION"
+	CodeRateLimited          Code = "RATE_LIMITED"
+	CodeCanceled             Code = "CANCELED"
+	CodeDeadlineExceeded     Code = "DEADLINE_EXCEEDED"
+	CodeUnavailable          Code = "UNAVAILABLE"
+	CodeUnimplemented        Code = "UNIMPLEMENTED"
+	CodeInternal             Code = "INTERNAL"
+	CodeAccountNotFound      Code = "ACCOUNT_NOT_FOUND"
+	CodeAccountClosed        Code = "ACCOUNT_CLOSED"
+	CodeCurrencyMismatch     Code = "CURRENCY_MISMATCH"
+	CodeInsufficientFunds    Code = "INSUFFICIENT_FUNDS"
+	CodeSecondFactorRequired Code = "SECOND_FACTOR_REQUIRED"
+	CodeLoginLocked          Code = "LOGIN_LOCKED"
+	CodeEmailNotVerified     Code = "EMAIL_NOT_VERIFIED"
+)
+
+// PreconditionEmailVerification is the PreconditionViolation type of
+// operations that need a verified email address.
+const PreconditionEmailVerification = "EMAIL_VERIFICATION"
+
+const internalMessage = "internal server error"
+
+type codeInfo struct {
+	grpcCode   codes.Code
+	httpStatus int
+	title      string
+}
+
+var codeInfos = map[Code]codeInfo{
+	CodeInvalidArgument:      {codes.InvalidArgument, http.StatusBadRequest, "Invalid argument"},
+	CodeUnauthenticated:      {codes.Unauthenticated, http.StatusUnauthorized, "Unauthenticated"},
+	CodePermissionDenied:     {codes.PermissionDenied, http.StatusForbidden, "Permission denied"},
+	CodeNotFound:             {codes.NotFound, http.StatusNotFound, "Not found"},
+	CodeAlreadyExists:        {codes.AlreadyExists, http.StatusConflict, "Already exists"},
+	CodeFailedPrecondition:   {codes.FailedPrecondition, http.StatusBadRequest, "Failed precondition"},
+	CodeRateLimited:          {codes.ResourceExhausted, http.StatusTooManyRequests, "Too many requests"},
+	CodeCanceled:             {codes.Canceled, 499, "Request canceled"},
+	CodeDeadlineExceeded:     {codes.DeadlineExceeded, http.StatusGatewayTimeout, "Deadline exceeded"},
+	CodeUnavailable:          {codes.Unavailable, http.StatusServiceUnavailable, "Service unavailable"},
+	CodeUnimplemented:        {codes.Unimplemented, http.StatusNotImplemented, "Not implemented"},
+	CodeInternal:             {codes.Internal, http.StatusInternalServerError, "Internal server error"},
+	CodeAccountNotFound:      {codes.NotFound, http.StatusNotFound, "Account not found"},
+	CodeAccountClosed:        {codes.FailedPrecondition, http.StatusForbidden, "Account closed"},
+	CodeCurrencyMismatch:     {codes.InvalidArgument, http.StatusBadRequest, "Currency mismatch"},
+	CodeInsufficientFunds:    {codes.FailedPrecondition, http.StatusUnprocessableEntity, "Insufficient funds"},
+	CodeSecondFactorRequired: {codes.PermissionDenied, http.StatusForbidden, "Second factor required"},
+	CodeLoginLocked:          {codes.ResourceExhausted, http.StatusTooManyRequests, "Login locked"},
+	CodeEmailNotVerified:     {codes.FailedPrecondition, http.StatusForbidden, "Email not verified"},
+}
+
+// grpcCodes maps gRPC codes without a more specific reason to a Code.
+var grpcCodes = map[codes.Code]Code{
+	codes.InvalidArgument:    CodeInvalidArgument,
+	codes.OutOfRange:         CodeInvalidArgument,
+	codes.Unauthenticated:    CodeUnauthenticated,
+	codes.PermissionDenied:   CodePermissionDenied,
+	codes.NotFound:           CodeNotFound,
+	codes.AlreadyExists:      CodeAlreadyExists,
+	codes.FailedPrecondition: CodeFailedPrecondition,
+	codes.Aborted:            CodeFailedPrecondition,
+	codes.ResourceExhausted:  CodeRateLimited,
+	codes.Canceled:           CodeCanceled,
+	codes.DeadlineExceeded:   CodeDeadlineExceeded,
+	codes.Unavailable:        CodeUnavailable,
+	codes.Unimplemented:      CodeUnimplemented,
+}
+
+// httpStatuses maps HTTP statuses without a more specific reason to a Code.
+var httpStatuses = map[int]Code{
+	http.StatusBadRequest:          CodeInvalidArgument,
+	http.StatusUnauthorized:        CodeUnauthenticated,
+	http.StatusForbidden:           CodePermissionDenied,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusConflict:            CodeAlreadyExists,
+	http.StatusPreconditionFailed:  CodeFailedPrecondition,
+	http.StatusUnprocessableEntity: CodeFailedPrecondition,
+	http.StatusTooManyRequests:     CodeRateLimited,
+	http.StatusServiceUnavailable:  CodeUnavailable,
+}
+
+func (code Code) info() codeInfo {
+	if info, ok := codeInfos[code]; ok {
+		return info
+	}
+	return codeInfos[CodeInternal]
+}
+
+func (code Code) GRPCCode() codes.Code {
+	return code.info().grpcCode
+}
+
+func (code Code) HTTPStatus() int {
+	return code.info().httpStatus
+}
+
+func (code Code) Title() string {
+	return code.info().title
+}
+
+// CodeFromGRPC returns the generic Code of a gRPC code.
+func CodeFromGRPC(grpcCode codes.Code) Code {
+	if code, ok := grpcCodes[grpcCode]; ok {
+		return code
+	}
+	return CodeInternal
+}
+
+// CodeFromHTTPStatus returns the generic Code of an HTTP error status.
+func CodeFromHTTPStatus(httpStatus int) Code {
+	if code, ok := httpStatuses[httpStatus]; ok {
+		return code
+	}
+	return CodeInternal
+}
@@ -0,0 +1,99 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Error is an error safe to show to clients. Message is the client facing
+// description; Cause keeps the underlying error for logs only.
+type Error struct {
+	Code          Code
+	Message       string
+	Violations    []FieldViolation
+	Preconditions []PreconditionViolation
+	RetryAfter    time.Duration
+	Cause         error
+}
+
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// PreconditionViolation is a failed precondition, like an unverified email.
+type PreconditionViolation struct {
+	Type        string `json:"type"`
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func Newf(code Code, format string, args ...any) *Error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// Internal hides cause behind a generic message.
+func Internal(cause error) *Error {
+	return &Error{Code: CodeInternal, Message: internalMessage, Cause: cause}
+}
+
+func InvalidArgument(violations []FieldViolation) *Error {
+	return &Error{Code: CodeInvalidArgument, Message: "invalid parameters", Violations: violations}
+}
+
+// EmailNotVerified is returned for operations that need username to verify
+// their email address first.
+func EmailNotVerified(username string) *Error {
+	return New(CodeEmailNotVerified, "email address is not verified").WithPrecondition(PreconditionViolation{
+		Type:        PreconditionEmailVerification,
+		Subject:     "users/" + username,
+		Description: "verify the email address with the link sent to it, a new link can be requested with ResendVerifyEmail",
+	})
+}
+
+func (err *Error) WithPrecondition(violation PreconditionViolation) *Error {
+	err.Preconditions = append(err.Preconditions, violation)
+	return err
+}
+
+func (err *Error) WithRetryAfter(retryAfter time.Duration) *Error {
+	err.RetryAfter = retryAfter
+	return err
+}
+
+func (err *Error) WithCause(cause error) *Error {
+	err.Cause = cause
+	return err
+}
+
+func (err *Error) Error() string {
+	if err.Cause != nil {
+		return fmt.Sprintf("%s: %s: %s", err.Code, err.Message, err.Cause)
+	}
+	return fmt.Sprintf("%s: %s", err.Code, err.Message)
+}
+
+func (err *Error) Unwrap() error {
+	return err.Cause
+}
+
+// Is matches errors with the same code, so sentinel errors can be
+// compared with errors.Is after a message or cause was added.
+func (err *Error) Is(target error) bool {
+	var other *Error
+	return errors.As(target, &other) && other.Code == err.Code
+}
+
+// From returns err as an *Error, treating anything else as internal.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
@@ -0,0 +1,98 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFrom(t *testing.T) {
+	insufficientFunds := New(CodeInsufficientFunds, "from account has insufficient funds")
+	wrapped := fmt.Errorf("transfer: %w", insufficientFunds)
+	require.Same(t, insufficientFunds, From(wrapped))
+	require.ErrorIs(t, wrapped, New(CodeInsufficientFunds, "other message"))
+
+	cause := errors.New("connection refused")
+	internal := From(cause)
+	require.Equal(t, CodeInternal, internal.Code)
+	require.Equal(t, internalMessage, internal.Message)
+	require.ErrorIs(t, internal, cause)
+}
+
+func TestGRPCStatusRoundTrip(t *testing.T) {
+	appErr := InvalidArgument([]FieldViolation{{Field: "amount", Description: "amount minimal 1"}})
+	appErr.Code = CodeCurrencyMismatch
+	appErr.RetryAfter = 2 * time.Second
+
+	st, ok := status.FromError(appErr)
+	require.True(t, ok)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 3)
+
+	got := FromStatus(st)
+	require.Equal(t, CodeCurrencyMismatch, got.Code)
+	require.Equal(t, appErr.Message, got.Message)
+	require.Equal(t, appErr.Violations, got.Violations)
+	require.Equal(t, appErr.RetryAfter, got.RetryAfter)
+}
+
+func TestFromGRPCRedactsInternal(t *testing.T) {
+	err := status.Error(codes.Internal, "failed to create user: connection refused")
+	appErr := FromGRPC(err)
+	require.Equal(t, CodeInternal, appErr.Code)
+
+	st := appErr.GRPCStatus()
+	require.Equal(t, codes.Internal, st.Code())
+	require.Equal(t, internalMessage, st.Message())
+	require.Equal(t, internalMessage, appErr.Problem("/v1/users").Detail)
+
+	appErr = FromGRPC(errors.New("plain error"))
+	require.Equal(t, CodeInternal, appErr.Code)
+
+	appErr = FromGRPC(status.Error(codes.NotFound, "account not found"))
+	require.Equal(t, CodeNotFound, appErr.Code)
+	require.Equal(t, "account not found", appErr.Message)
+}
+
+func TestProblem(t *testing.T) {
+	problem := New(CodeInsufficientFunds, "from account has insufficient funds").Problem("/v1/transfers")
+	require.Equal(t, Problem{
+		Type:     problemTypeBase + "insufficient-funds",
+		Title:    "Insufficient funds",
+		Status:   http.StatusUnprocessableEntity,
+		Detail:   "from account has insufficient funds",
+		Instance: "/v1/transfers",
+		Code:     CodeInsufficientFunds,
+	}, problem)
+}
+
+func TestCodes(t *testing.T) {
+	for code := range codeInfos {
+		require.NotEmpty(t, code.Title())
+		require.NotZero(t, code.HTTPStatus())
+	}
+	require.Equal(t, CodeInternal, CodeFromGRPC(codes.Unknown))
+	require.Equal(t, CodeInternal, CodeFromHTTPStatus(http.StatusTeapot))
+	require.Equal(t, CodeRateLimited, CodeFromHTTPStatus(http.StatusTooManyRequests))
+	require.Equal(t, http.StatusInternalServerError, Code("UNKNOWN_CODE").HTTPStatus())
+}
+
+func TestEmailNotVerified(t *testing.T) {
+	appErr := EmailNotVerified("alice")
+
+	st := appErr.GRPCStatus()
+	require.Equal(t, codes.FailedPrecondition, st.Code())
+	got := FromStatus(st)
+	require.Equal(t, CodeEmailNotVerified, got.Code)
+	require.Equal(t, appErr.Preconditions, got.Preconditions)
+
+	problem := appErr.Problem("/v1/accounts")
+	require.Equal(t, http.StatusForbidden, problem.Status)
+	require.Equal(t, "users/alice", problem.Preconditions[0].Subject)
+}
@@ -0,0 +1,38 @@
+package apperror
+
+import (
+	"strings"
+)
+
+// ProblemContentType is the RFC 7807 media type of Problem bodies.
+const ProblemContentType = "application/problem+json"
+
+const problemTypeBase = "https://github.com/labasubagia/simplebank/errors/"
+
+// Problem is an RFC 7807 problem details body, extended with the stable
+// error code, field violations and missing preconditions.
+type Problem struct {
+	Type          string                  `json:"type"`
+	Title         string                  `json:"title"`
+	Status        int                     `json:"status"`
+	Detail        string                  `json:"detail,omitempty"`
+	Instance      string                  `json:"instance,omitempty"`
+	Code          Code                    `json:"code"`
+	Errors        []FieldViolation        `json:"errors,omitempty"`
+	Preconditions []PreconditionViolation `json:"preconditions,omitempty"`
+}
+
+// Problem returns the client facing body of err. Instance is typically
+// the request path.
+func (err *Error) Problem(instance string) Problem {
+	return Problem{
+		Type:          problemTypeBase + strings.ToLower(strings.ReplaceAll(string(err.Code), "_", "-")),
+		Title:         err.Code.Title(),
+		Status:        err.Code.HTTPStatus(),
+		Detail:        err.Message,
+		Instance:      instance,
+		Code:          err.Code,
+		Errors:        err.Violations,
+		Preconditions: err.Preconditions,
+	}
+}
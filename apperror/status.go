@@ -0,0 +1,106 @@
+package apperror
+
+import (
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorDomain is the ErrorInfo domain attached to every gRPC error.
+const ErrorDomain = "simplebank"
+
+// GRPCStatus lets status.FromError and status.Code understand *Error.
+// Internal causes are never included.
+func (err *Error) GRPCStatus() *status.Status {
+	st := status.New(err.Code.GRPCCode(), err.Message)
+
+	var details []protoiface.MessageV1
+	if len(err.Violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, violation := range err.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+		details = append(details, badRequest)
+	}
+	if len(err.Preconditions) > 0 {
+		preconditionFailure := &errdetails.PreconditionFailure{}
+		for _, violation := range err.Preconditions {
+			preconditionFailure.Violations = append(preconditionFailure.Violations, &errdetails.PreconditionFailure_Violation{
+				Type:        violation.Type,
+				Subject:     violation.Subject,
+				Description: violation.Description,
+			})
+		}
+		details = append(details, preconditionFailure)
+	}
+	if err.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(err.RetryAfter)})
+	}
+	details = append(details, &errdetails.ErrorInfo{Reason: string(err.Code), Domain: ErrorDomain})
+
+	withDetails, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		return st
+	}
+	return withDetails
+}
+
+// FromGRPC converts any error returned by a gRPC handler into an *Error.
+// Plain status errors keep their message unless they are internal.
+func FromGRPC(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return Internal(err)
+	}
+	return FromStatus(st)
+}
+
+// FromStatus converts a gRPC status, e.g. one received by the gateway, into an *Error.
+func FromStatus(st *status.Status) *Error {
+	code := CodeFromGRPC(st.Code())
+	if code == CodeInternal {
+		if st.Code() == codes.OK {
+			return nil
+		}
+		return Internal(st.Err())
+	}
+
+	appErr := &Error{Code: code, Message: st.Message()}
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if detail.GetDomain() == ErrorDomain && detail.GetReason() != "" {
+				appErr.Code = Code(detail.GetReason())
+			}
+		case *errdetails.BadRequest:
+			for _, violation := range detail.GetFieldViolations() {
+				appErr.Violations = append(appErr.Violations, FieldViolation{
+					Field:       violation.GetField(),
+					Description: violation.GetDescription(),
+				})
+			}
+		case *errdetails.PreconditionFailure:
+			for _, violation := range detail.GetViolations() {
+				appErr.Preconditions = append(appErr.Preconditions, PreconditionViolation{
+					Type:        violation.GetType(),
+					Subject:     violation.GetSubject(),
+					Description: violation.GetDescription(),
+				})
+			}
+		case *errdetails.RetryInfo:
+			appErr.RetryAfter = detail.GetRetryDelay().AsDuration()
+		}
+	}
+	return appErr
+}
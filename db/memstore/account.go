@@ -0,0 +1,157 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+)
+
+func (store *Store) CloseAccount(ctx context.Context, id int64) (db.Account, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	account, ok := t.accounts[id]
+	if !ok || account.Status != util.AccountStatusActive || account.Balance != 0 {
+		return db.Account{}, db.ErrRecordNotFound
+	}
+	account.Status = util.AccountStatusClosed
+	t.accounts[id] = account
+	return account, nil
+}
+
+func (store *Store) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.createAccount(arg)
+}
+
+func (t *tables) createAccount(arg db.CreateAccountParams) (db.Account, error) {
+	if err := t.requireUser("accounts", "accounts_owner_fkey", arg.Owner); err != nil {
+		return db.Account{}, err
+	}
+	for _, account := range t.accounts {
+		if account.Owner == arg.Owner && account.Currency == arg.Currency {
+			return db.Account{}, uniqueViolation("accounts", "owner_currency_key")
+		}
+	}
+
+	account := db.Account{
+		ID:        t.nextID("accounts"),
+		Owner:     arg.Owner,
+		Balance:   arg.Balance,
+		Currency:  arg.Currency,
+		CreatedAt: now(),
+		Status:    util.AccountStatusActive,
+	}
+	t.accounts[account.ID] = account
+	return account, nil
+}
+
+func (store *Store) DeleteAccount(ctx context.Context, id int64) error {
+	t, unlock := store.write()
+	defer unlock()
+
+	for _, entry := range t.entries {
+		if entry.AccountID == id {
+			return referencedViolation("accounts", "entries", "entries_account_id_fkey")
+		}
+	}
+	for _, transfer := range t.transfers {
+		if transfer.FromAccountID == id {
+			return referencedViolation("accounts", "transfers", "transfers_from_account_id_fkey")
+		}
+		if transfer.ToAccountID == id {
+			return referencedViolation("accounts", "transfers", "transfers_to_account_id_fkey")
+		}
+	}
+	delete(t.accounts, id)
+	return nil
+}
+
+func (store *Store) GetAccount(ctx context.Context, id int64) (db.Account, error) {
+	t, unlock := store.read()
+	defer unlock()
+	return t.getAccount(id)
+}
+
+func (t *tables) getAccount(id int64) (db.Account, error) {
+	account, ok := t.accounts[id]
+	if !ok {
+		return db.Account{}, db.ErrRecordNotFound
+	}
+	return account, nil
+}
+
+// GetAccountForUpdate takes no row lock of its own: writes outside a
+// transaction are atomic and transactions are serialized.
+func (store *Store) GetAccountForUpdate(ctx context.Context, id int64) (db.Account, error) {
+	return store.GetAccount(ctx, id)
+}
+
+func (store *Store) GetAccountsForUpdate(ctx context.Context, ids []int64) ([]db.Account, error) {
+	t, unlock := store.read()
+	defer unlock()
+	return t.getAccounts(ids), nil
+}
+
+func (t *tables) getAccounts(ids []int64) []db.Account {
+	accounts := []db.Account{}
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		account, ok := t.accounts[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].ID < accounts[j].ID
+	})
+	return accounts
+}
+
+func (store *Store) ListAccounts(ctx context.Context, arg db.ListAccountsParams) ([]db.Account, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	accounts := []db.Account{}
+	for _, account := range t.accounts {
+		if account.Owner != arg.Owner {
+			continue
+		}
+		if arg.Currency.Valid && account.Currency != arg.Currency.String {
+			continue
+		}
+		if arg.Status.Valid && account.Status != arg.Status.String {
+			continue
+		}
+		if arg.CursorCreatedAt.Valid &&
+			!keysetAfter(account.CreatedAt, account.ID, arg.CursorCreatedAt.Time, arg.CursorID.Int64, arg.CursorID.Valid) {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return keysetAfter(accounts[j].CreatedAt, accounts[j].ID, accounts[i].CreatedAt, accounts[i].ID, true)
+	})
+	return limit(accounts, arg.Limit), nil
+}
+
+func (store *Store) UpdateAccount(ctx context.Context, arg db.UpdateAccountParams) (db.Account, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.updateAccount(arg)
+}
+
+func (t *tables) updateAccount(arg db.UpdateAccountParams) (db.Account, error) {
+	account, ok := t.accounts[arg.ID]
+	if !ok {
+		return db.Account{}, db.ErrRecordNotFound
+	}
+	account.Balance = arg.Balance
+	t.accounts[arg.ID] = account
+	return account, nil
+}
@@ -0,0 +1,95 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) CreateApiKey(ctx context.Context, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	if _, ok := t.apiKeys[arg.ID]; ok {
+		return db.ApiKey{}, uniqueViolation("api_keys", "api_keys_pkey")
+	}
+	for _, apiKey := range t.apiKeys {
+		if apiKey.Prefix == arg.Prefix {
+			return db.ApiKey{}, uniqueViolation("api_keys", "api_keys_prefix_key")
+		}
+	}
+	if err := t.requireUser("api_keys", "api_keys_username_fkey", arg.Username); err != nil {
+		return db.ApiKey{}, err
+	}
+
+	apiKey := db.ApiKey{
+		ID:         arg.ID,
+		Username:   arg.Username,
+		Name:       arg.Name,
+		Prefix:     arg.Prefix,
+		HashedKey:  arg.HashedKey,
+		Scopes:     append([]string{}, arg.Scopes...),
+		ExpiredAt:  arg.ExpiredAt,
+		LastUsedAt: zeroTime,
+		CreatedAt:  now(),
+	}
+	t.apiKeys[apiKey.ID] = apiKey
+	return apiKey, nil
+}
+
+func (store *Store) GetApiKeyByPrefix(ctx context.Context, prefix string) (db.ApiKey, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	for _, apiKey := range t.apiKeys {
+		if apiKey.Prefix == prefix {
+			return apiKey, nil
+		}
+	}
+	return db.ApiKey{}, db.ErrRecordNotFound
+}
+
+func (store *Store) ListApiKeys(ctx context.Context, username string) ([]db.ApiKey, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	apiKeys := []db.ApiKey{}
+	for _, apiKey := range t.apiKeys {
+		if apiKey.Username == username {
+			apiKeys = append(apiKeys, apiKey)
+		}
+	}
+	sort.Slice(apiKeys, func(i, j int) bool {
+		if !apiKeys[i].CreatedAt.Equal(apiKeys[j].CreatedAt) {
+			return apiKeys[i].CreatedAt.Before(apiKeys[j].CreatedAt)
+		}
+		return apiKeys[i].Prefix < apiKeys[j].Prefix
+	})
+	return apiKeys, nil
+}
+
+func (store *Store) RevokeApiKey(ctx context.Context, arg db.RevokeApiKeyParams) (db.ApiKey, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	apiKey, ok := t.apiKeys[arg.ID]
+	if !ok || apiKey.Username != arg.Username {
+		return db.ApiKey{}, db.ErrRecordNotFound
+	}
+	apiKey.IsRevoked = true
+	t.apiKeys[arg.ID] = apiKey
+	return apiKey, nil
+}
+
+func (store *Store) UpdateApiKeyLastUsed(ctx context.Context, id uuid.UUID) error {
+	t, unlock := store.write()
+	defer unlock()
+
+	if apiKey, ok := t.apiKeys[id]; ok {
+		apiKey.LastUsedAt = now()
+		t.apiKeys[id] = apiKey
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) CreateEntry(ctx context.Context, arg db.CreateEntryParams) (db.Entry, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.createEntry(arg)
+}
+
+func (t *tables) createEntry(arg db.CreateEntryParams) (db.Entry, error) {
+	if _, ok := t.accounts[arg.AccountID]; !ok {
+		return db.Entry{}, foreignKeyViolation("entries", "entries_account_id_fkey")
+	}
+
+	entry := db.Entry{
+		ID:        t.nextID("entries"),
+		AccountID: arg.AccountID,
+		Amount:    arg.Amount,
+		CreatedAt: now(),
+	}
+	t.entries[entry.ID] = entry
+	return entry, nil
+}
+
+func (store *Store) GetEntry(ctx context.Context, id int64) (db.Entry, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	entry, ok := t.entries[id]
+	if !ok {
+		return db.Entry{}, db.ErrRecordNotFound
+	}
+	return entry, nil
+}
+
+func (store *Store) ListAccountEntries(ctx context.Context, arg db.ListAccountEntriesParams) ([]db.Entry, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	entries := []db.Entry{}
+	for _, entry := range t.entries {
+		if entry.AccountID != arg.AccountID {
+			continue
+		}
+		if arg.StartTime.Valid && entry.CreatedAt.Before(arg.StartTime.Time) {
+			continue
+		}
+		if arg.EndTime.Valid && !entry.CreatedAt.Before(arg.EndTime.Time) {
+			continue
+		}
+		if arg.Direction.Valid &&
+			!(arg.Direction.String == "in" && entry.Amount > 0) &&
+			!(arg.Direction.String == "out" && entry.Amount < 0) {
+			continue
+		}
+		if arg.MinAmount.Valid && abs(entry.Amount) < arg.MinAmount.Int64 {
+			continue
+		}
+		if arg.MaxAmount.Valid && abs(entry.Amount) > arg.MaxAmount.Int64 {
+			continue
+		}
+		if arg.CursorCreatedAt.Valid &&
+			!keysetBefore(entry.CreatedAt, entry.ID, arg.CursorCreatedAt.Time, arg.CursorID.Int64, arg.CursorID.Valid) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sortEntriesNewestFirst(entries)
+	return limit(entries, arg.Limit), nil
+}
+
+func (store *Store) ListEntries(ctx context.Context, arg db.ListEntriesParams) ([]db.Entry, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	entries := []db.Entry{}
+	for _, entry := range t.entries {
+		if entry.AccountID != arg.AccountID {
+			continue
+		}
+		if arg.CursorCreatedAt.Valid &&
+			!keysetBefore(entry.CreatedAt, entry.ID, arg.CursorCreatedAt.Time, arg.CursorID.Int64, arg.CursorID.Valid) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sortEntriesNewestFirst(entries)
+	return limit(entries, arg.Limit), nil
+}
+
+// sortEntriesNewestFirst orders by created_at DESC, id DESC.
+func sortEntriesNewestFirst(entries []db.Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return keysetBefore(entries[j].CreatedAt, entries[j].ID, entries[i].CreatedAt, entries[i].ID, true)
+	})
+}
+
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
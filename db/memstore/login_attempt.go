@@ -0,0 +1,63 @@
+package memstore
+
+import (
+	"context"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) DeleteLoginAttempt(ctx context.Context, key string) error {
+	t, unlock := store.write()
+	defer unlock()
+
+	delete(t.loginAttempts, key)
+	return nil
+}
+
+func (store *Store) GetLoginAttempt(ctx context.Context, key string) (db.LoginAttempt, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	loginAttempt, ok := t.loginAttempts[key]
+	if !ok {
+		return db.LoginAttempt{}, db.ErrRecordNotFound
+	}
+	return loginAttempt, nil
+}
+
+func (store *Store) LockLoginAttempt(ctx context.Context, arg db.LockLoginAttemptParams) (db.LoginAttempt, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	loginAttempt, ok := t.loginAttempts[arg.Key]
+	if !ok {
+		return db.LoginAttempt{}, db.ErrRecordNotFound
+	}
+	loginAttempt.LockedUntil = arg.LockedUntil
+	t.loginAttempts[arg.Key] = loginAttempt
+	return loginAttempt, nil
+}
+
+func (store *Store) RecordFailedLoginAttempt(ctx context.Context, arg db.RecordFailedLoginAttemptParams) (db.LoginAttempt, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	failedAt := now()
+	loginAttempt, ok := t.loginAttempts[arg.Key]
+	switch {
+	case !ok:
+		loginAttempt = db.LoginAttempt{
+			Key:         arg.Key,
+			FailedCount: 1,
+			LockedUntil: zeroTime,
+			CreatedAt:   failedAt,
+		}
+	case loginAttempt.LastFailedAt.Before(arg.ResetBefore):
+		loginAttempt.FailedCount = 1
+	default:
+		loginAttempt.FailedCount++
+	}
+	loginAttempt.LastFailedAt = failedAt
+	t.loginAttempts[arg.Key] = loginAttempt
+	return loginAttempt, nil
+}
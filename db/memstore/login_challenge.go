@@ -0,0 +1,44 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) CreateLoginChallenge(ctx context.Context, arg db.CreateLoginChallengeParams) (db.LoginChallenge, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	if _, ok := t.loginChallenges[arg.ID]; ok {
+		return db.LoginChallenge{}, uniqueViolation("login_challenges", "login_challenges_pkey")
+	}
+	if err := t.requireUser("login_challenges", "login_challenges_username_fkey", arg.Username); err != nil {
+		return db.LoginChallenge{}, err
+	}
+
+	loginChallenge := db.LoginChallenge{
+		ID:        arg.ID,
+		Username:  arg.Username,
+		UserAgent: arg.UserAgent,
+		ClientIp:  arg.ClientIp,
+		ExpiredAt: arg.ExpiredAt,
+		CreatedAt: now(),
+	}
+	t.loginChallenges[loginChallenge.ID] = loginChallenge
+	return loginChallenge, nil
+}
+
+func (store *Store) UseLoginChallenge(ctx context.Context, id uuid.UUID) (db.LoginChallenge, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	loginChallenge, ok := t.loginChallenges[id]
+	if !ok || loginChallenge.IsUsed || !loginChallenge.ExpiredAt.After(now()) {
+		return db.LoginChallenge{}, db.ErrRecordNotFound
+	}
+	loginChallenge.IsUsed = true
+	t.loginChallenges[id] = loginChallenge
+	return loginChallenge, nil
+}
@@ -0,0 +1,64 @@
+package memstore
+
+import (
+	"context"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) CreateRecoveryCode(ctx context.Context, arg db.CreateRecoveryCodeParams) (db.RecoveryCode, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.createRecoveryCode(arg)
+}
+
+func (t *tables) createRecoveryCode(arg db.CreateRecoveryCodeParams) (db.RecoveryCode, error) {
+	if err := t.requireUser("recovery_codes", "recovery_codes_username_fkey", arg.Username); err != nil {
+		return db.RecoveryCode{}, err
+	}
+
+	recoveryCode := db.RecoveryCode{
+		ID:         t.nextID("recovery_codes"),
+		Username:   arg.Username,
+		HashedCode: arg.HashedCode,
+		CreatedAt:  now(),
+	}
+	t.recoveryCodes[recoveryCode.ID] = recoveryCode
+	return recoveryCode, nil
+}
+
+func (store *Store) DeleteRecoveryCodes(ctx context.Context, username string) error {
+	t, unlock := store.write()
+	defer unlock()
+	t.deleteRecoveryCodes(username)
+	return nil
+}
+
+func (t *tables) deleteRecoveryCodes(username string) {
+	for id, recoveryCode := range t.recoveryCodes {
+		if recoveryCode.Username == username {
+			delete(t.recoveryCodes, id)
+		}
+	}
+}
+
+func (store *Store) UseRecoveryCode(ctx context.Context, arg db.UseRecoveryCodeParams) (db.RecoveryCode, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	var found db.RecoveryCode
+	for _, recoveryCode := range t.recoveryCodes {
+		if recoveryCode.Username != arg.Username || recoveryCode.HashedCode != arg.HashedCode || recoveryCode.IsUsed {
+			continue
+		}
+		if found.ID == 0 || recoveryCode.ID < found.ID {
+			found = recoveryCode
+		}
+	}
+	if found.ID == 0 {
+		return db.RecoveryCode{}, db.ErrRecordNotFound
+	}
+	found.IsUsed = true
+	t.recoveryCodes[found.ID] = found
+	return found, nil
+}
@@ -0,0 +1,57 @@
+package memstore
+
+import (
+	"context"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) CreateResetPassword(ctx context.Context, arg db.CreateResetPasswordParams) (db.ResetPassword, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	if err := t.requireUser("reset_passwords", "reset_passwords_username_fkey", arg.Username); err != nil {
+		return db.ResetPassword{}, err
+	}
+
+	createdAt := now()
+	resetPassword := db.ResetPassword{
+		ID:         t.nextID("reset_passwords"),
+		Username:   arg.Username,
+		SecretCode: arg.SecretCode,
+		CreatedAt:  createdAt,
+		ExpiredAt:  createdAt.Add(secretCodeDuration),
+	}
+	t.resetPasswords[resetPassword.ID] = resetPassword
+	return resetPassword, nil
+}
+
+func (store *Store) InvalidateResetPasswords(ctx context.Context, username string) error {
+	t, unlock := store.write()
+	defer unlock()
+
+	expiredAt := now()
+	for id, resetPassword := range t.resetPasswords {
+		if resetPassword.Username == username && !resetPassword.IsUsed && resetPassword.ExpiredAt.After(expiredAt) {
+			resetPassword.ExpiredAt = expiredAt
+			t.resetPasswords[id] = resetPassword
+		}
+	}
+	return nil
+}
+
+func (store *Store) UpdateResetPassword(ctx context.Context, arg db.UpdateResetPasswordParams) (db.ResetPassword, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.updateResetPassword(arg)
+}
+
+func (t *tables) updateResetPassword(arg db.UpdateResetPasswordParams) (db.ResetPassword, error) {
+	resetPassword, ok := t.resetPasswords[arg.ID]
+	if !ok || resetPassword.SecretCode != arg.SecretCode || resetPassword.IsUsed || !resetPassword.ExpiredAt.After(now()) {
+		return db.ResetPassword{}, db.ErrRecordNotFound
+	}
+	resetPassword.IsUsed = true
+	t.resetPasswords[arg.ID] = resetPassword
+	return resetPassword, nil
+}
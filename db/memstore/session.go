@@ -0,0 +1,60 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) BlockUserSessions(ctx context.Context, username string) error {
+	t, unlock := store.write()
+	defer unlock()
+	t.blockUserSessions(username)
+	return nil
+}
+
+func (t *tables) blockUserSessions(username string) {
+	for id, session := range t.sessions {
+		if session.Username == username && !session.IsBlocked {
+			session.IsBlocked = true
+			t.sessions[id] = session
+		}
+	}
+}
+
+func (store *Store) CreateSession(ctx context.Context, arg db.CreateSessionParams) (db.Session, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	if _, ok := t.sessions[arg.ID]; ok {
+		return db.Session{}, uniqueViolation("sessions", "sessions_pkey")
+	}
+	if err := t.requireUser("sessions", "session_user_fkey", arg.Username); err != nil {
+		return db.Session{}, err
+	}
+
+	session := db.Session{
+		ID:           arg.ID,
+		Username:     arg.Username,
+		RefreshToken: arg.RefreshToken,
+		UserAgent:    arg.UserAgent,
+		ClientIp:     arg.ClientIp,
+		IsBlocked:    arg.IsBlocked,
+		ExpiredAt:    arg.ExpiredAt,
+		CreatedAt:    now(),
+	}
+	t.sessions[session.ID] = session
+	return session, nil
+}
+
+func (store *Store) GetSession(ctx context.Context, id uuid.UUID) (db.Session, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	session, ok := t.sessions[id]
+	if !ok {
+		return db.Session{}, db.ErrRecordNotFound
+	}
+	return session, nil
+}
@@ -0,0 +1,200 @@
+// Package memstore is an in-memory db.Store for tests and demo mode. It
+// follows the queries in db/query, including their constraint errors, so it
+// can stand in for Postgres wherever a db.Store is expected.
+package memstore
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+// Store keeps every table in maps behind one lock. Transactions hold the
+// lock for their whole duration, so they are serializable, and work on a
+// copy of the tables that is only kept when they succeed. The callbacks of
+// CreateUserTx and UpdateUserTx run inside the transaction and must not use
+// the store.
+type Store struct {
+	mu   sync.RWMutex
+	data *tables
+}
+
+var _ db.Store = (*Store)(nil)
+
+func NewStore() db.Store {
+	return &Store{data: newTables()}
+}
+
+type tables struct {
+	accounts        map[int64]db.Account
+	apiKeys         map[uuid.UUID]db.ApiKey
+	entries         map[int64]db.Entry
+	loginAttempts   map[string]db.LoginAttempt
+	loginChallenges map[uuid.UUID]db.LoginChallenge
+	recoveryCodes   map[int64]db.RecoveryCode
+	resetPasswords  map[int64]db.ResetPassword
+	sessions        map[uuid.UUID]db.Session
+	transfers       map[int64]db.Transfer
+	userTotps       map[string]db.UserTotp
+	users           map[string]db.User
+	verifyEmails    map[int64]db.VerifyEmail
+
+	// sequences of the bigserial ids, shared by every copy of the tables so
+	// that like in Postgres they are not rolled back
+	sequences map[string]int64
+}
+
+func (t *tables) nextID(table string) int64 {
+	t.sequences[table]++
+	return t.sequences[table]
+}
+
+func newTables() *tables {
+	return &tables{
+		accounts:        map[int64]db.Account{},
+		apiKeys:         map[uuid.UUID]db.ApiKey{},
+		entries:         map[int64]db.Entry{},
+		loginAttempts:   map[string]db.LoginAttempt{},
+		loginChallenges: map[uuid.UUID]db.LoginChallenge{},
+		recoveryCodes:   map[int64]db.RecoveryCode{},
+		resetPasswords:  map[int64]db.ResetPassword{},
+		sessions:        map[uuid.UUID]db.Session{},
+		transfers:       map[int64]db.Transfer{},
+		userTotps:       map[string]db.UserTotp{},
+		users:           map[string]db.User{},
+		verifyEmails:    map[int64]db.VerifyEmail{},
+		sequences:       map[string]int64{},
+	}
+}
+
+func (t *tables) clone() *tables {
+	return &tables{
+		accounts:        cloneMap(t.accounts),
+		apiKeys:         cloneMap(t.apiKeys),
+		entries:         cloneMap(t.entries),
+		loginAttempts:   cloneMap(t.loginAttempts),
+		loginChallenges: cloneMap(t.loginChallenges),
+		recoveryCodes:   cloneMap(t.recoveryCodes),
+		resetPasswords:  cloneMap(t.resetPasswords),
+		sessions:        cloneMap(t.sessions),
+		transfers:       cloneMap(t.transfers),
+		userTotps:       cloneMap(t.userTotps),
+		users:           cloneMap(t.users),
+		verifyEmails:    cloneMap(t.verifyEmails),
+		sequences:       t.sequences,
+	}
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	clone := make(map[K]V, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+func (store *Store) read() (*tables, func()) {
+	store.mu.RLock()
+	return store.data, store.mu.RUnlock
+}
+
+func (store *Store) write() (*tables, func()) {
+	store.mu.Lock()
+	return store.data, store.mu.Unlock
+}
+
+// execTx runs fn on a copy of the tables and keeps it only if fn succeeds.
+func (store *Store) execTx(fn func(tx *tables) error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	tx := store.data.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	store.data = tx
+	return nil
+}
+
+// now mirrors the microsecond precision of timestamptz.
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// zeroTime is the '0001-01-01 00:00:00Z' column default.
+var zeroTime = time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// secretCodeDuration is the expired_at default of verify_emails and
+// reset_passwords.
+const secretCodeDuration = 15 * time.Minute
+
+func uniqueViolation(table string, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           db.UniqueViolation,
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func foreignKeyViolation(table string, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           db.ForeignKeyViolation,
+		Message:        fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// referencedViolation is the error of deleting a row that is still referenced.
+func referencedViolation(table string, referencingTable string, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           db.ForeignKeyViolation,
+		Message:        fmt.Sprintf("update or delete on table %q violates foreign key constraint %q on table %q", table, constraint, referencingTable),
+		TableName:      referencingTable,
+		ConstraintName: constraint,
+	}
+}
+
+// requireUser checks a foreign key to users.
+func (t *tables) requireUser(table string, constraint string, username string) error {
+	if _, ok := t.users[username]; !ok {
+		return foreignKeyViolation(table, constraint)
+	}
+	return nil
+}
+
+// keysetAfter reports whether (createdAt, id) > (cursorCreatedAt, cursorID),
+// treating a null cursor id like Postgres' row comparison does.
+func keysetAfter(createdAt time.Time, id int64, cursorCreatedAt time.Time, cursorID int64, cursorIDValid bool) bool {
+	if !createdAt.Equal(cursorCreatedAt) {
+		return createdAt.After(cursorCreatedAt)
+	}
+	return cursorIDValid && id > cursorID
+}
+
+// keysetBefore reports whether (createdAt, id) < (cursorCreatedAt, cursorID).
+func keysetBefore(createdAt time.Time, id int64, cursorCreatedAt time.Time, cursorID int64, cursorIDValid bool) bool {
+	if !createdAt.Equal(cursorCreatedAt) {
+		return createdAt.Before(cursorCreatedAt)
+	}
+	return cursorIDValid && id < cursorID
+}
+
+// limit applies a LIMIT clause.
+func limit[T any](rows []T, n int32) []T {
+	if n < 0 {
+		n = 0
+	}
+	if len(rows) > int(n) {
+		return rows[:n]
+	}
+	return rows
+}
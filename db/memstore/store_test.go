@@ -0,0 +1,14 @@
+package memstore
+
+import (
+	"testing"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/db/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) db.Store {
+		return NewStore()
+	})
+}
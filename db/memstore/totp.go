@@ -0,0 +1,69 @@
+package memstore
+
+import (
+	"context"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) EnableUserTotp(ctx context.Context, username string) (db.UserTotp, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.enableUserTotp(username)
+}
+
+func (t *tables) enableUserTotp(username string) (db.UserTotp, error) {
+	userTotp, ok := t.userTotps[username]
+	if !ok {
+		return db.UserTotp{}, db.ErrRecordNotFound
+	}
+	userTotp.IsEnabled = true
+	userTotp.EnabledAt = now()
+	t.userTotps[username] = userTotp
+	return userTotp, nil
+}
+
+func (store *Store) GetUserTotp(ctx context.Context, username string) (db.UserTotp, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	userTotp, ok := t.userTotps[username]
+	if !ok {
+		return db.UserTotp{}, db.ErrRecordNotFound
+	}
+	return userTotp, nil
+}
+
+func (store *Store) UpsertUserTotp(ctx context.Context, arg db.UpsertUserTotpParams) (db.UserTotp, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	if err := t.requireUser("user_totps", "user_totps_username_fkey", arg.Username); err != nil {
+		return db.UserTotp{}, err
+	}
+
+	userTotp := db.UserTotp{
+		Username:  arg.Username,
+		Secret:    arg.Secret,
+		EnabledAt: zeroTime,
+		CreatedAt: now(),
+	}
+	if existing, ok := t.userTotps[arg.Username]; ok {
+		userTotp.LastUsedStep = existing.LastUsedStep
+	}
+	t.userTotps[arg.Username] = userTotp
+	return userTotp, nil
+}
+
+func (store *Store) UseUserTotpStep(ctx context.Context, arg db.UseUserTotpStepParams) (db.UserTotp, error) {
+	t, unlock := store.write()
+	defer unlock()
+
+	userTotp, ok := t.userTotps[arg.Username]
+	if !ok || userTotp.LastUsedStep >= arg.LastUsedStep {
+		return db.UserTotp{}, db.ErrRecordNotFound
+	}
+	userTotp.LastUsedStep = arg.LastUsedStep
+	t.userTotps[arg.Username] = userTotp
+	return userTotp, nil
+}
@@ -0,0 +1,113 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) CreateTransfer(ctx context.Context, arg db.CreateTransferParams) (db.Transfer, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.createTransfer(arg)
+}
+
+func (t *tables) createTransfer(arg db.CreateTransferParams) (db.Transfer, error) {
+	if _, ok := t.accounts[arg.FromAccountID]; !ok {
+		return db.Transfer{}, foreignKeyViolation("transfers", "transfers_from_account_id_fkey")
+	}
+	if _, ok := t.accounts[arg.ToAccountID]; !ok {
+		return db.Transfer{}, foreignKeyViolation("transfers", "transfers_to_account_id_fkey")
+	}
+
+	transfer := db.Transfer{
+		ID:            t.nextID("transfers"),
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		CreatedAt:     now(),
+	}
+	t.transfers[transfer.ID] = transfer
+	return transfer, nil
+}
+
+func (store *Store) GetTransfer(ctx context.Context, id int64) (db.Transfer, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	transfer, ok := t.transfers[id]
+	if !ok {
+		return db.Transfer{}, db.ErrRecordNotFound
+	}
+	return transfer, nil
+}
+
+func (store *Store) ListAccountTransfers(ctx context.Context, arg db.ListAccountTransfersParams) ([]db.Transfer, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	transfers := []db.Transfer{}
+	for _, transfer := range t.transfers {
+		outgoing := transfer.FromAccountID == arg.AccountID
+		incoming := transfer.ToAccountID == arg.AccountID
+		if !outgoing && !incoming {
+			continue
+		}
+		if arg.Direction.Valid &&
+			!(arg.Direction.String == "in" && incoming) &&
+			!(arg.Direction.String == "out" && outgoing) {
+			continue
+		}
+		if arg.CounterpartyAccountID.Valid &&
+			!(outgoing && transfer.ToAccountID == arg.CounterpartyAccountID.Int64) &&
+			!(incoming && transfer.FromAccountID == arg.CounterpartyAccountID.Int64) {
+			continue
+		}
+		if arg.StartTime.Valid && transfer.CreatedAt.Before(arg.StartTime.Time) {
+			continue
+		}
+		if arg.EndTime.Valid && !transfer.CreatedAt.Before(arg.EndTime.Time) {
+			continue
+		}
+		if arg.MinAmount.Valid && transfer.Amount < arg.MinAmount.Int64 {
+			continue
+		}
+		if arg.MaxAmount.Valid && transfer.Amount > arg.MaxAmount.Int64 {
+			continue
+		}
+		if arg.CursorCreatedAt.Valid &&
+			!keysetBefore(transfer.CreatedAt, transfer.ID, arg.CursorCreatedAt.Time, arg.CursorID.Int64, arg.CursorID.Valid) {
+			continue
+		}
+		transfers = append(transfers, transfer)
+	}
+	sortTransfersNewestFirst(transfers)
+	return limit(transfers, arg.Limit), nil
+}
+
+func (store *Store) ListTransfer(ctx context.Context, arg db.ListTransferParams) ([]db.Transfer, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	transfers := []db.Transfer{}
+	for _, transfer := range t.transfers {
+		if transfer.FromAccountID != arg.FromAccountID && transfer.ToAccountID != arg.ToAccountID {
+			continue
+		}
+		if arg.CursorCreatedAt.Valid &&
+			!keysetBefore(transfer.CreatedAt, transfer.ID, arg.CursorCreatedAt.Time, arg.CursorID.Int64, arg.CursorID.Valid) {
+			continue
+		}
+		transfers = append(transfers, transfer)
+	}
+	sortTransfersNewestFirst(transfers)
+	return limit(transfers, arg.Limit), nil
+}
+
+// sortTransfersNewestFirst orders by created_at DESC, id DESC.
+func sortTransfersNewestFirst(transfers []db.Transfer) {
+	sort.Slice(transfers, func(i, j int) bool {
+		return keysetBefore(transfers[j].CreatedAt, transfers[j].ID, transfers[i].CreatedAt, transfers[i].ID, true)
+	})
+}
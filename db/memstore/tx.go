@@ -0,0 +1,175 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+)
+
+func (store *Store) TransferTx(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+	var result db.TransferTxResult
+	err := store.execTx(func(tx *tables) error {
+		accountMap := map[int64]db.Account{}
+		for _, account := range tx.getAccounts([]int64{arg.FromAccountID, arg.ToAccountID}) {
+			accountMap[account.ID] = account
+		}
+		fromAccount, ok := accountMap[arg.FromAccountID]
+		if !ok {
+			return errors.New("from account not found")
+		}
+		toAccount, ok := accountMap[arg.ToAccountID]
+		if !ok {
+			return errors.New("to account not found")
+		}
+		if fromAccount.Status != util.AccountStatusActive || toAccount.Status != util.AccountStatusActive {
+			return db.ErrAccountClosed
+		}
+		if fromAccount.Balance < arg.Amount {
+			return db.ErrInsufficientFunds
+		}
+
+		var err error
+		result.Transfer, err = tx.createTransfer(db.CreateTransferParams(arg))
+		if err != nil {
+			return err
+		}
+		result.FromEntry, err = tx.createEntry(db.CreateEntryParams{
+			AccountID: arg.FromAccountID,
+			Amount:    -arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+		result.ToEntry, err = tx.createEntry(db.CreateEntryParams{
+			AccountID: arg.ToAccountID,
+			Amount:    arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+		result.FromAccount, err = tx.updateAccount(db.UpdateAccountParams{
+			ID:      fromAccount.ID,
+			Balance: fromAccount.Balance - arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+		result.ToAccount, err = tx.updateAccount(db.UpdateAccountParams{
+			ID:      toAccount.ID,
+			Balance: toAccount.Balance + arg.Amount,
+		})
+		return err
+	})
+	return result, err
+}
+
+func (store *Store) CreateUserTx(ctx context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+	var result db.CreateUserTxResult
+	err := store.execTx(func(tx *tables) error {
+		var err error
+		result.User, err = tx.createUser(arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+		return arg.AfterCreate(result.User)
+	})
+	return result, err
+}
+
+func (store *Store) UpdateUserTx(ctx context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+	var result db.UpdateUserTxResult
+	err := store.execTx(func(tx *tables) error {
+		var err error
+		result.User, err = tx.updateUser(arg.UpdateUserParams)
+		if err != nil {
+			return err
+		}
+		if arg.HashedPassword.Valid {
+			tx.blockUserSessions(result.User.Username)
+		}
+		if arg.AfterUpdate == nil {
+			return nil
+		}
+		return arg.AfterUpdate(result.User)
+	})
+	return result, err
+}
+
+func (store *Store) VerifyEmailTx(ctx context.Context, arg db.VerifyEmailTxParams) (db.VerifyEmailTxResult, error) {
+	var result db.VerifyEmailTxResult
+	err := store.execTx(func(tx *tables) error {
+		var err error
+		result.VerifyEmail, err = tx.updateVerifyEmail(db.UpdateVerifyEmailParams{
+			ID:         arg.EmailID,
+			SecretCode: arg.SecretCode,
+		})
+		if err != nil {
+			return err
+		}
+		result.User, err = tx.confirmUserEmail(db.ConfirmUserEmailParams{
+			Username: result.VerifyEmail.Username,
+			Email:    result.VerifyEmail.Email,
+		})
+		return err
+	})
+	return result, err
+}
+
+func (store *Store) EnableTotpTx(ctx context.Context, arg db.EnableTotpTxParams) (db.EnableTotpTxResult, error) {
+	var result db.EnableTotpTxResult
+	err := store.execTx(func(tx *tables) error {
+		var err error
+		result.UserTotp, err = tx.enableUserTotp(arg.Username)
+		if err != nil {
+			return err
+		}
+		tx.deleteRecoveryCodes(arg.Username)
+		for _, hashedCode := range arg.HashedRecoveryCodes {
+			recoveryCode, err := tx.createRecoveryCode(db.CreateRecoveryCodeParams{
+				Username:   arg.Username,
+				HashedCode: hashedCode,
+			})
+			if err != nil {
+				return err
+			}
+			result.RecoveryCodes = append(result.RecoveryCodes, recoveryCode)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (store *Store) ResetPasswordTx(ctx context.Context, arg db.ResetPasswordTxParams) (db.ResetPasswordTxResult, error) {
+	var result db.ResetPasswordTxResult
+	err := store.execTx(func(tx *tables) error {
+		var err error
+		result.ResetPassword, err = tx.updateResetPassword(db.UpdateResetPasswordParams{
+			ID:         arg.ResetPasswordID,
+			SecretCode: arg.SecretCode,
+		})
+		if err != nil {
+			return err
+		}
+		result.User, err = tx.updateUser(db.UpdateUserParams{
+			Username: result.ResetPassword.Username,
+			HashedPassword: pgtype.Text{
+				String: arg.HashedPassword,
+				Valid:  true,
+			},
+			PasswordChangedAt: pgtype.Timestamptz{
+				Time:  time.Now(),
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		tx.blockUserSessions(result.User.Username)
+		return nil
+	})
+	return result, err
+}
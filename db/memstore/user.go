@@ -0,0 +1,122 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) ConfirmUserEmail(ctx context.Context, arg db.ConfirmUserEmailParams) (db.User, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.confirmUserEmail(arg)
+}
+
+func (t *tables) confirmUserEmail(arg db.ConfirmUserEmailParams) (db.User, error) {
+	user, ok := t.users[arg.Username]
+	pending := user.PendingEmail.Valid && user.PendingEmail.String == arg.Email
+	if !ok || (user.Email != arg.Email && !pending) {
+		return db.User{}, db.ErrRecordNotFound
+	}
+	user.Email = arg.Email
+	if pending {
+		user.PendingEmail = pgtype.Text{}
+	}
+	user.IsEmailVerified = true
+	t.users[arg.Username] = user
+	return user, nil
+}
+
+func (store *Store) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.createUser(arg)
+}
+
+func (t *tables) createUser(arg db.CreateUserParams) (db.User, error) {
+	if _, ok := t.users[arg.Username]; ok {
+		return db.User{}, uniqueViolation("users", "users_pkey")
+	}
+
+	user := db.User{
+		Username:          arg.Username,
+		HashedPassword:    arg.HashedPassword,
+		FullName:          arg.FullName,
+		Email:             arg.Email,
+		PasswordChangedAt: zeroTime,
+		CreatedAt:         now(),
+	}
+	t.users[user.Username] = user
+	return user, nil
+}
+
+func (store *Store) GetUser(ctx context.Context, username string) (db.User, error) {
+	t, unlock := store.read()
+	defer unlock()
+	return t.getUser(username)
+}
+
+func (t *tables) getUser(username string) (db.User, error) {
+	user, ok := t.users[username]
+	if !ok {
+		return db.User{}, db.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (store *Store) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	var (
+		found db.User
+		ok    bool
+	)
+	for _, user := range t.users {
+		if user.Email != email {
+			continue
+		}
+		if !ok || user.CreatedAt.Before(found.CreatedAt) ||
+			(user.CreatedAt.Equal(found.CreatedAt) && user.Username < found.Username) {
+			found, ok = user, true
+		}
+	}
+	if !ok {
+		return db.User{}, db.ErrRecordNotFound
+	}
+	return found, nil
+}
+
+func (store *Store) UpdateUser(ctx context.Context, arg db.UpdateUserParams) (db.User, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.updateUser(arg)
+}
+
+func (t *tables) updateUser(arg db.UpdateUserParams) (db.User, error) {
+	user, ok := t.users[arg.Username]
+	if !ok {
+		return db.User{}, db.ErrRecordNotFound
+	}
+	if arg.Email.Valid {
+		user.Email = arg.Email.String
+	}
+	if arg.FullName.Valid {
+		user.FullName = arg.FullName.String
+	}
+	if arg.HashedPassword.Valid {
+		user.HashedPassword = arg.HashedPassword.String
+	}
+	if arg.PasswordChangedAt.Valid {
+		user.PasswordChangedAt = arg.PasswordChangedAt.Time
+	}
+	if arg.IsEmailVerified.Valid {
+		user.IsEmailVerified = arg.IsEmailVerified.Bool
+	}
+	if arg.PendingEmail.Valid {
+		user.PendingEmail = arg.PendingEmail
+	}
+	t.users[arg.Username] = user
+	return user, nil
+}
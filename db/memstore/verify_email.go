@@ -0,0 +1,80 @@
+package memstore
+
+import (
+	"context"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+)
+
+func (store *Store) CreateVerifyEmail(ctx context.Context, arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.createVerifyEmail(arg)
+}
+
+func (t *tables) createVerifyEmail(arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+	if err := t.requireUser("verify_emails", "verify_emails_username_fkey", arg.Username); err != nil {
+		return db.VerifyEmail{}, err
+	}
+
+	createdAt := now()
+	verifyEmail := db.VerifyEmail{
+		ID:         t.nextID("verify_emails"),
+		Username:   arg.Username,
+		Email:      arg.Email,
+		SecretCode: arg.SecretCode,
+		CreatedAt:  createdAt,
+		ExpiredAt:  createdAt.Add(secretCodeDuration),
+	}
+	t.verifyEmails[verifyEmail.ID] = verifyEmail
+	return verifyEmail, nil
+}
+
+func (store *Store) GetLatestVerifyEmail(ctx context.Context, username string) (db.VerifyEmail, error) {
+	t, unlock := store.read()
+	defer unlock()
+
+	var latest db.VerifyEmail
+	for _, verifyEmail := range t.verifyEmails {
+		if verifyEmail.Username == username && verifyEmail.ID > latest.ID {
+			latest = verifyEmail
+		}
+	}
+	if latest.ID == 0 {
+		return db.VerifyEmail{}, db.ErrRecordNotFound
+	}
+	return latest, nil
+}
+
+func (store *Store) InvalidateVerifyEmails(ctx context.Context, username string) error {
+	t, unlock := store.write()
+	defer unlock()
+	t.invalidateVerifyEmails(username)
+	return nil
+}
+
+func (t *tables) invalidateVerifyEmails(username string) {
+	expiredAt := now()
+	for id, verifyEmail := range t.verifyEmails {
+		if verifyEmail.Username == username && !verifyEmail.IsUsed && verifyEmail.ExpiredAt.After(expiredAt) {
+			verifyEmail.ExpiredAt = expiredAt
+			t.verifyEmails[id] = verifyEmail
+		}
+	}
+}
+
+func (store *Store) UpdateVerifyEmail(ctx context.Context, arg db.UpdateVerifyEmailParams) (db.VerifyEmail, error) {
+	t, unlock := store.write()
+	defer unlock()
+	return t.updateVerifyEmail(arg)
+}
+
+func (t *tables) updateVerifyEmail(arg db.UpdateVerifyEmailParams) (db.VerifyEmail, error) {
+	verifyEmail, ok := t.verifyEmails[arg.ID]
+	if !ok || verifyEmail.SecretCode != arg.SecretCode || verifyEmail.IsUsed || !verifyEmail.ExpiredAt.After(now()) {
+		return db.VerifyEmail{}, db.ErrRecordNotFound
+	}
+	verifyEmail.IsUsed = true
+	t.verifyEmails[arg.ID] = verifyEmail
+	return verifyEmail, nil
+}
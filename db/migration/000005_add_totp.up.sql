@@ -0,0 +1,34 @@
+CREATE TABLE "user_totps" (
+    "username" varchar PRIMARY KEY,
+    "secret" varchar NOT NULL,
+    "is_enabled" boolean NOT NULL DEFAULT false,
+    "enabled_at" timestamptz NOT NULL DEFAULT('0001-01-01 00:00:00Z'),
+    "last_used_step" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "user_totps" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "recovery_codes" (
+    "id" bigserial PRIMARY KEY,
+    "username" varchar NOT NULL,
+    "hashed_code" varchar NOT NULL,
+    "is_used" boolean NOT NULL DEFAULT false,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "recovery_codes" ("username");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "login_challenges" (
+    "id" uuid PRIMARY KEY,
+    "username" varchar NOT NULL,
+    "user_agent" varchar NOT NULL,
+    "client_ip" varchar NOT NULL,
+    "is_used" boolean NOT NULL DEFAULT false,
+    "expired_at" timestamptz NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "login_challenges" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,12 @@
+CREATE TABLE "reset_passwords" (
+    "id" bigserial PRIMARY KEY,
+    "username" varchar NOT NULL,
+    "secret_code" varchar NOT NULL,
+    "is_used" boolean NOT NULL DEFAULT false,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    "expired_at" timestamptz NOT NULL DEFAULT (now() + interval '15 minutes')
+);
+
+ALTER TABLE "reset_passwords" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "sessions" ("username");
@@ -0,0 +1,7 @@
+CREATE TABLE "login_attempts" (
+    "key" varchar PRIMARY KEY,
+    "failed_count" integer NOT NULL DEFAULT 0,
+    "locked_until" timestamptz NOT NULL DEFAULT('0001-01-01 00:00:00Z'),
+    "last_failed_at" timestamptz NOT NULL DEFAULT (now()),
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
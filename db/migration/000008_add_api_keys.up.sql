@@ -0,0 +1,16 @@
+CREATE TABLE "api_keys" (
+    "id" uuid PRIMARY KEY,
+    "username" varchar NOT NULL,
+    "name" varchar NOT NULL,
+    "prefix" varchar UNIQUE NOT NULL,
+    "hashed_key" varchar NOT NULL,
+    "scopes" varchar[] NOT NULL,
+    "is_revoked" boolean NOT NULL DEFAULT false,
+    "expired_at" timestamptz NOT NULL,
+    "last_used_at" timestamptz NOT NULL DEFAULT('0001-01-01 00:00:00Z'),
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "api_keys" ("username");
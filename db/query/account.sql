@@ -24,10 +24,13 @@ FOR NO KEY UPDATE;
 
 -- name: ListAccounts :many
 SELECT * FROM accounts
-WHERE owner = $1
-ORDER BY id
-LIMIT $2
-OFFSET $3;
+WHERE owner = sqlc.arg(owner)
+    AND (sqlc.narg(currency)::varchar IS NULL OR currency = sqlc.narg(currency))
+    AND (sqlc.narg(status)::varchar IS NULL OR status = sqlc.narg(status))
+    AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL
+        OR (created_at, id) > (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_id)::bigint))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
 
 -- name: UpdateAccount :one
 UPDATE accounts
@@ -35,6 +38,14 @@ SET balance = $2
 WHERE id = $1
 RETURNING *;
 
+-- name: CloseAccount :one
+UPDATE accounts
+SET status = 'closed'
+WHERE id = $1
+    AND status = 'active'
+    AND balance = 0
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM accounts
 WHERE id = $1;
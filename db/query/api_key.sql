@@ -0,0 +1,32 @@
+-- name: CreateApiKey :one
+INSERT INTO api_keys (
+    id,
+    username,
+    name,
+    prefix,
+    hashed_key,
+    scopes,
+    expired_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetApiKeyByPrefix :one
+SELECT * FROM api_keys
+WHERE prefix = $1 LIMIT 1;
+
+-- name: ListApiKeys :many
+SELECT * FROM api_keys
+WHERE username = $1
+ORDER BY created_at;
+
+-- name: RevokeApiKey :one
+UPDATE api_keys
+SET is_revoked = true
+WHERE id = @id AND username = @username
+RETURNING *;
+
+-- name: UpdateApiKeyLastUsed :exec
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1;
@@ -0,0 +1,20 @@
+-- name: CreateLoginChallenge :one
+INSERT INTO login_challenges (
+    id,
+    username,
+    user_agent,
+    client_ip,
+    expired_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: UseLoginChallenge :one
+UPDATE login_challenges
+SET
+    is_used = true
+WHERE
+    id = @id
+    AND is_used = false
+    AND expired_at > now()
+RETURNING *;
@@ -0,0 +1,26 @@
+-- name: CreateResetPassword :one
+INSERT INTO reset_passwords (
+    username, secret_code
+) VALUES (
+    $1, $2
+) RETURNING *;
+
+-- name: InvalidateResetPasswords :exec
+UPDATE reset_passwords
+SET
+    expired_at = now()
+WHERE
+    username = @username
+    AND is_used = false
+    AND expired_at > now();
+
+-- name: UpdateResetPassword :one
+UPDATE reset_passwords
+SET
+    is_used = true
+WHERE
+    id = @id
+    AND secret_code = @secret_code
+    AND is_used = false
+    AND expired_at > now()
+RETURNING *;
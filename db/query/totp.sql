@@ -0,0 +1,35 @@
+-- name: UpsertUserTotp :one
+INSERT INTO user_totps (
+    username,
+    secret
+) VALUES (
+    $1, $2
+) ON CONFLICT (username) DO UPDATE
+SET
+    secret = EXCLUDED.secret,
+    is_enabled = false,
+    enabled_at = '0001-01-01 00:00:00Z',
+    created_at = now()
+RETURNING *;
+
+-- name: GetUserTotp :one
+SELECT * FROM user_totps
+WHERE username = $1 LIMIT 1;
+
+-- name: EnableUserTotp :one
+UPDATE user_totps
+SET
+    is_enabled = true,
+    enabled_at = now()
+WHERE
+    username = $1
+RETURNING *;
+
+-- name: UseUserTotpStep :one
+UPDATE user_totps
+SET
+    last_used_step = $2
+WHERE
+    username = $1
+    AND last_used_step < $2
+RETURNING *;
@@ -14,8 +14,27 @@ WHERE id = $1 LIMIT 1;
 -- name: ListTransfer :many
 SELECT * FROM transfers
 WHERE
-    from_account_id = $1 OR
-    to_account_id = $2
-ORDER BY id
-LIMIT $3
-OFFSET $4;
+    (from_account_id = sqlc.arg(from_account_id) OR
+    to_account_id = sqlc.arg(to_account_id))
+    AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL
+        OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_id)::bigint))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListAccountTransfers :many
+SELECT * FROM transfers
+WHERE (from_account_id = sqlc.arg(account_id) OR to_account_id = sqlc.arg(account_id))
+    AND (sqlc.narg(direction)::varchar IS NULL
+        OR (sqlc.narg(direction) = 'in' AND to_account_id = sqlc.arg(account_id))
+        OR (sqlc.narg(direction) = 'out' AND from_account_id = sqlc.arg(account_id)))
+    AND (sqlc.narg(counterparty_account_id)::bigint IS NULL
+        OR (from_account_id = sqlc.arg(account_id) AND to_account_id = sqlc.narg(counterparty_account_id))
+        OR (to_account_id = sqlc.arg(account_id) AND from_account_id = sqlc.narg(counterparty_account_id)))
+    AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+    AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR amount >= sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR amount <= sqlc.narg(max_amount))
+    AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL
+        OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_id)::bigint))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
@@ -12,6 +12,12 @@ INSERT INTO users (
 SELECT * FROM users
 WHERE username = $1 LIMIT 1;
 
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1
+ORDER BY created_at
+LIMIT 1;
+
 
 -- name: UpdateUser :one
 UPDATE users
@@ -20,7 +26,19 @@ SET
     full_name = COALESCE(sqlc.narg(full_name), full_name),
     hashed_password = COALESCE(sqlc.narg(hashed_password), hashed_password),
     password_changed_at = COALESCE(sqlc.narg(password_changed_at), password_changed_at),
-    is_email_verified = COALESCE(sqlc.narg(is_email_verified), is_email_verified)
+    is_email_verified = COALESCE(sqlc.narg(is_email_verified), is_email_verified),
+    pending_email = COALESCE(sqlc.narg(pending_email), pending_email)
+WHERE
+    username = sqlc.arg(username)
+RETURNING *;
+
+-- name: ConfirmUserEmail :one
+UPDATE users
+SET
+    email = sqlc.arg(email),
+    pending_email = CASE WHEN pending_email = sqlc.arg(email) THEN NULL ELSE pending_email END,
+    is_email_verified = true
 WHERE
     username = sqlc.arg(username)
+    AND (email = sqlc.arg(email) OR pending_email = sqlc.arg(email))
 RETURNING *;
@@ -16,3 +16,18 @@ WHERE
     AND is_used = false
     AND expired_at > now()
 RETURNING *;
+
+-- name: GetLatestVerifyEmail :one
+SELECT * FROM verify_emails
+WHERE username = @username
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: InvalidateVerifyEmails :exec
+UPDATE verify_emails
+SET
+    expired_at = now()
+WHERE
+    username = @username
+    AND is_used = false
+    AND expired_at > now();
@@ -5,16 +5,21 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labasubagia/simplebank/util"
 	"github.com/stretchr/testify/require"
 )
 
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountWithBalance(t, util.RandomMoney())
+}
+
+func createRandomAccountWithBalance(t *testing.T, balance int64) Account {
 	user := createRandomUser(t)
 
 	arg := CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  util.RandomMoney(),
+		Balance:  balance,
 		Currency: util.RandomCurrency(),
 	}
 
@@ -25,6 +30,7 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, util.AccountStatusActive, account.Status)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -86,9 +92,8 @@ func TestListAccounts(t *testing.T) {
 		lastAccount = createRandomAccount(t)
 	}
 	arg := ListAccountsParams{
-		Owner:  lastAccount.Owner,
-		Limit:  5,
-		Offset: 0,
+		Owner: lastAccount.Owner,
+		Limit: 5,
 	}
 
 	accounts, err := testStore.ListAccounts(context.Background(), arg)
@@ -100,3 +105,44 @@ func TestListAccounts(t *testing.T) {
 		require.Equal(t, lastAccount.Owner, account.Owner)
 	}
 }
+
+func TestListAccountsFilter(t *testing.T) {
+	account := createRandomAccount(t)
+
+	arg := ListAccountsParams{
+		Owner:    account.Owner,
+		Currency: pgtype.Text{String: account.Currency, Valid: true},
+		Status:   pgtype.Text{String: util.AccountStatusActive, Valid: true},
+		Limit:    5,
+	}
+	accounts, err := testStore.ListAccounts(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, account.ID, accounts[0].ID)
+
+	arg.Status = pgtype.Text{String: util.AccountStatusClosed, Valid: true}
+	accounts, err = testStore.ListAccounts(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+}
+
+func TestCloseAccount(t *testing.T) {
+	account1 := createRandomAccount(t)
+
+	_, err := testStore.CloseAccount(context.Background(), account1.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = testStore.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account1.ID,
+		Balance: 0,
+	})
+	require.NoError(t, err)
+
+	account2, err := testStore.CloseAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, account2.ID)
+	require.Equal(t, util.AccountStatusClosed, account2.Status)
+
+	_, err = testStore.CloseAccount(context.Background(), account1.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
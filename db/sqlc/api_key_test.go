@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomApiKey(t *testing.T, username string) ApiKey {
+	key, prefix, err := util.GenerateApiKey()
+	require.NoError(t, err)
+
+	arg := CreateApiKeyParams{
+		ID:        uuid.New(),
+		Username:  username,
+		Name:      util.RandomString(8),
+		Prefix:    prefix,
+		HashedKey: util.HashApiKey(key),
+		Scopes:    []string{util.ScopeAccountsRead, util.ScopeTransfersWrite},
+		ExpiredAt: time.Now().Add(time.Hour),
+	}
+	apiKey, err := testStore.CreateApiKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, apiKey.ID)
+	require.Equal(t, arg.Username, apiKey.Username)
+	require.Equal(t, arg.Name, apiKey.Name)
+	require.Equal(t, arg.Prefix, apiKey.Prefix)
+	require.Equal(t, arg.HashedKey, apiKey.HashedKey)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.False(t, apiKey.IsRevoked)
+	require.True(t, apiKey.LastUsedAt.IsZero())
+	require.WithinDuration(t, arg.ExpiredAt, apiKey.ExpiredAt, time.Second)
+	return apiKey
+}
+
+func TestCreateApiKey(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomApiKey(t, user.Username)
+}
+
+func TestGetApiKeyByPrefix(t *testing.T) {
+	user := createRandomUser(t)
+	apiKey1 := createRandomApiKey(t, user.Username)
+
+	apiKey2, err := testStore.GetApiKeyByPrefix(context.Background(), apiKey1.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, apiKey1.ID, apiKey2.ID)
+	require.Equal(t, apiKey1.HashedKey, apiKey2.HashedKey)
+	require.Equal(t, apiKey1.Scopes, apiKey2.Scopes)
+}
+
+func TestListApiKeys(t *testing.T) {
+	user := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomApiKey(t, user.Username)
+	}
+
+	apiKeys, err := testStore.ListApiKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 3)
+	for _, apiKey := range apiKeys {
+		require.Equal(t, user.Username, apiKey.Username)
+	}
+}
+
+func TestRevokeApiKey(t *testing.T) {
+	user := createRandomUser(t)
+	apiKey := createRandomApiKey(t, user.Username)
+
+	_, err := testStore.RevokeApiKey(context.Background(), RevokeApiKeyParams{
+		ID:       apiKey.ID,
+		Username: util.RandomOwner(),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	revoked, err := testStore.RevokeApiKey(context.Background(), RevokeApiKeyParams{
+		ID:       apiKey.ID,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, revoked.IsRevoked)
+}
+
+func TestUpdateApiKeyLastUsed(t *testing.T) {
+	user := createRandomUser(t)
+	apiKey := createRandomApiKey(t, user.Username)
+
+	err := testStore.UpdateApiKeyLastUsed(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+
+	updated, err := testStore.GetApiKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), updated.LastUsedAt, time.Second)
+}
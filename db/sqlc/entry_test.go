@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labasubagia/simplebank/util"
 	"github.com/stretchr/testify/require"
 )
@@ -53,15 +54,63 @@ func TestListEntries(t *testing.T) {
 	arg := ListEntriesParams{
 		AccountID: account.ID,
 		Limit:     5,
-		Offset:    5,
 	}
 
-	entries, err := testStore.ListEntries(context.Background(), arg)
+	page1, err := testStore.ListEntries(context.Background(), arg)
 	require.NoError(t, err)
-	require.Len(t, entries, 5)
+	require.Len(t, page1, 5)
 
-	for _, entry := range entries {
+	last := page1[len(page1)-1]
+	arg.CursorCreatedAt = pgtype.Timestamptz{Time: last.CreatedAt, Valid: true}
+	arg.CursorID = pgtype.Int8{Int64: last.ID, Valid: true}
+	page2, err := testStore.ListEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, page2, 5)
+
+	seen := make(map[int64]bool)
+	for _, entry := range append(page1, page2...) {
 		require.NotEmpty(t, entry)
 		require.Equal(t, account.ID, entry.AccountID)
+		require.False(t, seen[entry.ID])
+		seen[entry.ID] = true
 	}
 }
+
+func TestListAccountEntries(t *testing.T) {
+	account := createRandomAccount(t)
+	for i := 0; i < 5; i++ {
+		createRandomEntry(t, account)
+	}
+	for _, amount := range []int64{-10, -20, -30} {
+		_, err := testStore.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    amount,
+		})
+		require.NoError(t, err)
+	}
+
+	arg := ListAccountEntriesParams{
+		AccountID: account.ID,
+		Limit:     20,
+	}
+	entries, err := testStore.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 8)
+	for i := 1; i < len(entries); i++ {
+		require.False(t, entries[i].CreatedAt.After(entries[i-1].CreatedAt))
+	}
+
+	arg.Direction = pgtype.Text{String: util.DirectionOut, Valid: true}
+	arg.MinAmount = pgtype.Int8{Int64: 15, Valid: true}
+	arg.MaxAmount = pgtype.Int8{Int64: 30, Valid: true}
+	entries, err = testStore.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, int64(-30), entries[0].Amount)
+	require.Equal(t, int64(-20), entries[1].Amount)
+
+	arg.EndTime = pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true}
+	entries, err = testStore.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -14,6 +14,10 @@ const (
 
 var ErrRecordNotFound = pgx.ErrNoRows
 
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+var ErrAccountClosed = errors.New("account is closed")
+
 var ErrForeignKeyViolation = &pgconn.PgError{
 	Code: ForeignKeyViolation,
 }
@@ -16,6 +16,7 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 		if rbErr := tx.Rollback(ctx); rbErr != nil {
 			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
 		}
+		return err
 	}
 
 	return tx.Commit(ctx)
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomLoginAttempt(t *testing.T) LoginAttempt {
+	arg := RecordFailedLoginAttemptParams{
+		Key:         "user:" + util.RandomOwner(),
+		ResetBefore: time.Now().Add(-time.Hour),
+	}
+	loginAttempt, err := testStore.RecordFailedLoginAttempt(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Key, loginAttempt.Key)
+	require.Equal(t, int32(1), loginAttempt.FailedCount)
+	require.True(t, loginAttempt.LockedUntil.IsZero())
+	require.WithinDuration(t, time.Now(), loginAttempt.LastFailedAt, time.Second)
+	return loginAttempt
+}
+
+func TestRecordFailedLoginAttempt(t *testing.T) {
+	old := createRandomLoginAttempt(t)
+
+	current, err := testStore.RecordFailedLoginAttempt(context.Background(), RecordFailedLoginAttemptParams{
+		Key:         old.Key,
+		ResetBefore: time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), current.FailedCount)
+
+	// failures older than reset_before start counting again
+	current, err = testStore.RecordFailedLoginAttempt(context.Background(), RecordFailedLoginAttemptParams{
+		Key:         old.Key,
+		ResetBefore: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), current.FailedCount)
+}
+
+func TestGetLoginAttempt(t *testing.T) {
+	loginAttempt1 := createRandomLoginAttempt(t)
+	loginAttempt2, err := testStore.GetLoginAttempt(context.Background(), loginAttempt1.Key)
+	require.NoError(t, err)
+	require.Equal(t, loginAttempt1.Key, loginAttempt2.Key)
+	require.Equal(t, loginAttempt1.FailedCount, loginAttempt2.FailedCount)
+}
+
+func TestLockLoginAttempt(t *testing.T) {
+	loginAttempt := createRandomLoginAttempt(t)
+	lockedUntil := time.Now().Add(time.Minute)
+
+	locked, err := testStore.LockLoginAttempt(context.Background(), LockLoginAttemptParams{
+		Key:         loginAttempt.Key,
+		LockedUntil: lockedUntil,
+	})
+	require.NoError(t, err)
+	require.WithinDuration(t, lockedUntil, locked.LockedUntil, time.Second)
+}
+
+func TestDeleteLoginAttempt(t *testing.T) {
+	loginAttempt := createRandomLoginAttempt(t)
+	err := testStore.DeleteLoginAttempt(context.Background(), loginAttempt.Key)
+	require.NoError(t, err)
+
+	_, err = testStore.GetLoginAttempt(context.Background(), loginAttempt.Key)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomLoginChallenge(t *testing.T, expires time.Duration) LoginChallenge {
+	user := createRandomUser(t)
+	arg := CreateLoginChallengeParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		UserAgent: util.RandomString(4),
+		ClientIp:  util.RandomString(4),
+		ExpiredAt: time.Now().Add(expires),
+	}
+	challenge, err := testStore.CreateLoginChallenge(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, challenge.ID)
+	require.Equal(t, arg.Username, challenge.Username)
+	require.Equal(t, arg.UserAgent, challenge.UserAgent)
+	require.Equal(t, arg.ClientIp, challenge.ClientIp)
+	require.False(t, challenge.IsUsed)
+	require.WithinDuration(t, arg.ExpiredAt, challenge.ExpiredAt, time.Second)
+	return challenge
+}
+
+func TestCreateLoginChallenge(t *testing.T) {
+	createRandomLoginChallenge(t, time.Minute)
+}
+
+func TestUseLoginChallenge(t *testing.T) {
+	challenge := createRandomLoginChallenge(t, time.Minute)
+
+	used, err := testStore.UseLoginChallenge(context.Background(), challenge.ID)
+	require.NoError(t, err)
+	require.Equal(t, challenge.ID, used.ID)
+	require.True(t, used.IsUsed)
+
+	_, err = testStore.UseLoginChallenge(context.Background(), challenge.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseExpiredLoginChallenge(t *testing.T) {
+	challenge := createRandomLoginChallenge(t, -time.Minute)
+	_, err := testStore.UseLoginChallenge(context.Background(), challenge.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
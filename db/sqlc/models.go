@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
@@ -16,6 +17,20 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	Status    string    `json:"status"`
+}
+
+type ApiKey struct {
+	ID         uuid.UUID `json:"id"`
+	Username   string    `json:"username"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	HashedKey  string    `json:"hashed_key"`
+	Scopes     []string  `json:"scopes"`
+	IsRevoked  bool      `json:"is_revoked"`
+	ExpiredAt  time.Time `json:"expired_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -26,6 +41,41 @@ type Entry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type LoginAttempt struct {
+	Key          string    `json:"key"`
+	FailedCount  int32     `json:"failed_count"`
+	LockedUntil  time.Time `json:"locked_until"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type LoginChallenge struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	IsUsed    bool      `json:"is_used"`
+	ExpiredAt time.Time `json:"expired_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	HashedCode string    `json:"hashed_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ResetPassword struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
+}
+
 type Session struct {
 	ID           uuid.UUID `json:"id"`
 	Username     string    `json:"username"`
@@ -47,13 +97,23 @@ type Transfer struct {
 }
 
 type User struct {
-	Username          string    `json:"username"`
-	HashedPassword    string    `json:"hashed_password"`
-	FullName          string    `json:"full_name"`
-	Email             string    `json:"email"`
-	PasswordChangedAt time.Time `json:"password_changed_at"`
-	CreatedAt         time.Time `json:"created_at"`
-	IsEmailVerified   bool      `json:"is_email_verified"`
+	Username          string      `json:"username"`
+	HashedPassword    string      `json:"hashed_password"`
+	FullName          string      `json:"full_name"`
+	Email             string      `json:"email"`
+	PasswordChangedAt time.Time   `json:"password_changed_at"`
+	CreatedAt         time.Time   `json:"created_at"`
+	IsEmailVerified   bool        `json:"is_email_verified"`
+	PendingEmail      pgtype.Text `json:"pending_email"`
+}
+
+type UserTotp struct {
+	Username     string    `json:"username"`
+	Secret       string    `json:"secret"`
+	IsEnabled    bool      `json:"is_enabled"`
+	EnabledAt    time.Time `json:"enabled_at"`
+	LastUsedStep int64     `json:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type VerifyEmail struct {
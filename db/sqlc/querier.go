@@ -11,26 +11,55 @@ import (
 )
 
 type Querier interface {
+	BlockUserSessions(ctx context.Context, username string) error
+	CloseAccount(ctx context.Context, id int64) (Account, error)
+	ConfirmUserEmail(ctx context.Context, arg ConfirmUserEmailParams) (User, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateApiKey(ctx context.Context, arg CreateApiKeyParams) (ApiKey, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateLoginChallenge(ctx context.Context, arg CreateLoginChallengeParams) (LoginChallenge, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateResetPassword(ctx context.Context, arg CreateResetPasswordParams) (ResetPassword, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteLoginAttempt(ctx context.Context, key string) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	EnableUserTotp(ctx context.Context, username string) (UserTotp, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
 	GetAccountsForUpdate(ctx context.Context, ids []int64) ([]Account, error)
+	GetApiKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetLatestVerifyEmail(ctx context.Context, username string) (VerifyEmail, error)
+	GetLoginAttempt(ctx context.Context, key string) (LoginAttempt, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserTotp(ctx context.Context, username string) (UserTotp, error)
+	InvalidateResetPasswords(ctx context.Context, username string) error
+	InvalidateVerifyEmails(ctx context.Context, username string) error
+	ListAccountEntries(ctx context.Context, arg ListAccountEntriesParams) ([]Entry, error)
+	ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListApiKeys(ctx context.Context, username string) ([]ApiKey, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
 	ListTransfer(ctx context.Context, arg ListTransferParams) ([]Transfer, error)
+	LockLoginAttempt(ctx context.Context, arg LockLoginAttemptParams) (LoginAttempt, error)
+	RecordFailedLoginAttempt(ctx context.Context, arg RecordFailedLoginAttemptParams) (LoginAttempt, error)
+	RevokeApiKey(ctx context.Context, arg RevokeApiKeyParams) (ApiKey, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateApiKeyLastUsed(ctx context.Context, id uuid.UUID) error
+	UpdateResetPassword(ctx context.Context, arg UpdateResetPasswordParams) (ResetPassword, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error)
+	UpsertUserTotp(ctx context.Context, arg UpsertUserTotpParams) (UserTotp, error)
+	UseLoginChallenge(ctx context.Context, id uuid.UUID) (LoginChallenge, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	UseUserTotpStep(ctx context.Context, arg UseUserTotpStepParams) (UserTotp, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomRecoveryCode(t *testing.T, username string) RecoveryCode {
+	arg := CreateRecoveryCodeParams{
+		Username:   username,
+		HashedCode: util.RandomString(64),
+	}
+	recoveryCode, err := testStore.CreateRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, recoveryCode.ID)
+	require.Equal(t, arg.Username, recoveryCode.Username)
+	require.Equal(t, arg.HashedCode, recoveryCode.HashedCode)
+	require.False(t, recoveryCode.IsUsed)
+	return recoveryCode
+}
+
+func TestCreateRecoveryCode(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomRecoveryCode(t, user.Username)
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	user := createRandomUser(t)
+	recoveryCode := createRandomRecoveryCode(t, user.Username)
+	arg := UseRecoveryCodeParams{
+		Username:   recoveryCode.Username,
+		HashedCode: recoveryCode.HashedCode,
+	}
+
+	used, err := testStore.UseRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, recoveryCode.ID, used.ID)
+	require.True(t, used.IsUsed)
+
+	_, err = testStore.UseRecoveryCode(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestDeleteRecoveryCodes(t *testing.T) {
+	user := createRandomUser(t)
+	recoveryCode := createRandomRecoveryCode(t, user.Username)
+
+	err := testStore.DeleteRecoveryCodes(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	_, err = testStore.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username:   recoveryCode.Username,
+		HashedCode: recoveryCode.HashedCode,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
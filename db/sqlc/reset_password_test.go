@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomResetPassword(t *testing.T) ResetPassword {
+	user := createRandomUser(t)
+	arg := CreateResetPasswordParams{
+		Username:   user.Username,
+		SecretCode: util.RandomString(32),
+	}
+	resetPassword, err := testStore.CreateResetPassword(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotNil(t, resetPassword)
+	require.Equal(t, arg.Username, resetPassword.Username)
+	require.Equal(t, arg.SecretCode, resetPassword.SecretCode)
+	require.False(t, resetPassword.IsUsed)
+	return resetPassword
+}
+
+func TestCreateResetPassword(t *testing.T) {
+	createRandomResetPassword(t)
+}
+
+func TestUpdateResetPassword(t *testing.T) {
+	old := createRandomResetPassword(t)
+	arg := UpdateResetPasswordParams{
+		ID:         old.ID,
+		SecretCode: old.SecretCode,
+	}
+	current, err := testStore.UpdateResetPassword(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotNil(t, current)
+	require.Equal(t, old.ID, current.ID)
+	require.True(t, current.IsUsed)
+
+	_, err = testStore.UpdateResetPassword(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestInvalidateResetPasswords(t *testing.T) {
+	old := createRandomResetPassword(t)
+
+	err := testStore.InvalidateResetPasswords(context.Background(), old.Username)
+	require.NoError(t, err)
+
+	_, err = testStore.UpdateResetPassword(context.Background(), UpdateResetPasswordParams{
+		ID:         old.ID,
+		SecretCode: old.SecretCode,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
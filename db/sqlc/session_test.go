@@ -47,3 +47,13 @@ func TestGetSession(t *testing.T) {
 	require.Equal(t, session.UserAgent, result.UserAgent)
 	require.Equal(t, session.ClientIp, result.ClientIp)
 }
+
+func TestBlockUserSessions(t *testing.T) {
+	session := createRandomSession(t, time.Minute)
+	err := testStore.BlockUserSessions(context.Background(), session.Username)
+	require.NoError(t, err)
+
+	result, err := testStore.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, result.IsBlocked)
+}
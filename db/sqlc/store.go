@@ -6,12 +6,22 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Backends of the store. The memory backend is db/memstore and keeps
+// nothing across restarts.
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
 //go:generate go run go.uber.org/mock/mockgen -source=store.go -destination=./../mock/store.go
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
 	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error)
 	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	EnableTotpTx(ctx context.Context, arg EnableTotpTxParams) (EnableTotpTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error)
 }
 
 type SQLStore struct {
@@ -0,0 +1,26 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/db/storetest"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLStore(t *testing.T) {
+	config, err := util.LoadConfig("../../.env.test")
+	require.NoError(t, err)
+
+	connPool, err := pgxpool.New(context.Background(), config.DBSource)
+	require.NoError(t, err)
+	t.Cleanup(connPool.Close)
+
+	store := db.NewStore(connPool)
+	storetest.Run(t, func(t *testing.T) db.Store {
+		return store
+	})
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomUserTotp(t *testing.T) UserTotp {
+	user := createRandomUser(t)
+	arg := UpsertUserTotpParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+	}
+	userTotp, err := testStore.UpsertUserTotp(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, userTotp.Username)
+	require.Equal(t, arg.Secret, userTotp.Secret)
+	require.False(t, userTotp.IsEnabled)
+	require.True(t, userTotp.EnabledAt.IsZero())
+	require.NotZero(t, userTotp.CreatedAt)
+	return userTotp
+}
+
+func TestUpsertUserTotp(t *testing.T) {
+	userTotp1 := createRandomUserTotp(t)
+
+	_, err := testStore.EnableUserTotp(context.Background(), userTotp1.Username)
+	require.NoError(t, err)
+
+	arg := UpsertUserTotpParams{
+		Username: userTotp1.Username,
+		Secret:   util.RandomString(32),
+	}
+	userTotp2, err := testStore.UpsertUserTotp(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, userTotp1.Username, userTotp2.Username)
+	require.Equal(t, arg.Secret, userTotp2.Secret)
+	require.False(t, userTotp2.IsEnabled)
+	require.True(t, userTotp2.EnabledAt.IsZero())
+}
+
+func TestGetUserTotp(t *testing.T) {
+	userTotp1 := createRandomUserTotp(t)
+	userTotp2, err := testStore.GetUserTotp(context.Background(), userTotp1.Username)
+	require.NoError(t, err)
+	require.Equal(t, userTotp1.Username, userTotp2.Username)
+	require.Equal(t, userTotp1.Secret, userTotp2.Secret)
+	require.Equal(t, userTotp1.IsEnabled, userTotp2.IsEnabled)
+}
+
+func TestEnableUserTotp(t *testing.T) {
+	userTotp1 := createRandomUserTotp(t)
+	userTotp2, err := testStore.EnableUserTotp(context.Background(), userTotp1.Username)
+	require.NoError(t, err)
+	require.Equal(t, userTotp1.Username, userTotp2.Username)
+	require.Equal(t, userTotp1.Secret, userTotp2.Secret)
+	require.True(t, userTotp2.IsEnabled)
+	require.False(t, userTotp2.EnabledAt.IsZero())
+}
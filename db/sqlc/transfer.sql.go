@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
@@ -56,29 +58,101 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 	return i, err
 }
 
+const listAccountTransfers = `-- name: ListAccountTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+WHERE (from_account_id = $1 OR to_account_id = $1)
+    AND ($2::varchar IS NULL
+        OR ($2 = 'in' AND to_account_id = $1)
+        OR ($2 = 'out' AND from_account_id = $1))
+    AND ($3::bigint IS NULL
+        OR (from_account_id = $1 AND to_account_id = $3)
+        OR (to_account_id = $1 AND from_account_id = $3))
+    AND ($4::timestamptz IS NULL OR created_at >= $4)
+    AND ($5::timestamptz IS NULL OR created_at < $5)
+    AND ($6::bigint IS NULL OR amount >= $6)
+    AND ($7::bigint IS NULL OR amount <= $7)
+    AND ($8::timestamptz IS NULL
+        OR (created_at, id) < ($8::timestamptz, $9::bigint))
+ORDER BY created_at DESC, id DESC
+LIMIT $10
+`
+
+type ListAccountTransfersParams struct {
+	AccountID             int64              `json:"account_id"`
+	Direction             pgtype.Text        `json:"direction"`
+	CounterpartyAccountID pgtype.Int8        `json:"counterparty_account_id"`
+	StartTime             pgtype.Timestamptz `json:"start_time"`
+	EndTime               pgtype.Timestamptz `json:"end_time"`
+	MinAmount             pgtype.Int8        `json:"min_amount"`
+	MaxAmount             pgtype.Int8        `json:"max_amount"`
+	CursorCreatedAt       pgtype.Timestamptz `json:"cursor_created_at"`
+	CursorID              pgtype.Int8        `json:"cursor_id"`
+	Limit                 int32              `json:"limit"`
+}
+
+func (q *Queries) ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.Query(ctx, listAccountTransfers,
+		arg.AccountID,
+		arg.Direction,
+		arg.CounterpartyAccountID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfer = `-- name: ListTransfer :many
 SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
 WHERE
-    from_account_id = $1 OR
-    to_account_id = $2
-ORDER BY id
-LIMIT $3
-OFFSET $4
+    (from_account_id = $1 OR
+    to_account_id = $2)
+    AND ($3::timestamptz IS NULL
+        OR (created_at, id) < ($3::timestamptz, $4::bigint))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
 `
 
 type ListTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Limit         int32 `json:"limit"`
-	Offset        int32 `json:"offset"`
+	FromAccountID   int64              `json:"from_account_id"`
+	ToAccountID     int64              `json:"to_account_id"`
+	CursorCreatedAt pgtype.Timestamptz `json:"cursor_created_at"`
+	CursorID        pgtype.Int8        `json:"cursor_id"`
+	Limit           int32              `json:"limit"`
 }
 
 func (q *Queries) ListTransfer(ctx context.Context, arg ListTransferParams) ([]Transfer, error) {
 	rows, err := q.db.Query(ctx, listTransfer,
 		arg.FromAccountID,
 		arg.ToAccountID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
 		arg.Limit,
-		arg.Offset,
 	)
 	if err != nil {
 		return nil, err
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labasubagia/simplebank/util"
 	"github.com/stretchr/testify/require"
 )
@@ -60,16 +61,69 @@ func TestListTransfers(t *testing.T) {
 		FromAccountID: account1.ID,
 		ToAccountID:   account2.ID,
 		Limit:         5,
-		Offset:        5,
 	}
 
-	entries, err := testStore.ListTransfer(context.Background(), arg)
+	page1, err := testStore.ListTransfer(context.Background(), arg)
 	require.NoError(t, err)
-	require.Len(t, entries, 5)
+	require.Len(t, page1, 5)
 
-	for _, entry := range entries {
-		require.NotEmpty(t, entry)
-		require.Equal(t, account1.ID, entry.FromAccountID)
-		require.Equal(t, account2.ID, entry.ToAccountID)
+	last := page1[len(page1)-1]
+	arg.CursorCreatedAt = pgtype.Timestamptz{Time: last.CreatedAt, Valid: true}
+	arg.CursorID = pgtype.Int8{Int64: last.ID, Valid: true}
+	page2, err := testStore.ListTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, page2, 5)
+
+	seen := make(map[int64]bool)
+	for _, transfer := range append(page1, page2...) {
+		require.NotEmpty(t, transfer)
+		require.Equal(t, account1.ID, transfer.FromAccountID)
+		require.Equal(t, account2.ID, transfer.ToAccountID)
+		require.False(t, seen[transfer.ID])
+		seen[transfer.ID] = true
+	}
+}
+
+func TestListAccountTransfers(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	account3 := createRandomAccount(t)
+
+	for i := 0; i < 3; i++ {
+		createRandomTransfer(t, account1, account2)
+		createRandomTransfer(t, account2, account1)
+		createRandomTransfer(t, account1, account3)
+	}
+
+	arg := ListAccountTransfersParams{
+		AccountID: account1.ID,
+		Limit:     20,
 	}
+	transfers, err := testStore.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 9)
+	for i := 1; i < len(transfers); i++ {
+		require.False(t, transfers[i].CreatedAt.After(transfers[i-1].CreatedAt))
+	}
+
+	arg.Direction = pgtype.Text{String: util.DirectionOut, Valid: true}
+	transfers, err = testStore.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 6)
+	for _, transfer := range transfers {
+		require.Equal(t, account1.ID, transfer.FromAccountID)
+	}
+
+	arg.CounterpartyAccountID = pgtype.Int8{Int64: account2.ID, Valid: true}
+	transfers, err = testStore.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 3)
+	for _, transfer := range transfers {
+		require.Equal(t, account2.ID, transfer.ToAccountID)
+	}
+
+	arg.StartTime = pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true}
+	transfers, err = testStore.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, transfers)
 }
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+)
+
+type EnableTotpTxParams struct {
+	Username            string
+	HashedRecoveryCodes []string
+}
+
+type EnableTotpTxResult struct {
+	UserTotp      UserTotp
+	RecoveryCodes []RecoveryCode
+}
+
+func (store *SQLStore) EnableTotpTx(ctx context.Context, arg EnableTotpTxParams) (EnableTotpTxResult, error) {
+	var result EnableTotpTxResult
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.UserTotp, err = q.EnableUserTotp(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		for _, hashedCode := range arg.HashedRecoveryCodes {
+			recoveryCode, err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username:   arg.Username,
+				HashedCode: hashedCode,
+			})
+			if err != nil {
+				return err
+			}
+			result.RecoveryCodes = append(result.RecoveryCodes, recoveryCode)
+		}
+
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableTotpTx(t *testing.T) {
+	userTotp := createRandomUserTotp(t)
+	oldCode := createRandomRecoveryCode(t, userTotp.Username)
+
+	arg := EnableTotpTxParams{
+		Username:            userTotp.Username,
+		HashedRecoveryCodes: []string{util.RandomString(64), util.RandomString(64)},
+	}
+	result, err := testStore.EnableTotpTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.UserTotp.IsEnabled)
+	require.Len(t, result.RecoveryCodes, len(arg.HashedRecoveryCodes))
+	for i, recoveryCode := range result.RecoveryCodes {
+		require.Equal(t, arg.Username, recoveryCode.Username)
+		require.Equal(t, arg.HashedRecoveryCodes[i], recoveryCode.HashedCode)
+		require.False(t, recoveryCode.IsUsed)
+	}
+
+	_, err = testStore.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username:   oldCode.Username,
+		HashedCode: oldCode.HashedCode,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ResetPasswordTxParams struct {
+	ResetPasswordID int64
+	SecretCode      string
+	HashedPassword  string
+}
+
+type ResetPasswordTxResult struct {
+	User          User
+	ResetPassword ResetPassword
+}
+
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error) {
+	var result ResetPasswordTxResult
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.ResetPassword, err = q.UpdateResetPassword(ctx, UpdateResetPasswordParams{
+			ID:         arg.ResetPasswordID,
+			SecretCode: arg.SecretCode,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
+			Username: result.ResetPassword.Username,
+			HashedPassword: pgtype.Text{
+				String: arg.HashedPassword,
+				Valid:  true,
+			},
+			PasswordChangedAt: pgtype.Timestamptz{
+				Time:  time.Now(),
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.BlockUserSessions(ctx, result.User.Username)
+	})
+	return result, err
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetPasswordTx(t *testing.T) {
+	resetPassword := createRandomResetPassword(t)
+	session, err := testStore.CreateSession(context.Background(), CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     resetPassword.Username,
+		RefreshToken: util.RandomString(32),
+		ExpiredAt:    time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		ResetPasswordID: resetPassword.ID,
+		SecretCode:      resetPassword.SecretCode,
+		HashedPassword:  hashedPassword,
+	}
+	result, err := testStore.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.ResetPassword.IsUsed)
+	require.Equal(t, resetPassword.Username, result.User.Username)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.WithinDuration(t, time.Now(), result.User.PasswordChangedAt, time.Second)
+
+	session, err = testStore.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+}
@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"errors"
+
+	"github.com/labasubagia/simplebank/util"
 )
 
 type TransferTxParams struct {
@@ -40,6 +42,13 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 		if !ok {
 			return errors.New("to account not found")
 		}
+		// an account can be closed after the caller checked it
+		if fromAccount.Status != util.AccountStatusActive || toAccount.Status != util.AccountStatusActive {
+			return ErrAccountClosed
+		}
+		if fromAccount.Balance < arg.Amount {
+			return ErrInsufficientFunds
+		}
 
 		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
 		if err != nil {
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+)
+
+// UpdateUserTxParams updates the user and runs AfterUpdate in the same
+// transaction. Setting HashedPassword also blocks the user's sessions.
+type UpdateUserTxParams struct {
+	UpdateUserParams
+	AfterUpdate func(user User) error
+}
+
+type UpdateUserTxResult struct {
+	User User
+}
+
+func (store *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error) {
+	var result UpdateUserTxResult
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.User, err = q.UpdateUser(ctx, arg.UpdateUserParams)
+		if err != nil {
+			return err
+		}
+		if arg.HashedPassword.Valid {
+			if err := q.BlockUserSessions(ctx, result.User.Username); err != nil {
+				return err
+			}
+		}
+		if arg.AfterUpdate == nil {
+			return nil
+		}
+		return arg.AfterUpdate(result.User)
+	})
+	return result, err
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateUserTx(t *testing.T) {
+	user := createRandomUser(t)
+	pendingEmail := util.RandomEmail()
+
+	arg := UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username:     user.Username,
+			PendingEmail: pgtype.Text{String: pendingEmail, Valid: true},
+		},
+		AfterUpdate: func(updated User) error {
+			require.Equal(t, pendingEmail, updated.PendingEmail.String)
+			return nil
+		},
+	}
+	result, err := testStore.UpdateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Email, result.User.Email)
+	require.Equal(t, pendingEmail, result.User.PendingEmail.String)
+}
+
+func TestUpdateUserTxRollback(t *testing.T) {
+	user := createRandomUser(t)
+
+	arg := UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username:     user.Username,
+			PendingEmail: pgtype.Text{String: util.RandomEmail(), Valid: true},
+		},
+		AfterUpdate: func(updated User) error {
+			return errors.New("after update failed")
+		},
+	}
+	_, err := testStore.UpdateUserTx(context.Background(), arg)
+	require.Error(t, err)
+
+	user2, err := testStore.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.False(t, user2.PendingEmail.Valid)
+}
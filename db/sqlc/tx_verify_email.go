@@ -2,8 +2,6 @@ package db
 
 import (
 	"context"
-
-	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type VerifyEmailTxParams struct {
@@ -29,12 +27,11 @@ func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParam
 			return err
 		}
 
-		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
+		// The code may confirm the current email or a pending change; a code
+		// for a pending email that was since replaced matches no row.
+		result.User, err = q.ConfirmUserEmail(ctx, ConfirmUserEmailParams{
 			Username: result.VerifyEmail.Username,
-			IsEmailVerified: pgtype.Bool{
-				Bool:  true,
-				Valid: true,
-			},
+			Email:    result.VerifyEmail.Email,
 		})
 
 		return err
@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labasubagia/simplebank/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,3 +23,58 @@ func TestVerifyEmailTx(t *testing.T) {
 	require.True(t, result.VerifyEmail.IsUsed)
 	require.True(t, result.User.IsEmailVerified)
 }
+
+func TestVerifyEmailTxPendingEmail(t *testing.T) {
+	user := createRandomUser(t)
+	pendingEmail := util.RandomEmail()
+
+	_, err := testStore.UpdateUser(context.Background(), UpdateUserParams{
+		Username:     user.Username,
+		PendingEmail: pgtype.Text{String: pendingEmail, Valid: true},
+	})
+	require.NoError(t, err)
+
+	verifyEmail, err := testStore.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      pendingEmail,
+		SecretCode: util.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	result, err := testStore.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.NoError(t, err)
+	require.Equal(t, pendingEmail, result.User.Email)
+	require.False(t, result.User.PendingEmail.Valid)
+	require.True(t, result.User.IsEmailVerified)
+}
+
+func TestVerifyEmailTxSupersededEmail(t *testing.T) {
+	user := createRandomUser(t)
+
+	verifyEmail, err := testStore.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      util.RandomEmail(),
+		SecretCode: util.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.UpdateUser(context.Background(), UpdateUserParams{
+		Username:     user.Username,
+		PendingEmail: pgtype.Text{String: util.RandomEmail(), Valid: true},
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.Error(t, err)
+
+	user2, err := testStore.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.Email, user2.Email)
+	require.False(t, user2.IsEmailVerified)
+}
@@ -11,6 +11,39 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const confirmUserEmail = `-- name: ConfirmUserEmail :one
+UPDATE users
+SET
+    email = $1,
+    pending_email = CASE WHEN pending_email = $1 THEN NULL ELSE pending_email END,
+    is_email_verified = true
+WHERE
+    username = $2
+    AND (email = $1 OR pending_email = $1)
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, pending_email
+`
+
+type ConfirmUserEmailParams struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) ConfirmUserEmail(ctx context.Context, arg ConfirmUserEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, confirmUserEmail, arg.Email, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.PendingEmail,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (
     username,
@@ -19,7 +52,7 @@ INSERT INTO users (
     email
 ) VALUES (
     $1, $2, $3, $4
-) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified
+) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, pending_email
 `
 
 type CreateUserParams struct {
@@ -45,12 +78,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
 		&i.IsEmailVerified,
+		&i.PendingEmail,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified FROM users
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, pending_email FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -65,6 +99,30 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
 		&i.IsEmailVerified,
+		&i.PendingEmail,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, pending_email FROM users
+WHERE email = $1
+ORDER BY created_at
+LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.PendingEmail,
 	)
 	return i, err
 }
@@ -76,10 +134,11 @@ SET
     full_name = COALESCE($2, full_name),
     hashed_password = COALESCE($3, hashed_password),
     password_changed_at = COALESCE($4, password_changed_at),
-    is_email_verified = COALESCE($5, is_email_verified)
+    is_email_verified = COALESCE($5, is_email_verified),
+    pending_email = COALESCE($6, pending_email)
 WHERE
-    username = $6
-RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified
+    username = $7
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, pending_email
 `
 
 type UpdateUserParams struct {
@@ -88,6 +147,7 @@ type UpdateUserParams struct {
 	HashedPassword    pgtype.Text        `json:"hashed_password"`
 	PasswordChangedAt pgtype.Timestamptz `json:"password_changed_at"`
 	IsEmailVerified   pgtype.Bool        `json:"is_email_verified"`
+	PendingEmail      pgtype.Text        `json:"pending_email"`
 	Username          string             `json:"username"`
 }
 
@@ -98,6 +158,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		arg.HashedPassword,
 		arg.PasswordChangedAt,
 		arg.IsEmailVerified,
+		arg.PendingEmail,
 		arg.Username,
 	)
 	var i User
@@ -109,6 +170,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
 		&i.IsEmailVerified,
+		&i.PendingEmail,
 	)
 	return i, err
 }
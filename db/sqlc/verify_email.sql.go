@@ -38,6 +38,43 @@ func (q *Queries) CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailPa
 	return i, err
 }
 
+const getLatestVerifyEmail = `-- name: GetLatestVerifyEmail :one
+SELECT id, username, email, secret_code, is_used, created_at, expired_at FROM verify_emails
+WHERE username = $1
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestVerifyEmail(ctx context.Context, username string) (VerifyEmail, error) {
+	row := q.db.QueryRow(ctx, getLatestVerifyEmail, username)
+	var i VerifyEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.SecretCode,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
+
+const invalidateVerifyEmails = `-- name: InvalidateVerifyEmails :exec
+UPDATE verify_emails
+SET
+    expired_at = now()
+WHERE
+    username = $1
+    AND is_used = false
+    AND expired_at > now()
+`
+
+func (q *Queries) InvalidateVerifyEmails(ctx context.Context, username string) error {
+	_, err := q.db.Exec(ctx, invalidateVerifyEmails, username)
+	return err
+}
+
 const updateVerifyEmail = `-- name: UpdateVerifyEmail :one
 UPDATE verify_emails
 SET
@@ -12,7 +12,7 @@ func createRandomVerifyEmail(t *testing.T) VerifyEmail {
 	user := createRandomUser(t)
 	arg := CreateVerifyEmailParams{
 		Username:   user.Username,
-		Email:      util.RandomEmail(),
+		Email:      user.Email,
 		SecretCode: util.RandomString(32),
 	}
 	verifyEmail, err := testStore.CreateVerifyEmail(context.Background(), arg)
@@ -42,3 +42,30 @@ func TestUpdateVerifyEmail(t *testing.T) {
 	require.False(t, old.IsUsed)
 	require.True(t, current.IsUsed)
 }
+
+func TestGetLatestVerifyEmail(t *testing.T) {
+	old := createRandomVerifyEmail(t)
+	latest, err := testStore.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   old.Username,
+		Email:      old.Email,
+		SecretCode: util.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	got, err := testStore.GetLatestVerifyEmail(context.Background(), old.Username)
+	require.NoError(t, err)
+	require.Equal(t, latest.ID, got.ID)
+}
+
+func TestInvalidateVerifyEmails(t *testing.T) {
+	old := createRandomVerifyEmail(t)
+
+	err := testStore.InvalidateVerifyEmails(context.Background(), old.Username)
+	require.NoError(t, err)
+
+	_, err = testStore.UpdateVerifyEmail(context.Background(), UpdateVerifyEmailParams{
+		ID:         old.ID,
+		SecretCode: old.SecretCode,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,633 @@
+// Package storetest is a conformance suite for db.Store implementations. It
+// only creates random rows of its own, so it can run against a shared
+// database.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the suite against the stores returned by newStore.
+func Run(t *testing.T, newStore func(t *testing.T) db.Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store db.Store)
+	}{
+		{"User", testUser},
+		{"UserUniqueViolation", testUserUniqueViolation},
+		{"UpdateUser", testUpdateUser},
+		{"AccountForeignKeyViolation", testAccountForeignKeyViolation},
+		{"AccountUniqueViolation", testAccountUniqueViolation},
+		{"ListAccounts", testListAccounts},
+		{"CloseAccount", testCloseAccount},
+		{"DeleteReferencedAccount", testDeleteReferencedAccount},
+		{"ListAccountEntries", testListAccountEntries},
+		{"SessionConstraints", testSessionConstraints},
+		{"ApiKeyPrefixUniqueViolation", testApiKeyPrefixUniqueViolation},
+		{"RecordFailedLoginAttempt", testRecordFailedLoginAttempt},
+		{"TransferTx", testTransferTx},
+		{"TransferTxDeadlock", testTransferTxDeadlock},
+		{"TransferTxInsufficientFunds", testTransferTxInsufficientFunds},
+		{"TransferTxAccountClosed", testTransferTxAccountClosed},
+		{"CreateUserTxRollback", testCreateUserTxRollback},
+		{"VerifyEmailTx", testVerifyEmailTx},
+		{"EnableTotpTx", testEnableTotpTx},
+		{"UseUserTotpStep", testUseUserTotpStep},
+		{"ResetPasswordTx", testResetPasswordTx},
+		{"UpdateUserTxPasswordBlocksSessions", testUpdateUserTxPasswordBlocksSessions},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newStore(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, store db.Store) db.User {
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: util.RandomString(32),
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	return user
+}
+
+func createAccount(t *testing.T, store db.Store, owner string, currency string, balance int64) db.Account {
+	account, err := store.CreateAccount(context.Background(), db.CreateAccountParams{
+		Owner:    owner,
+		Balance:  balance,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func createRandomAccount(t *testing.T, store db.Store, balance int64) db.Account {
+	return createAccount(t, store, createUser(t, store).Username, util.RandomCurrency(), balance)
+}
+
+func testUser(t *testing.T, store db.Store) {
+	user1 := createUser(t, store)
+	require.NotZero(t, user1.CreatedAt)
+	require.True(t, user1.PasswordChangedAt.IsZero())
+	require.False(t, user1.IsEmailVerified)
+	require.False(t, user1.PendingEmail.Valid)
+
+	user2, err := store.GetUser(context.Background(), user1.Username)
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, user1.Email, user2.Email)
+	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
+
+	user3, err := store.GetUserByEmail(context.Background(), user1.Email)
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user3.Username)
+
+	_, err = store.GetUser(context.Background(), util.RandomString(12))
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testUserUniqueViolation(t *testing.T, store db.Store) {
+	user := createUser(t, store)
+
+	_, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       user.Username,
+		HashedPassword: util.RandomString(32),
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.Equal(t, db.UniqueViolation, db.ErrorCode(err))
+}
+
+func testUpdateUser(t *testing.T, store db.Store) {
+	user := createUser(t, store)
+	newFullName := util.RandomOwner()
+
+	updated, err := store.UpdateUser(context.Background(), db.UpdateUserParams{
+		Username: user.Username,
+		FullName: pgtype.Text{String: newFullName, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newFullName, updated.FullName)
+	require.Equal(t, user.Email, updated.Email)
+	require.Equal(t, user.HashedPassword, updated.HashedPassword)
+
+	_, err = store.UpdateUser(context.Background(), db.UpdateUserParams{
+		Username: util.RandomString(12),
+		FullName: pgtype.Text{String: newFullName, Valid: true},
+	})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testAccountForeignKeyViolation(t *testing.T, store db.Store) {
+	_, err := store.CreateAccount(context.Background(), db.CreateAccountParams{
+		Owner:    util.RandomString(12),
+		Currency: util.USD,
+	})
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+
+	_, err = store.CreateEntry(context.Background(), db.CreateEntryParams{
+		AccountID: -1,
+		Amount:    10,
+	})
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+
+	account := createRandomAccount(t, store, 0)
+	_, err = store.CreateTransfer(context.Background(), db.CreateTransferParams{
+		FromAccountID: account.ID,
+		ToAccountID:   -1,
+		Amount:        10,
+	})
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+}
+
+func testAccountUniqueViolation(t *testing.T, store db.Store) {
+	account := createRandomAccount(t, store, 0)
+
+	_, err := store.CreateAccount(context.Background(), db.CreateAccountParams{
+		Owner:    account.Owner,
+		Currency: account.Currency,
+	})
+	require.Equal(t, db.UniqueViolation, db.ErrorCode(err))
+}
+
+func testListAccounts(t *testing.T, store db.Store) {
+	user := createUser(t, store)
+	var accounts []db.Account
+	for _, currency := range []string{util.USD, util.EUR, util.IDR} {
+		accounts = append(accounts, createAccount(t, store, user.Username, currency, 0))
+	}
+
+	firstPage, err := store.ListAccounts(context.Background(), db.ListAccountsParams{
+		Owner: user.Username,
+		Limit: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, firstPage, 2)
+	require.Equal(t, accounts[0].ID, firstPage[0].ID)
+	require.Equal(t, accounts[1].ID, firstPage[1].ID)
+
+	last := firstPage[len(firstPage)-1]
+	secondPage, err := store.ListAccounts(context.Background(), db.ListAccountsParams{
+		Owner:           user.Username,
+		CursorCreatedAt: pgtype.Timestamptz{Time: last.CreatedAt, Valid: true},
+		CursorID:        pgtype.Int8{Int64: last.ID, Valid: true},
+		Limit:           2,
+	})
+	require.NoError(t, err)
+	require.Len(t, secondPage, 1)
+	require.Equal(t, accounts[2].ID, secondPage[0].ID)
+
+	filtered, err := store.ListAccounts(context.Background(), db.ListAccountsParams{
+		Owner:    user.Username,
+		Currency: pgtype.Text{String: util.EUR, Valid: true},
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	require.Equal(t, accounts[1].ID, filtered[0].ID)
+}
+
+func testCloseAccount(t *testing.T, store db.Store) {
+	funded := createRandomAccount(t, store, 10)
+	_, err := store.CloseAccount(context.Background(), funded.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	empty := createRandomAccount(t, store, 0)
+	closed, err := store.CloseAccount(context.Background(), empty.ID)
+	require.NoError(t, err)
+	require.Equal(t, util.AccountStatusClosed, closed.Status)
+
+	_, err = store.CloseAccount(context.Background(), empty.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testDeleteReferencedAccount(t *testing.T, store db.Store) {
+	account := createRandomAccount(t, store, 0)
+	_, err := store.CreateEntry(context.Background(), db.CreateEntryParams{
+		AccountID: account.ID,
+		Amount:    10,
+	})
+	require.NoError(t, err)
+
+	err = store.DeleteAccount(context.Background(), account.ID)
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+
+	_, err = store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+
+	unused := createRandomAccount(t, store, 0)
+	require.NoError(t, store.DeleteAccount(context.Background(), unused.ID))
+	_, err = store.GetAccount(context.Background(), unused.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testListAccountEntries(t *testing.T, store db.Store) {
+	account := createRandomAccount(t, store, 0)
+	var entries []db.Entry
+	for _, amount := range []int64{10, -20, 30} {
+		entry, err := store.CreateEntry(context.Background(), db.CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    amount,
+		})
+		require.NoError(t, err)
+		entries = append(entries, entry)
+	}
+
+	all, err := store.ListAccountEntries(context.Background(), db.ListAccountEntriesParams{
+		AccountID: account.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	require.Equal(t, entries[2].ID, all[0].ID)
+	require.Equal(t, entries[0].ID, all[2].ID)
+
+	out, err := store.ListAccountEntries(context.Background(), db.ListAccountEntriesParams{
+		AccountID: account.ID,
+		Direction: pgtype.Text{String: "out", Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	require.Equal(t, entries[1].ID, out[0].ID)
+
+	large, err := store.ListAccountEntries(context.Background(), db.ListAccountEntriesParams{
+		AccountID: account.ID,
+		MinAmount: pgtype.Int8{Int64: 20, Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, large, 2)
+}
+
+func testSessionConstraints(t *testing.T, store db.Store) {
+	user := createUser(t, store)
+	arg := db.CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		RefreshToken: util.RandomString(32),
+		ExpiredAt:    time.Now().Add(time.Minute),
+	}
+	_, err := store.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+
+	_, err = store.CreateSession(context.Background(), arg)
+	require.Equal(t, db.UniqueViolation, db.ErrorCode(err))
+
+	arg.ID = uuid.New()
+	arg.Username = util.RandomString(12)
+	_, err = store.CreateSession(context.Background(), arg)
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+}
+
+func testApiKeyPrefixUniqueViolation(t *testing.T, store db.Store) {
+	user := createUser(t, store)
+	arg := db.CreateApiKeyParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		Name:      util.RandomOwner(),
+		Prefix:    util.RandomString(12),
+		HashedKey: util.RandomString(64),
+		Scopes:    []string{"accounts:read"},
+		ExpiredAt: time.Now().Add(time.Hour),
+	}
+	_, err := store.CreateApiKey(context.Background(), arg)
+	require.NoError(t, err)
+
+	arg.ID = uuid.New()
+	_, err = store.CreateApiKey(context.Background(), arg)
+	require.Equal(t, db.UniqueViolation, db.ErrorCode(err))
+
+	apiKeys, err := store.ListApiKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+}
+
+func testRecordFailedLoginAttempt(t *testing.T, store db.Store) {
+	key := util.RandomString(12)
+
+	for i := 1; i <= 2; i++ {
+		attempt, err := store.RecordFailedLoginAttempt(context.Background(), db.RecordFailedLoginAttemptParams{
+			Key:         key,
+			ResetBefore: time.Now().Add(-time.Hour),
+		})
+		require.NoError(t, err)
+		require.Equal(t, int32(i), attempt.FailedCount)
+	}
+
+	attempt, err := store.RecordFailedLoginAttempt(context.Background(), db.RecordFailedLoginAttemptParams{
+		Key:         key,
+		ResetBefore: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), attempt.FailedCount)
+
+	require.NoError(t, store.DeleteLoginAttempt(context.Background(), key))
+	_, err = store.GetLoginAttempt(context.Background(), key)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testTransferTx(t *testing.T, store db.Store) {
+	account1 := createRandomAccount(t, store, 1000)
+	account2 := createRandomAccount(t, store, 1000)
+
+	n := 5
+	amount := int64(10)
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), db.TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-int64(n)*amount, updatedAccount1.Balance)
+	require.Equal(t, account2.Balance+int64(n)*amount, updatedAccount2.Balance)
+
+	entries, err := store.ListEntries(context.Background(), db.ListEntriesParams{
+		AccountID: account1.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, n)
+
+	transfers, err := store.ListAccountTransfers(context.Background(), db.ListAccountTransfersParams{
+		AccountID: account2.ID,
+		Direction: pgtype.Text{String: "in", Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, n)
+}
+
+func testTransferTxDeadlock(t *testing.T, store db.Store) {
+	account1 := createRandomAccount(t, store, 1000)
+	account2 := createRandomAccount(t, store, 1000)
+
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		fromAccountID, toAccountID := account1.ID, account2.ID
+		if i%2 == 1 {
+			fromAccountID, toAccountID = account2.ID, account1.ID
+		}
+		go func() {
+			_, err := store.TransferTx(context.Background(), db.TransferTxParams{
+				FromAccountID: fromAccountID,
+				ToAccountID:   toAccountID,
+				Amount:        10,
+			})
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func testTransferTxInsufficientFunds(t *testing.T, store db.Store) {
+	account1 := createRandomAccount(t, store, 10)
+	account2 := createRandomAccount(t, store, 0)
+
+	_, err := store.TransferTx(context.Background(), db.TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        11,
+	})
+	require.ErrorIs(t, err, db.ErrInsufficientFunds)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	entries, err := store.ListEntries(context.Background(), db.ListEntriesParams{
+		AccountID: account1.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func testTransferTxAccountClosed(t *testing.T, store db.Store) {
+	account1 := createRandomAccount(t, store, 10)
+	account2 := createRandomAccount(t, store, 0)
+	_, err := store.CloseAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), db.TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        5,
+	})
+	require.ErrorIs(t, err, db.ErrAccountClosed)
+
+	_, err = store.TransferTx(context.Background(), db.TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        0,
+	})
+	require.ErrorIs(t, err, db.ErrAccountClosed)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
+
+func testCreateUserTxRollback(t *testing.T, store db.Store) {
+	errAfterCreate := errors.New("after create failed")
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       util.RandomOwner(),
+			HashedPassword: util.RandomString(32),
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomEmail(),
+		},
+		AfterCreate: func(user db.User) error {
+			return errAfterCreate
+		},
+	}
+	_, err := store.CreateUserTx(context.Background(), arg)
+	require.ErrorIs(t, err, errAfterCreate)
+
+	_, err = store.GetUser(context.Background(), arg.Username)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testVerifyEmailTx(t *testing.T, store db.Store) {
+	user := createUser(t, store)
+	verifyEmail, err := store.CreateVerifyEmail(context.Background(), db.CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+	})
+	require.NoError(t, err)
+	require.WithinDuration(t, verifyEmail.CreatedAt.Add(15*time.Minute), verifyEmail.ExpiredAt, time.Second)
+
+	arg := db.VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	}
+	result, err := store.VerifyEmailTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.VerifyEmail.IsUsed)
+	require.True(t, result.User.IsEmailVerified)
+
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testEnableTotpTx(t *testing.T, store db.Store) {
+	user := createUser(t, store)
+	_, err := store.UpsertUserTotp(context.Background(), db.UpsertUserTotpParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	result, err := store.EnableTotpTx(context.Background(), db.EnableTotpTxParams{
+		Username:            user.Username,
+		HashedRecoveryCodes: []string{util.RandomString(64), util.RandomString(64)},
+	})
+	require.NoError(t, err)
+	require.True(t, result.UserTotp.IsEnabled)
+	require.Len(t, result.RecoveryCodes, 2)
+
+	used, err := store.UseRecoveryCode(context.Background(), db.UseRecoveryCodeParams{
+		Username:   user.Username,
+		HashedCode: result.RecoveryCodes[0].HashedCode,
+	})
+	require.NoError(t, err)
+	require.True(t, used.IsUsed)
+
+	_, err = store.UseRecoveryCode(context.Background(), db.UseRecoveryCodeParams{
+		Username:   user.Username,
+		HashedCode: result.RecoveryCodes[0].HashedCode,
+	})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testUseUserTotpStep(t *testing.T, store db.Store) {
+	user := createUser(t, store)
+	_, err := store.UpsertUserTotp(context.Background(), db.UpsertUserTotpParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	arg := db.UseUserTotpStepParams{
+		Username:     user.Username,
+		LastUsedStep: time.Now().Unix() / 30,
+	}
+	userTotp, err := store.UseUserTotpStep(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.LastUsedStep, userTotp.LastUsedStep)
+
+	_, err = store.UseUserTotpStep(context.Background(), arg)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	arg.LastUsedStep--
+	_, err = store.UseUserTotpStep(context.Background(), arg)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	// setting up a new secret keeps the last step, codes are time based
+	userTotp, err = store.UpsertUserTotp(context.Background(), db.UpsertUserTotpParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.LastUsedStep+1, userTotp.LastUsedStep)
+}
+
+func testUpdateUserTxPasswordBlocksSessions(t *testing.T, store db.Store) {
+	user := createUser(t, store)
+	session, err := store.CreateSession(context.Background(), db.CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		RefreshToken: util.RandomString(32),
+		ExpiredAt:    time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = store.UpdateUserTx(context.Background(), db.UpdateUserTxParams{
+		UpdateUserParams: db.UpdateUserParams{
+			Username: user.Username,
+			FullName: pgtype.Text{String: util.RandomOwner(), Valid: true},
+		},
+	})
+	require.NoError(t, err)
+	session, err = store.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.False(t, session.IsBlocked)
+
+	_, err = store.UpdateUserTx(context.Background(), db.UpdateUserTxParams{
+		UpdateUserParams: db.UpdateUserParams{
+			Username:       user.Username,
+			HashedPassword: pgtype.Text{String: util.RandomString(32), Valid: true},
+		},
+	})
+	require.NoError(t, err)
+	session, err = store.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+}
+
+func testResetPasswordTx(t *testing.T, store db.Store) {
+	user := createUser(t, store)
+	resetPassword, err := store.CreateResetPassword(context.Background(), db.CreateResetPasswordParams{
+		Username:   user.Username,
+		SecretCode: util.RandomString(32),
+	})
+	require.NoError(t, err)
+	session, err := store.CreateSession(context.Background(), db.CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		RefreshToken: util.RandomString(32),
+		ExpiredAt:    time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	hashedPassword := util.RandomString(32)
+	result, err := store.ResetPasswordTx(context.Background(), db.ResetPasswordTxParams{
+		ResetPasswordID: resetPassword.ID,
+		SecretCode:      resetPassword.SecretCode,
+		HashedPassword:  hashedPassword,
+	})
+	require.NoError(t, err)
+	require.True(t, result.ResetPassword.IsUsed)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+
+	session, err = store.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+}
@@ -12,35 +12,39 @@ import (
 const (
 	authorizationHeader = "authorization"
 	authorizationBearer = "bearer"
+	authorizationApiKey = "apikey"
 )
 
-func (server *Server) authorizeUser(ctx context.Context) (*token.Payload, error) {
+// authorizeUser accepts a bearer access token, or an api key holding every
+// given scope. Endpoints that list no scopes can only be used with a token.
+func (server *Server) authorizeUser(ctx context.Context, scopes ...string) (*token.Payload, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, fmt.Errorf("missing metadata")
+		return nil, unauthenticatedError(fmt.Errorf("missing metadata"))
 	}
 
 	values := md.Get(authorizationHeader)
 	if len(values) == 0 {
-		return nil, fmt.Errorf("missing authorization header")
+		return nil, unauthenticatedError(fmt.Errorf("missing authorization header"))
 	}
 
 	authHeader := values[0]
 	fields := strings.Fields(authHeader)
 	if len(fields) < 2 {
-		return nil, fmt.Errorf("invalid authorization header format")
+		return nil, unauthenticatedError(fmt.Errorf("invalid authorization header format"))
 	}
 
 	authType := strings.ToLower(fields[0])
-	if authType != authorizationBearer {
-		return nil, fmt.Errorf("unsupported authorization type: %s", authType)
+	switch authType {
+	case authorizationBearer:
+		accessToken := fields[1]
+		payload, err := server.tokenMaker.VerifyToken(accessToken)
+		if err != nil {
+			return nil, unauthenticatedError(fmt.Errorf("invalid access token: %s", err))
+		}
+		return payload, nil
+	case authorizationApiKey:
+		return server.authService.VerifyApiKey(ctx, fields[1], scopes)
 	}
-
-	accessToken := fields[1]
-	payload, err := server.tokenMaker.VerifyToken(accessToken)
-	if err != nil {
-		return nil, fmt.Errorf("invalid access token: %s", err)
-	}
-
-	return payload, nil
+	return nil, unauthenticatedError(fmt.Errorf("unsupported authorization type: %s", authType))
 }
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+
+	"github.com/labasubagia/simplebank/util/certs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// gatewayIdentity is the identity of the proxying gateway, which presents
+// the server's own certificate. Its calls are made on behalf of HTTP
+// clients, so they are told apart by the forwarded client address.
+const gatewayIdentity = "gateway"
+
+type clientIdentityKey struct{}
+
+func (server *Server) ClientIdentityUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := server.withClientIdentity(ctx, peerTLSState(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (server *Server) ClientIdentityStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := server.withClientIdentity(stream.Context(), peerTLSState(stream.Context()))
+	if err != nil {
+		return err
+	}
+	return handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+}
+
+// HttpClientIdentity does for gateway requests what the client identity
+// interceptors do for gRPC calls.
+func (server *Server) HttpClientIdentity(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ctx, err := server.withClientIdentity(req.Context(), req.TLS)
+		if err != nil {
+			http.Error(res, status.Convert(err).Message(), http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(res, req.WithContext(ctx))
+	})
+}
+
+// withClientIdentity maps the verified client certificate of the connection
+// to the service identity configured for its subject. Connections without a
+// client certificate get no identity; when identities are configured,
+// certificates with an unknown subject are refused.
+func (server *Server) withClientIdentity(ctx context.Context, state *tls.ConnectionState) (context.Context, error) {
+	if state == nil {
+		return ctx, nil
+	}
+	subject, ok := certs.ClientSubject(*state)
+	if !ok {
+		return ctx, nil
+	}
+
+	identity, ok := server.clientIdentities[subject]
+	if !ok {
+		if len(server.clientIdentities) == 0 {
+			return ctx, nil
+		}
+		return ctx, status.Errorf(codes.PermissionDenied, "client certificate %q is not allowed", subject)
+	}
+	return context.WithValue(ctx, clientIdentityKey{}, identity), nil
+}
+
+func peerTLSState(ctx context.Context) *tls.ConnectionState {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return &tlsInfo.State
+}
+
+func clientIdentityFromContext(ctx context.Context) string {
+	identity, _ := ctx.Value(clientIdentityKey{}).(string)
+	return identity
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labasubagia/simplebank/util/certs"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithClientIdentity(t *testing.T) {
+	testCases := []struct {
+		name        string
+		identities  map[string]string
+		state       *tls.ConnectionState
+		checkResult func(t *testing.T, ctx context.Context, err error)
+	}{
+		{
+			name:       "Plaintext",
+			identities: map[string]string{"worker.internal": "worker"},
+			state:      nil,
+			checkResult: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+				require.Empty(t, clientIdentityFromContext(ctx))
+			},
+		},
+		{
+			name:       "NoClientCertificate",
+			identities: map[string]string{"worker.internal": "worker"},
+			state:      &tls.ConnectionState{},
+			checkResult: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+				require.Empty(t, clientIdentityFromContext(ctx))
+			},
+		},
+		{
+			name:       "KnownSubject",
+			identities: map[string]string{"worker.internal": "worker"},
+			state:      clientCertificateState("worker.internal"),
+			checkResult: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "worker", clientIdentityFromContext(ctx))
+			},
+		},
+		{
+			name:       "UnknownSubject",
+			identities: map[string]string{"worker.internal": "worker"},
+			state:      clientCertificateState("intruder.internal"),
+			checkResult: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name:       "NoIdentitiesConfigured",
+			identities: map[string]string{},
+			state:      clientCertificateState("worker.internal"),
+			checkResult: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+				require.Empty(t, clientIdentityFromContext(ctx))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil, nil)
+			server.clientIdentities = tc.identities
+
+			ctx, err := server.withClientIdentity(context.Background(), tc.state)
+			tc.checkResult(t, ctx, err)
+		})
+	}
+}
+
+func TestHttpClientIdentity(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+	server.clientIdentities = map[string]string{"worker.internal": "worker"}
+
+	var identity string
+	handler := server.HttpClientIdentity(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		identity = clientIdentityFromContext(req.Context())
+	}))
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/v1/accounts", nil)
+	request.TLS = clientCertificateState("worker.internal")
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "worker", identity)
+
+	recorder = httptest.NewRecorder()
+	request.TLS = clientCertificateState("intruder.internal")
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestGatewayClientIdentity(t *testing.T) {
+	dir := t.TempDir()
+	certificates, err := certs.GenerateDev([]string{"localhost"}, "worker.internal", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, certificates.WriteFiles(dir))
+
+	config := newTestConfig()
+	config.TLSCertFile = filepath.Join(dir, "server.pem")
+	config.TLSKeyFile = filepath.Join(dir, "server-key.pem")
+	config.TLSClientIdentities = "worker.internal=worker"
+
+	server, err := NewServer(config, nil, nil, nil)
+	require.NoError(t, err)
+
+	// the proxying gateway presents the server certificate
+	ctx, err := server.withClientIdentity(context.Background(), clientCertificateState("localhost"))
+	require.NoError(t, err)
+	require.Equal(t, gatewayIdentity, clientIdentityFromContext(ctx))
+
+	ctx, err = server.withClientIdentity(context.Background(), clientCertificateState("worker.internal"))
+	require.NoError(t, err)
+	require.Equal(t, "worker", clientIdentityFromContext(ctx))
+
+	config.TLSClientIdentities = "localhost=" + gatewayIdentity
+	_, err = NewServer(config, nil, nil, nil)
+	require.NoError(t, err)
+
+	config.TLSClientIdentities = "localhost=api"
+	_, err = NewServer(config, nil, nil, nil)
+	require.Error(t, err)
+}
+
+func clientCertificateState(subject string) *tls.ConnectionState {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: subject}}
+	return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+}
@@ -1,19 +1,26 @@
 package api
 
 import (
+	"time"
+
 	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/grpc/pb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func convertUser(user db.User) *pb.User {
-	return &pb.User{
+	pbUser := &pb.User{
 		Username:          user.Username,
 		Email:             user.Email,
 		FullName:          user.FullName,
 		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
 		CreatedAt:         timestamppb.New(user.CreatedAt),
+		IsEmailVerified:   user.IsEmailVerified,
+	}
+	if user.PendingEmail.Valid {
+		pbUser.PendingEmail = &user.PendingEmail.String
 	}
+	return pbUser
 }
 
 func convertAccount(account db.Account) *pb.Account {
@@ -23,6 +30,7 @@ func convertAccount(account db.Account) *pb.Account {
 		Balance:   account.Balance,
 		Currency:  account.Currency,
 		CreatedAt: timestamppb.New(account.CreatedAt),
+		Status:    account.Status,
 	}
 }
 
@@ -44,3 +52,24 @@ func convertEntry(entry db.Entry) *pb.Entry {
 		CreatedAt: timestamppb.New(entry.CreatedAt),
 	}
 }
+
+func convertApiKey(apiKey db.ApiKey) *pb.ApiKey {
+	return &pb.ApiKey{
+		Id:         apiKey.ID.String(),
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		IsRevoked:  apiKey.IsRevoked,
+		ExpiredAt:  timestamppb.New(apiKey.ExpiredAt),
+		LastUsedAt: timestamppb.New(apiKey.LastUsedAt),
+		CreatedAt:  timestamppb.New(apiKey.CreatedAt),
+	}
+}
+
+func toTime(value *timestamppb.Timestamp) *time.Time {
+	if value == nil {
+		return nil
+	}
+	t := value.AsTime()
+	return &t
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/labasubagia/simplebank/apperror"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		verified   bool
+		buildStubs func(store *mock_db.MockStore)
+		call       func(ctx context.Context, server *Server) error
+	}{
+		{
+			name: "CreateAccountUnverified",
+			call: func(ctx context.Context, server *Server) error {
+				req := &pb.CreateAccountRequest{Currency: util.USD}
+				_, err := callRPC(ctx, server, "/pb.SimpleBank/CreateAccount", req, server.CreateAccount)
+				return err
+			},
+		},
+		{
+			name: "CreateTransferUnverified",
+			call: func(ctx context.Context, server *Server) error {
+				req := &pb.CreateTransferRequest{FromAccountId: 1, ToAccountId: 2, Amount: 10, Currency: util.USD}
+				_, err := callRPC(ctx, server, "/pb.SimpleBank/CreateTransfer", req, server.CreateTransfer)
+				return err
+			},
+		},
+		{
+			name:     "CreateAccountVerified",
+			verified: true,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+			},
+			call: func(ctx context.Context, server *Server) error {
+				req := &pb.CreateAccountRequest{Currency: util.USD}
+				_, err := callRPC(ctx, server, "/pb.SimpleBank/CreateAccount", req, server.CreateAccount)
+				return err
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock_db.NewMockStore(ctrl)
+
+			user := user
+			user.IsEmailVerified = tc.verified
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			if tc.buildStubs != nil {
+				tc.buildStubs(store)
+			}
+
+			config := newTestConfig()
+			config.RequireVerifiedEmail = true
+			server, err := NewServer(config, store, nil, nil)
+			require.NoError(t, err)
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, time.Minute)
+
+			err = tc.call(ctx, server)
+			if tc.verified {
+				require.NoError(t, err)
+				return
+			}
+
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			require.Equal(t, codes.FailedPrecondition, st.Code())
+			requireErrorReason(t, st, apperror.CodeEmailNotVerified)
+
+			var preconditionFailure *errdetails.PreconditionFailure
+			for _, detail := range st.Details() {
+				if detail, ok := detail.(*errdetails.PreconditionFailure); ok {
+					preconditionFailure = detail
+				}
+			}
+			require.NotNil(t, preconditionFailure)
+			require.Len(t, preconditionFailure.GetViolations(), 1)
+			require.Equal(t, apperror.PreconditionEmailVerification, preconditionFailure.GetViolations()[0].GetType())
+			require.Equal(t, "users/"+user.Username, preconditionFailure.GetViolations()[0].GetSubject())
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package api
 
 import (
+	"context"
+	"time"
+
+	"github.com/labasubagia/simplebank/apperror"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc"
 )
 
 func fieldValidation(field string, err error) *errdetails.BadRequest_FieldViolation {
@@ -14,16 +17,39 @@ func fieldValidation(field string, err error) *errdetails.BadRequest_FieldViolat
 }
 
 func invalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) error {
-	badRequest := &errdetails.BadRequest{FieldViolations: violations}
-	statusInvalid := status.New(codes.InvalidArgument, "invalid parameters")
-
-	statusDetails, err := statusInvalid.WithDetails(badRequest)
-	if err != nil {
-		return statusInvalid.Err()
+	fieldViolations := make([]apperror.FieldViolation, 0, len(violations))
+	for _, violation := range violations {
+		fieldViolations = append(fieldViolations, apperror.FieldViolation{
+			Field:       violation.GetField(),
+			Description: violation.GetDescription(),
+		})
 	}
-	return statusDetails.Err()
+	return apperror.InvalidArgument(fieldViolations)
 }
 
 func unauthenticatedError(err error) error {
-	return status.Errorf(codes.Unauthenticated, "unauthorized: %s", err)
+	return apperror.Newf(apperror.CodeUnauthenticated, "unauthorized: %s", err)
+}
+
+func resourceExhaustedError(message string, retryDelay time.Duration) error {
+	return apperror.New(apperror.CodeRateLimited, message).WithRetryAfter(retryDelay)
+}
+
+// ErrorUnaryInterceptor turns handler errors into statuses carrying a stable
+// error code. Internal errors are redacted; GrpcLogger, which runs inside
+// this interceptor, has already logged their cause.
+func ErrorUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, clientError(err)
+}
+
+func ErrorStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return clientError(handler(srv, stream))
+}
+
+func clientError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return apperror.FromGRPC(err).GRPCStatus().Err()
 }
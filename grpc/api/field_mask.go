@@ -0,0 +1,54 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+const updateMaskField = "update_mask"
+
+// updateMask is the set of field paths an update RPC changes.
+type updateMask map[string]bool
+
+func (mask updateMask) Has(path string) bool {
+	return mask[path]
+}
+
+// newUpdateMask resolves the paths req updates. An explicit mask must only
+// name paths in allowed; a listed field takes its value in req even when
+// unset. Masks don't clear fields: the unset value is validated like any
+// other, and the fields UpdateUser allows reject empty values. Without a
+// mask, the allowed fields present in req are updated, which is also what
+// PATCH calls through the gateway get, as its body "*" mapping leaves the
+// mask empty.
+func newUpdateMask(req proto.Message, mask *fieldmaskpb.FieldMask, allowed ...string) (updateMask, *errdetails.BadRequest_FieldViolation) {
+	paths := updateMask{}
+	if len(mask.GetPaths()) == 0 {
+		message := req.ProtoReflect()
+		for _, path := range allowed {
+			field := message.Descriptor().Fields().ByName(protoreflect.Name(path))
+			if field != nil && message.Has(field) {
+				paths[path] = true
+			}
+		}
+		return paths, nil
+	}
+
+	allowedPaths := map[string]bool{}
+	for _, path := range allowed {
+		allowedPaths[path] = true
+	}
+	for _, path := range mask.GetPaths() {
+		if !allowedPaths[path] {
+			err := fmt.Errorf("unsupported path %q, must be one of: %s", path, strings.Join(allowed, ", "))
+			return nil, fieldValidation(updateMaskField, err)
+		}
+		paths[path] = true
+	}
+	return paths, nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/labasubagia/simplebank/apperror"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+)
+
+// GatewayIncomingHeaderMatcher forwards the request id to the gRPC server
+// under its own name, on top of the headers the gateway forwards by default.
+func GatewayIncomingHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, requestIDHeader) {
+		return requestIDHeader, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// GatewayOutgoingHeaderMatcher returns the request id and retry delay set by
+// the interceptors as plain HTTP headers, and every other header metadata
+// with the usual Grpc-Metadata- prefix.
+func GatewayOutgoingHeaderMatcher(key string) (string, bool) {
+	switch key {
+	case requestIDHeader, retryAfterHeader:
+		return http.CanonicalHeaderKey(key), true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// GatewayErrorHandler writes gateway errors as RFC 7807 problem details
+// carrying the same error code as the gRPC status.
+func GatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, res http.ResponseWriter, req *http.Request, err error) {
+	httpStatus := 0
+	var statusErr *runtime.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		httpStatus = statusErr.HTTPStatus
+		err = statusErr.Err
+	}
+
+	appErr := apperror.FromGRPC(err)
+	if appErr.Code == apperror.CodeInternal {
+		log.Error().Err(err).Str("path", req.URL.Path).Msg("gateway request failed")
+	}
+	problem := appErr.Problem(req.URL.Path)
+	if httpStatus != 0 {
+		problem.Status = httpStatus
+	}
+
+	if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+		for key, values := range md.HeaderMD {
+			if header, ok := GatewayOutgoingHeaderMatcher(key); ok {
+				for _, value := range values {
+					res.Header().Add(header, value)
+				}
+			}
+		}
+	}
+	if appErr.RetryAfter > 0 && res.Header().Get(retryAfterHeader) == "" {
+		res.Header().Set(retryAfterHeader, strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+	}
+	if problem.Code == apperror.CodeUnauthenticated {
+		res.Header().Set("WWW-Authenticate", "Bearer")
+	}
+
+	res.Header().Del("Trailer")
+	res.Header().Del("Transfer-Encoding")
+	res.Header().Set("Content-Type", apperror.ProblemContentType)
+	res.WriteHeader(problem.Status)
+	if err := json.NewEncoder(res).Encode(problem); err != nil {
+		log.Error().Err(err).Msg("failed to write gateway error")
+	}
+}
+
+// MultiplexHandler serves gRPC calls with grpcServer and everything else with
+// handler, so both protocols can share one port. Plaintext listeners need
+// it wrapped in h2c for gRPC clients to connect.
+func MultiplexHandler(grpcServer *grpc.Server, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.ProtoMajor == 2 && strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(res, req)
+			return
+		}
+		handler.ServeHTTP(res, req)
+	})
+}
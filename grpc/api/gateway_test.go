@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labasubagia/simplebank/apperror"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/ratelimit"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// newProxyGateway serves server over a loopback gRPC listener and returns a
+// gateway proxying to it, the way main wires GATEWAY_MODE=proxy.
+func newProxyGateway(t *testing.T, server *Server) http.Handler {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(server.UnaryInterceptors()...))
+	pb.RegisterSimpleBankServer(grpcServer, server)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	grpcMux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(GatewayIncomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(GatewayOutgoingHeaderMatcher),
+		runtime.WithErrorHandler(GatewayErrorHandler),
+	)
+	err = pb.RegisterSimpleBankHandler(context.Background(), grpcMux, conn)
+	require.NoError(t, err)
+	return grpcMux
+}
+
+func TestProxyGateway(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+	server.rateLimiter = ratelimit.NewMemoryLimiter()
+	gateway := newProxyGateway(t, server)
+
+	// an invalid login is rejected before the store is used
+	login := func(remoteAddr string, requestID string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/v1/users/login", strings.NewReader(`{"username":"!"}`))
+		request.RemoteAddr = remoteAddr
+		// spoofed entries are ignored, the gateway appends the real address
+		request.Header.Set("X-Forwarded-For", "203.0.113.7")
+		if requestID != "" {
+			request.Header.Set("X-Request-Id", requestID)
+		}
+		gateway.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := login("10.0.0.1:40000", "request-1")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, "request-1", recorder.Header().Get("X-Request-Id"))
+	problem := requireProblem(t, recorder, apperror.CodeInvalidArgument)
+	require.NotEmpty(t, problem.Errors)
+
+	recorder = login("10.0.0.1:40000", "")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("X-Request-Id"))
+
+	// interceptors run, so gateway calls are rate limited per client ip
+	for i := 2; i < methodRateLimits["/pb.SimpleBank/LoginUser"].Burst; i++ {
+		require.Equal(t, http.StatusBadRequest, login("10.0.0.1:40000", "").Code)
+	}
+	recorder = login("10.0.0.1:40000", "")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "6", recorder.Header().Get("Retry-After"))
+	requireProblem(t, recorder, apperror.CodeRateLimited)
+
+	require.Equal(t, http.StatusBadRequest, login("10.0.0.2:40000", "").Code)
+}
+
+func TestGatewayErrorHandler(t *testing.T) {
+	gateway := newProxyGateway(t, newTestServer(t, nil, nil))
+
+	recorder := httptest.NewRecorder()
+	gateway.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/accounts", nil))
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	problem := requireProblem(t, recorder, apperror.CodeUnauthenticated)
+	require.Equal(t, "/v1/accounts", problem.Instance)
+
+	recorder = httptest.NewRecorder()
+	gateway.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/unknown", nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	requireProblem(t, recorder, apperror.CodeNotFound)
+}
+
+func TestGatewayUpdateUserMask(t *testing.T) {
+	user, _ := randomUser(t)
+	newName := util.RandomOwner()
+
+	ctrl := gomock.NewController(t)
+	store := mock_db.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+			require.Equal(t, pgtype.Text{String: newName, Valid: true}, arg.FullName)
+			require.False(t, arg.PendingEmail.Valid)
+			return db.UpdateUserTxResult{User: user}, nil
+		})
+
+	server := newTestServer(t, store, nil)
+	gateway := newProxyGateway(t, server)
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, time.Minute)
+	require.NoError(t, err)
+
+	// the email is ignored because the mask leaves it out, so no current
+	// password is needed
+	body := fmt.Sprintf(`{"username":%q,"full_name":%q,"email":"new@example.com","update_mask":"fullName"}`, user.Username, newName)
+	request := httptest.NewRequest(http.MethodPatch, "/v1/users", strings.NewReader(body))
+	request.Header.Set("Authorization", authorizationBearer+" "+accessToken)
+	recorder := httptest.NewRecorder()
+	gateway.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+}
+
+func requireProblem(t *testing.T, recorder *httptest.ResponseRecorder, code apperror.Code) apperror.Problem {
+	require.Equal(t, apperror.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+	var problem apperror.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	require.Equal(t, code, problem.Code)
+	require.Equal(t, recorder.Code, problem.Status)
+	return problem
+}
+
+func TestMultiplexHandler(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+
+	handler := MultiplexHandler(grpcServer, http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusTeapot)
+	}))
+	httpServer := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	t.Cleanup(httpServer.Close)
+
+	res, err := http.Get(httpServer.URL + "/v1/accounts")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusTeapot, res.StatusCode)
+
+	conn, err := grpc.Dial(strings.TrimPrefix(httpServer.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	check, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, check.Status)
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	requestIDHeader         = "x-request-id"
+	simpleBankServicePrefix = "/pb.SimpleBank/"
+)
+
+type (
+	requestIDKey   struct{}
+	authPayloadKey struct{}
+)
+
+// publicMethods can be called without any credentials.
+var publicMethods = map[string]bool{
+	"/pb.SimpleBank/CreateUser":           true,
+	"/pb.SimpleBank/LoginUser":            true,
+	"/pb.SimpleBank/VerifyLoginTotp":      true,
+	"/pb.SimpleBank/RenewAccessToken":     true,
+	"/pb.SimpleBank/VerifyEmail":          true,
+	"/pb.SimpleBank/RequestPasswordReset": true,
+	"/pb.SimpleBank/ResetPassword":        true,
+}
+
+// methodScopes lists the api key scopes each authenticated method requires.
+// Methods without scopes only accept access tokens.
+var methodScopes = map[string][]string{
+	"/pb.SimpleBank/UpdateUser":            nil,
+	"/pb.SimpleBank/SetupTotp":             nil,
+	"/pb.SimpleBank/ConfirmTotp":           nil,
+	"/pb.SimpleBank/CreateApiKey":          nil,
+	"/pb.SimpleBank/ListApiKeys":           nil,
+	"/pb.SimpleBank/RevokeApiKey":          nil,
+	"/pb.SimpleBank/ResendVerifyEmail":     nil,
+	"/pb.SimpleBank/GetVerificationStatus": nil,
+	"/pb.SimpleBank/CreateAccount":         {util.ScopeAccountsWrite},
+	"/pb.SimpleBank/GetAccount":            {util.ScopeAccountsRead},
+	"/pb.SimpleBank/ListAccounts":          {util.ScopeAccountsRead},
+	"/pb.SimpleBank/CloseAccount":          {util.ScopeAccountsWrite},
+	"/pb.SimpleBank/ListAccountEntries":    {util.ScopeAccountsRead},
+	"/pb.SimpleBank/CreateTransfer":        {util.ScopeTransfersWrite},
+	"/pb.SimpleBank/ListTransfers":         {util.ScopeTransfersRead},
+}
+
+// UnaryInterceptors returns the interceptor chain for unary RPCs, outermost first.
+func (server *Server) UnaryInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		otelgrpc.UnaryServerInterceptor(),
+		GrpcMetrics,
+		RecoveryUnaryInterceptor,
+		RequestIDUnaryInterceptor,
+		ErrorUnaryInterceptor,
+		server.ClientIdentityUnaryInterceptor,
+		GrpcLogger,
+		server.AuthUnaryInterceptor,
+		server.RateLimitUnaryInterceptor,
+	}
+}
+
+// StreamInterceptors returns the interceptor chain for streaming RPCs, outermost first.
+func (server *Server) StreamInterceptors() []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		otelgrpc.StreamServerInterceptor(),
+		GrpcStreamMetrics,
+		RecoveryStreamInterceptor,
+		RequestIDStreamInterceptor,
+		ErrorStreamInterceptor,
+		server.ClientIdentityStreamInterceptor,
+		GrpcStreamLogger,
+		server.AuthStreamInterceptor,
+		server.RateLimitStreamInterceptor,
+	}
+}
+
+// wrappedStream lets stream interceptors replace the stream context.
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *wrappedStream) Context() context.Context {
+	return stream.ctx
+}
+
+func RecoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverPanic(ctx, info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func RecoveryStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverPanic(stream.Context(), info.FullMethod, r)
+		}
+	}()
+	return handler(srv, stream)
+}
+
+func recoverPanic(ctx context.Context, method string, r interface{}) error {
+	log.Error().
+		Str("request_id", requestIDFromContext(ctx)).
+		Str("method", method).
+		Interface("panic", r).
+		Bytes("stack", debug.Stack()).
+		Msg("recovered from panic")
+	return status.Errorf(codes.Internal, "internal server error")
+}
+
+func RequestIDUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(withRequestID(ctx), req)
+}
+
+func RequestIDStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &wrappedStream{ServerStream: stream, ctx: withRequestID(stream.Context())})
+}
+
+// withRequestID reuses the caller's request id, or generates one, and echoes
+// it back in the response header.
+func withRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	// fails only when there is no transport stream, e.g. in unit tests
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID))
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+func (server *Server) AuthUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := server.authorizeMethod(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (server *Server) AuthStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := server.authorizeMethod(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authorizeMethod authenticates the caller of method and stores the payload
+// in the returned context. Methods of other services registered on the same
+// server, like reflection, are left alone; unknown SimpleBank methods are denied.
+func (server *Server) authorizeMethod(ctx context.Context, method string) (context.Context, error) {
+	if !strings.HasPrefix(method, simpleBankServicePrefix) || publicMethods[method] {
+		return ctx, nil
+	}
+
+	scopes, ok := methodScopes[method]
+	if !ok {
+		return ctx, status.Errorf(codes.PermissionDenied, "method %s is not allowed", method)
+	}
+
+	payload, err := server.authorizeUser(ctx, scopes...)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, authPayloadKey{}, payload), nil
+}
+
+// authenticatedPayload returns the payload stored by the auth interceptor.
+// The in-process gateway calls handlers without the interceptor chain, so
+// those requests are authorized here against the same method table.
+func (server *Server) authenticatedPayload(ctx context.Context) (*token.Payload, error) {
+	if payload, ok := ctx.Value(authPayloadKey{}).(*token.Payload); ok {
+		return payload, nil
+	}
+
+	method, ok := runtime.RPCMethod(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "unauthorized: missing credentials")
+	}
+	ctx, err := server.authorizeMethod(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	payload, ok := ctx.Value(authPayloadKey{}).(*token.Payload)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "unauthorized: missing credentials")
+	}
+	return payload, nil
+}
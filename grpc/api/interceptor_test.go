@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoveryUnaryInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/CreateUser"}
+	res, err := RecoveryUnaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	require.Nil(t, res)
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestRecoveryStreamInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/pb.SimpleBank/Stream"}
+	stream := &wrappedStream{ctx: context.Background()}
+	err := RecoveryStreamInterceptor(nil, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+		panic("boom")
+	})
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestRequestIDUnaryInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/CreateUser"}
+	captureRequestID := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return requestIDFromContext(ctx), nil
+	}
+
+	res, err := RequestIDUnaryInterceptor(context.Background(), nil, info, captureRequestID)
+	require.NoError(t, err)
+	require.NotEmpty(t, res)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDHeader, "request-1"))
+	res, err = RequestIDUnaryInterceptor(ctx, nil, info, captureRequestID)
+	require.NoError(t, err)
+	require.Equal(t, "request-1", res)
+}
+
+func TestAuthorizeMethod(t *testing.T) {
+	user, _ := randomUser(t)
+	server := newTestServer(t, nil, nil)
+
+	testCases := []struct {
+		name         string
+		method       string
+		buildContext func(t *testing.T) context.Context
+		checkResult  func(t *testing.T, ctx context.Context, err error)
+	}{
+		{
+			name:   "PublicMethod",
+			method: "/pb.SimpleBank/LoginUser",
+			buildContext: func(t *testing.T) context.Context {
+				return context.Background()
+			},
+			checkResult: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+				require.Nil(t, ctx.Value(authPayloadKey{}))
+			},
+		},
+		{
+			name:   "OtherService",
+			method: "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+			buildContext: func(t *testing.T) context.Context {
+				return context.Background()
+			},
+			checkResult: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:   "Authenticated",
+			method: "/pb.SimpleBank/ListAccounts",
+			buildContext: func(t *testing.T) context.Context {
+				return newContextWithBearerToken(t, server.tokenMaker, user.Username, time.Minute)
+			},
+			checkResult: func(t *testing.T, ctx context.Context, err error) {
+				require.NoError(t, err)
+				payload, err := server.authenticatedPayload(ctx)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+			},
+		},
+		{
+			name:   "MissingCredentials",
+			method: "/pb.SimpleBank/ListAccounts",
+			buildContext: func(t *testing.T) context.Context {
+				return context.Background()
+			},
+			checkResult: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name:   "UnknownMethod",
+			method: "/pb.SimpleBank/DropDatabase",
+			buildContext: func(t *testing.T) context.Context {
+				return newContextWithBearerToken(t, server.tokenMaker, user.Username, time.Minute)
+			},
+			checkResult: func(t *testing.T, ctx context.Context, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, err := server.authorizeMethod(tc.buildContext(t), tc.method)
+			tc.checkResult(t, ctx, err)
+		})
+	}
+}
+
+func TestAuthenticatedPayloadGateway(t *testing.T) {
+	user, _ := randomUser(t)
+	server := newTestServer(t, nil, nil)
+
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, time.Minute)
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodGet, "/v1/accounts", nil)
+	require.NoError(t, err)
+	request.Header.Set("Authorization", authorizationBearer+" "+accessToken)
+
+	ctx, err := runtime.AnnotateIncomingContext(context.Background(), runtime.NewServeMux(), request, "/pb.SimpleBank/ListAccounts")
+	require.NoError(t, err)
+
+	payload, err := server.authenticatedPayload(ctx)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, payload.Username)
+
+	_, err = server.authenticatedPayload(context.Background())
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -14,8 +14,18 @@ import (
 func GrpcLogger(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 	startTime := time.Now()
 	result, err := handler(ctx, req)
-	duration := time.Since(startTime)
+	logGrpcRequest(ctx, info.FullMethod, time.Since(startTime), err)
+	return result, err
+}
+
+func GrpcStreamLogger(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	startTime := time.Now()
+	err := handler(srv, stream)
+	logGrpcRequest(stream.Context(), info.FullMethod, time.Since(startTime), err)
+	return err
+}
 
+func logGrpcRequest(ctx context.Context, method string, duration time.Duration, err error) {
 	statusCode := codes.Unknown
 	if st, ok := status.FromError(err); ok {
 		statusCode = st.Code()
@@ -25,16 +35,18 @@ func GrpcLogger(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo
 	if err != nil {
 		logger = log.Error().Err(err)
 	}
+	if identity := clientIdentityFromContext(ctx); identity != "" {
+		logger = logger.Str("client_identity", identity)
+	}
 
 	logger.
 		Str("protocol", "grpc").
-		Str("method", info.FullMethod).
+		Str("request_id", requestIDFromContext(ctx)).
+		Str("method", method).
 		Int("status_code", int(statusCode)).
 		Str("status_text", statusCode.String()).
 		Dur("duration", duration).
 		Msg("receiver grpc request")
-
-	return result, err
 }
 
 type ResponseRecorder struct {
@@ -6,27 +6,61 @@ import (
 	"testing"
 	"time"
 
+	"github.com/labasubagia/simplebank/apperror"
 	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/util"
 	"github.com/labasubagia/simplebank/util/token"
 	"github.com/labasubagia/simplebank/worker"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-func newTestServer(t *testing.T, store db.Store, taskDistributor worker.TaskDistributor) *Server {
-	config := util.Config{
-		TokenSymmetricKey:    util.RandomString(32),
-		AccessTokenDuration:  time.Minute,
-		RefreshTokenDuration: time.Hour,
+func newTestConfig() util.Config {
+	return util.Config{
+		TokenSymmetricKey:      util.RandomString(32),
+		AccessTokenDuration:    time.Minute,
+		RefreshTokenDuration:   time.Hour,
+		LoginChallengeDuration: time.Minute,
+		LoginMaxAttempts:       5,
+		LoginAttemptWindow:     time.Hour,
+		LoginLockoutDuration:   time.Minute,
 	}
+}
 
-	server, err := NewServer(config, store, taskDistributor)
+func newTestServer(t *testing.T, store db.Store, taskDistributor worker.TaskDistributor) *Server {
+	server, err := NewServer(newTestConfig(), store, taskDistributor, nil)
 	require.NoError(t, err)
 
 	return server
 }
 
+// callRPC invokes handler behind the server's unary interceptor chain, the
+// same way the gRPC server would for method.
+func callRPC[Req any, Res any](ctx context.Context, server *Server, method string, req Req, handler func(context.Context, Req) (Res, error)) (Res, error) {
+	info := &grpc.UnaryServerInfo{Server: server, FullMethod: method}
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return handler(ctx, req.(Req))
+	}
+
+	interceptors := server.UnaryInterceptors()
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := interceptors[i], next
+		next = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, inner)
+		}
+	}
+
+	var zero Res
+	res, err := next(ctx, req)
+	if err != nil {
+		return zero, err
+	}
+	return res.(Res), nil
+}
+
 func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username string, duration time.Duration) context.Context {
 	accessToken, _, err := tokenMaker.CreateToken(username, duration)
 	require.NoError(t, err)
@@ -37,3 +71,20 @@ func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username st
 	}
 	return metadata.NewIncomingContext(context.Background(), md)
 }
+
+func newContextWithApiKey(key string) context.Context {
+	md := metadata.MD{
+		authorizationHeader: []string{fmt.Sprintf("%s %s", authorizationApiKey, key)},
+	}
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func requireErrorReason(t *testing.T, st *status.Status, code apperror.Code) {
+	for _, detail := range st.Details() {
+		if errorInfo, ok := detail.(*errdetails.ErrorInfo); ok {
+			require.Equal(t, string(code), errorInfo.Reason)
+			return
+		}
+	}
+	t.Fatalf("status %v has no error info", st)
+}
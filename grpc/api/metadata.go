@@ -2,13 +2,16 @@ package api
 
 import (
 	"context"
+	"net"
+	"strings"
 
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
 const (
 	grpcGatewayUserAgentHeader = "grpcgateway-user-agent"
-	xForwardedForHeader        = "x-forwarded-for"
+	forwardedForHeader         = "x-forwarded-for"
 )
 
 type Metadata struct {
@@ -17,14 +20,52 @@ type Metadata struct {
 }
 
 func (server *Server) extractMetadata(ctx context.Context) *Metadata {
-	data := &Metadata{}
+	data := &Metadata{
+		ClientIP: clientIP(ctx),
+	}
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		if userAgents := md.Get(grpcGatewayUserAgentHeader); len(userAgents) > 0 {
 			data.UserAgent = userAgents[0]
 		}
-		if clientIPs := md.Get(xForwardedForHeader); len(clientIPs) > 0 {
-			data.ClientIP = clientIPs[0]
-		}
 	}
 	return data
 }
+
+// clientIP returns the address of the caller, or an empty string when it is
+// unknown. x-forwarded-for is only trusted from the gateway: when it calls
+// the handlers in process there is no peer, and when it proxies it dials the
+// server on loopback or, with mTLS, presents the gateway identity.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		forwarded, _ := gatewayClientIP(ctx)
+		return forwarded
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	if ip := net.ParseIP(host); (ip != nil && ip.IsLoopback()) || clientIdentityFromContext(ctx) == gatewayIdentity {
+		if forwarded, ok := gatewayClientIP(ctx); ok {
+			return forwarded
+		}
+	}
+	return host
+}
+
+// gatewayClientIP returns the address of the HTTP client the gateway calls
+// on behalf of. The gateway appends it to x-forwarded-for, so only the last
+// entry is trusted; the ones before come from the client itself.
+func gatewayClientIP(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get(forwardedForHeader)
+	if len(values) == 0 {
+		return "", false
+	}
+	entries := strings.Split(values[len(values)-1], ",")
+	forwarded := strings.TrimSpace(entries[len(entries)-1])
+	return forwarded, forwarded != ""
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestClientIP(t *testing.T) {
+	newContext := func(peerIP string, forwardedFor ...string) context.Context {
+		ctx := context.Background()
+		if peerIP != "" {
+			ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(peerIP), Port: 40000}})
+		}
+		if len(forwardedFor) > 0 {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(forwardedForHeader, forwardedFor[0]))
+		}
+		return ctx
+	}
+
+	testCases := []struct {
+		name string
+		ctx  context.Context
+		ip   string
+	}{
+		{
+			name: "Unknown",
+			ctx:  newContext(""),
+			ip:   "",
+		},
+		{
+			name: "DirectCaller",
+			ctx:  newContext("203.0.113.7"),
+			ip:   "203.0.113.7",
+		},
+		{
+			name: "DirectCallerSpoofsForwardedFor",
+			ctx:  newContext("203.0.113.7", "198.51.100.1"),
+			ip:   "203.0.113.7",
+		},
+		{
+			name: "ProxyingGateway",
+			ctx:  newContext("127.0.0.1", "198.51.100.1, 203.0.113.7"),
+			ip:   "203.0.113.7",
+		},
+		{
+			name: "MutualTLSGateway",
+			ctx:  context.WithValue(newContext("10.0.0.8", "198.51.100.1, 203.0.113.7"), clientIdentityKey{}, gatewayIdentity),
+			ip:   "203.0.113.7",
+		},
+		{
+			name: "MutualTLSServiceSpoofsForwardedFor",
+			ctx:  context.WithValue(newContext("10.0.0.9", "198.51.100.1"), clientIdentityKey{}, "worker"),
+			ip:   "10.0.0.9",
+		},
+		{
+			name: "InProcessGateway",
+			ctx:  newContext("", "198.51.100.1, 203.0.113.7"),
+			ip:   "203.0.113.7",
+		},
+	}
+
+	server := newTestServer(t, nil, nil)
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.ip, clientIP(tc.ctx))
+			require.Equal(t, tc.ip, server.extractMetadata(tc.ctx).ClientIP)
+		})
+	}
+}
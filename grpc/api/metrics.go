@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/labasubagia/simplebank/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const unmatchedRoute = "unmatched"
+
+type httpRouteKey struct{}
+
+// httpRoute is filled in while the request is being handled, once the
+// router knows which pattern matched.
+type httpRoute struct {
+	pattern string
+}
+
+func GrpcMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	startTime := time.Now()
+	result, err := handler(ctx, req)
+	metrics.ObserveGrpcRequest(info.FullMethod, status.Code(err).String(), time.Since(startTime))
+	return result, err
+}
+
+func GrpcStreamMetrics(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	startTime := time.Now()
+	err := handler(srv, stream)
+	metrics.ObserveGrpcRequest(info.FullMethod, status.Code(err).String(), time.Since(startTime))
+	return err
+}
+
+// HttpMetrics records every request under the route pattern set by
+// GatewayRouteAnnotator or HttpRoute, so raw paths never become labels.
+func HttpMetrics(server string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		rec := &ResponseRecorder{
+			ResponseWriter: res,
+			StatusCode:     http.StatusOK,
+		}
+		route := &httpRoute{pattern: unmatchedRoute}
+		req = req.WithContext(context.WithValue(req.Context(), httpRouteKey{}, route))
+
+		startTime := time.Now()
+		handler.ServeHTTP(rec, req)
+		metrics.ObserveHTTPRequest(server, req.Method, route.pattern, rec.StatusCode, time.Since(startTime))
+	})
+}
+
+// HttpRoute labels requests served by handler with a fixed route.
+func HttpRoute(pattern string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		setHttpRoute(req.Context(), pattern)
+		handler.ServeHTTP(res, req)
+	})
+}
+
+// GatewayRouteAnnotator is a gateway metadata annotator that reports the
+// matched HTTP path pattern to HttpMetrics. It adds no metadata itself.
+func GatewayRouteAnnotator(ctx context.Context, req *http.Request) metadata.MD {
+	if pattern, ok := runtime.HTTPPathPattern(ctx); ok {
+		setHttpRoute(ctx, pattern)
+	}
+	return nil
+}
+
+func setHttpRoute(ctx context.Context, pattern string) {
+	if route, ok := ctx.Value(httpRouteKey{}).(*httpRoute); ok {
+		route.pattern = pattern
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpMetricsRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", HttpRoute("/healthz", http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	})))
+	handler := HttpMetrics("test", mux)
+
+	testCases := []struct {
+		name  string
+		path  string
+		route string
+		code  int
+	}{
+		{
+			name:  "MatchedRoute",
+			path:  "/healthz",
+			route: "/healthz",
+			code:  http.StatusOK,
+		},
+		{
+			name:  "UnmatchedRoute",
+			path:  "/v1/unknown/123",
+			route: unmatchedRoute,
+			code:  http.StatusNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			before := httpRequestsCount(t, tc.route, tc.code)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			require.Equal(t, tc.code, recorder.Code)
+
+			require.Equal(t, before+1, httpRequestsCount(t, tc.route, tc.code))
+		})
+	}
+}
+
+func httpRequestsCount(t *testing.T, route string, code int) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	want := map[string]string{"server": "test", "route": route, "code": strconv.Itoa(code)}
+	for _, family := range families {
+		if family.GetName() != "simplebank_http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			matched := 0
+			for _, label := range metric.GetLabel() {
+				if value, ok := want[label.GetName()]; ok && value == label.GetValue() {
+					matched++
+				}
+			}
+			if matched == len(want) {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/labasubagia/simplebank/ratelimit"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const retryAfterHeader = "retry-after"
+
+// defaultRateLimit applies to every SimpleBank method missing from methodRateLimits.
+var defaultRateLimit = ratelimit.PerSecond(10, 20)
+
+// methodRateLimits are tighter limits for the methods that are cheap to abuse.
+var methodRateLimits = map[string]ratelimit.Limit{
+	"/pb.SimpleBank/CreateUser":           ratelimit.PerMinute(5),
+	"/pb.SimpleBank/LoginUser":            ratelimit.PerMinute(10),
+	"/pb.SimpleBank/VerifyLoginTotp":      ratelimit.PerMinute(10),
+	"/pb.SimpleBank/RenewAccessToken":     ratelimit.PerMinute(30),
+	"/pb.SimpleBank/VerifyEmail":          ratelimit.PerMinute(10),
+	"/pb.SimpleBank/ResendVerifyEmail":    ratelimit.PerMinute(1),
+	"/pb.SimpleBank/RequestPasswordReset": ratelimit.PerMinute(5),
+	"/pb.SimpleBank/ResetPassword":        ratelimit.PerMinute(10),
+	"/pb.SimpleBank/CreateApiKey":         ratelimit.PerMinute(10),
+	"/pb.SimpleBank/CreateTransfer":       ratelimit.PerMinute(30),
+}
+
+func (server *Server) RateLimitUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := server.checkRateLimit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (server *Server) RateLimitStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := server.checkRateLimit(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// checkRateLimit takes a token from the caller's bucket for method. It runs
+// after authentication so authenticated calls are limited per user, and
+// anonymous ones per service identity or client ip. Limiter failures let
+// the call through.
+func (server *Server) checkRateLimit(ctx context.Context, method string) error {
+	if server.rateLimiter == nil || !strings.HasPrefix(method, simpleBankServicePrefix) {
+		return nil
+	}
+
+	limit, ok := methodRateLimits[method]
+	if !ok {
+		limit = defaultRateLimit
+	}
+
+	result, err := server.rateLimiter.Allow(ctx, method+":"+rateLimitCaller(ctx), limit)
+	if err != nil {
+		log.Error().Err(err).Str("method", method).Msg("cannot check rate limit")
+		return nil
+	}
+	if result.Allowed {
+		return nil
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterHeader, strconv.FormatInt(result.RetryAfterSeconds(), 10)))
+	return resourceExhaustedError("rate limit exceeded, try again later", result.RetryAfter)
+}
+
+// rateLimitCaller keys the limit by user, then by service identity, then by
+// client address. Gateway calls are keyed by the client they are made for.
+func rateLimitCaller(ctx context.Context) string {
+	if payload, ok := ctx.Value(authPayloadKey{}).(*token.Payload); ok {
+		return "user:" + payload.Username
+	}
+	if identity := clientIdentityFromContext(ctx); identity != "" && identity != gatewayIdentity {
+		return "service:" + identity
+	}
+	if ip := clientIP(ctx); ip != "" {
+		return "ip:" + ip
+	}
+	return "ip:unknown"
+}
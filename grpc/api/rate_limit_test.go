@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/labasubagia/simplebank/apperror"
+	"github.com/labasubagia/simplebank/ratelimit"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitUnaryInterceptor(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+	server.rateLimiter = ratelimit.NewMemoryLimiter()
+
+	ok := func(ctx context.Context, req struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}
+	newContextFromIP := func(ctx context.Context, ip string) context.Context {
+		return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000}})
+	}
+
+	// anonymous calls share a bucket per client ip
+	method := "/pb.SimpleBank/CreateUser"
+	limit := methodRateLimits[method]
+	ctx := newContextFromIP(context.Background(), "10.0.0.1")
+	for i := 0; i < limit.Burst; i++ {
+		_, err := callRPC(ctx, server, method, struct{}{}, ok)
+		require.NoError(t, err)
+	}
+
+	_, err := callRPC(ctx, server, method, struct{}{}, ok)
+	requireRateLimited(t, err)
+
+	_, err = callRPC(newContextFromIP(context.Background(), "10.0.0.2"), server, method, struct{}{}, ok)
+	require.NoError(t, err)
+
+	// authenticated calls share a bucket per user, whatever their ip
+	user, _ := randomUser(t)
+	method = "/pb.SimpleBank/GetAccount"
+	for i := 0; i < defaultRateLimit.Burst; i++ {
+		ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, time.Minute)
+		_, err := callRPC(newContextFromIP(ctx, "10.0.0.3"), server, method, struct{}{}, ok)
+		require.NoError(t, err)
+	}
+
+	ctx = newContextWithBearerToken(t, server.tokenMaker, user.Username, time.Minute)
+	_, err = callRPC(newContextFromIP(ctx, "10.0.0.4"), server, method, struct{}{}, ok)
+	requireRateLimited(t, err)
+
+	// methods of other services are never limited
+	for i := 0; i < defaultRateLimit.Burst+1; i++ {
+		_, err = callRPC(context.Background(), server, "/grpc.health.v1.Health/Check", struct{}{}, ok)
+		require.NoError(t, err)
+	}
+}
+
+func TestRateLimitCaller(t *testing.T) {
+	require.Equal(t, "ip:unknown", rateLimitCaller(context.Background()))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("::1"), Port: 40000}})
+	require.Equal(t, "ip:::1", rateLimitCaller(ctx))
+
+	serviceCtx := context.WithValue(ctx, clientIdentityKey{}, "worker")
+	require.Equal(t, "service:worker", rateLimitCaller(serviceCtx))
+
+	// gateway calls are limited per client, not as one service
+	gatewayCtx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.8"), Port: 40000}})
+	gatewayCtx = metadata.NewIncomingContext(gatewayCtx, metadata.Pairs(forwardedForHeader, "203.0.113.7"))
+	gatewayCtx = context.WithValue(gatewayCtx, clientIdentityKey{}, gatewayIdentity)
+	require.Equal(t, "ip:203.0.113.7", rateLimitCaller(gatewayCtx))
+
+	server := newTestServer(t, nil, nil)
+	user, _ := randomUser(t)
+	ctx, err := server.authorizeMethod(
+		newContextWithBearerToken(t, server.tokenMaker, user.Username, time.Minute),
+		"/pb.SimpleBank/GetAccount",
+	)
+	require.NoError(t, err)
+	require.Equal(t, "user:"+user.Username, rateLimitCaller(ctx))
+}
+
+func requireRateLimited(t *testing.T, err error) {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+
+	require.Len(t, st.Details(), 2)
+	retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.Positive(t, retryInfo.RetryDelay.AsDuration())
+	errorInfo, ok := st.Details()[1].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	require.Equal(t, string(apperror.CodeRateLimited), errorInfo.Reason)
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"context"
+
+	"github.com/labasubagia/simplebank/grpc/pb"
+)
+
+func (server *Server) CloseAccount(ctx context.Context, req *pb.CloseAccountRequest) (*pb.CloseAccountResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := server.accountService.CloseAccount(ctx, authPayload.Username, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.CloseAccountResponse{Account: convertAccount(account)}
+	return res, nil
+}
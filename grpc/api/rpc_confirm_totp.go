@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) ConfirmTotp(ctx context.Context, req *pb.ConfirmTotpRequest) (*pb.ConfirmTotpResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if violations := validateConfirmTotpRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	userTotp, err := server.store.GetUserTotp(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Error(codes.FailedPrecondition, "two-factor authentication setup is not started")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user totp: %s", err)
+	}
+	if userTotp.IsEnabled {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+
+	step, ok := util.CheckTOTP(req.GetCode(), userTotp.Secret)
+	if !ok {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+			fieldValidation("code", errors.New("invalid totp code")),
+		})
+	}
+	// the confirmation code must not log in again afterwards
+	_, err = server.store.UseUserTotpStep(ctx, db.UseUserTotpStepParams{
+		Username:     authPayload.Username,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Error(codes.PermissionDenied, "totp code has already been used")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to use totp code: %s", err)
+	}
+
+	recoveryCodes, err := util.GenerateRecoveryCodes(util.RecoveryCodeCount)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate recovery codes: %s", err)
+	}
+	hashedRecoveryCodes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		hashedRecoveryCodes = append(hashedRecoveryCodes, util.HashRecoveryCode(code))
+	}
+
+	_, err = server.store.EnableTotpTx(ctx, db.EnableTotpTxParams{
+		Username:            authPayload.Username,
+		HashedRecoveryCodes: hashedRecoveryCodes,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to enable totp: %s", err)
+	}
+
+	res := &pb.ConfirmTotpResponse{
+		RecoveryCodes: recoveryCodes,
+	}
+	return res, nil
+}
+
+func validateConfirmTotpRequest(req *pb.ConfirmTotpRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := util.ValidateTOTPCode(req.GetCode()); err != nil {
+		violations = append(violations, fieldValidation("code", err))
+	}
+	return violations
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConfirmTotp(t *testing.T) {
+	user, _ := randomUser(t)
+	userTotp := randomUserTotp(t, user.Username, false)
+
+	validCode, err := totp.GenerateCode(userTotp.Secret, time.Now())
+	require.NoError(t, err)
+	invalidCode := "000000"
+	if invalidCode == validCode {
+		invalidCode = "111111"
+	}
+
+	testCases := []struct {
+		name          string
+		req           *pb.ConfirmTotpRequest
+		buildStubs    func(store *mock_db.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.ConfirmTotpResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.ConfirmTotpRequest{Code: validCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTotp, nil)
+				store.EXPECT().UseUserTotpStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseUserTotpStepParams) (db.UserTotp, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Positive(t, arg.LastUsedStep)
+						return userTotp, nil
+					})
+				store.EXPECT().EnableTotpTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.EnableTotpTxParams) (db.EnableTotpTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.HashedRecoveryCodes, util.RecoveryCodeCount)
+						return db.EnableTotpTxResult{}, nil
+					})
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTotpResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+				require.Len(t, res.GetRecoveryCodes(), util.RecoveryCodeCount)
+			},
+		},
+		{
+			name: "InvalidCode",
+			req:  &pb.ConfirmTotpRequest{Code: invalidCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTotp, nil)
+				store.EXPECT().EnableTotpTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTotpResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "InvalidInput",
+			req:  &pb.ConfirmTotpRequest{Code: "12ab"},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTotpResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "SetupNotStarted",
+			req:  &pb.ConfirmTotpRequest{Code: validCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTotpResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.FailedPrecondition, st.Code())
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			req:  &pb.ConfirmTotpRequest{Code: validCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				enabled := userTotp
+				enabled.IsEnabled = true
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(enabled, nil)
+				store.EXPECT().EnableTotpTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTotpResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.FailedPrecondition, st.Code())
+			},
+		},
+		{
+			name: "ReplayedCode",
+			req:  &pb.ConfirmTotpRequest{Code: validCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTotp, nil)
+				store.EXPECT().UseUserTotpStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().EnableTotpTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTotpResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name: "ErrEnableTotpTx",
+			req:  &pb.ConfirmTotpRequest{Code: validCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTotp, nil)
+				store.EXPECT().UseUserTotpStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(userTotp, nil)
+				store.EXPECT().EnableTotpTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EnableTotpTxResult{}, pgx.ErrTxClosed)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTotpResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			req:  &pb.ConfirmTotpRequest{Code: validCode},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTotpResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			ctx := tc.buildContext(t, server.tokenMaker)
+
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/ConfirmTotp", tc.req, server.ConfirmTotp)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
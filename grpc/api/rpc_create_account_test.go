@@ -148,7 +148,7 @@ func TestCreateAccount(t *testing.T) {
 			server := newTestServer(t, store, nil)
 			ctx := tc.buildContext(t, server.tokenMaker)
 
-			res, err := server.CreateAccount(ctx, tc.req)
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/CreateAccount", tc.req, server.CreateAccount)
 			tc.checkResponse(t, res, err)
 		})
 	}
@@ -160,5 +160,6 @@ func randomAccount(owner string) db.Account {
 		Owner:    owner,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Status:   util.AccountStatusActive,
 	}
 }
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) CreateApiKey(ctx context.Context, req *pb.CreateApiKeyRequest) (*pb.CreateApiKeyResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if violations := validateCreateApiKeyRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	key, prefix, err := util.GenerateApiKey()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate api key: %s", err)
+	}
+
+	expiresInDays := int32(util.DefaultApiKeyDays)
+	if req.ExpiresInDays != nil {
+		expiresInDays = req.GetExpiresInDays()
+	}
+
+	apiKey, err := server.store.CreateApiKey(ctx, db.CreateApiKeyParams{
+		ID:        uuid.New(),
+		Username:  authPayload.Username,
+		Name:      req.GetName(),
+		Prefix:    prefix,
+		HashedKey: util.HashApiKey(key),
+		Scopes:    req.GetScopes(),
+		ExpiredAt: time.Now().AddDate(0, 0, int(expiresInDays)),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create api key: %s", err)
+	}
+
+	res := &pb.CreateApiKeyResponse{
+		ApiKey: convertApiKey(apiKey),
+		Key:    key,
+	}
+	return res, nil
+}
+
+func validateCreateApiKeyRequest(req *pb.CreateApiKeyRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := util.ValidateString(req.GetName(), 1, 100); err != nil {
+		violations = append(violations, fieldValidation("name", err))
+	}
+	if err := util.ValidateScopes(req.GetScopes()); err != nil {
+		violations = append(violations, fieldValidation("scopes", err))
+	}
+	if req.ExpiresInDays != nil {
+		if days := req.GetExpiresInDays(); days < 1 || days > util.MaxApiKeyDays {
+			violations = append(violations, fieldValidation("expires_in_days", errors.New("must be between 1 and 365")))
+		}
+	}
+	return violations
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateApiKey(t *testing.T) {
+	user, _ := randomUser(t)
+	name := util.RandomString(8)
+	scopes := []string{util.ScopeTransfersWrite}
+	expiresInDays := int32(30)
+	invalidExpiresInDays := int32(1000)
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateApiKeyRequest
+		buildStubs    func(store *mock_db.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.CreateApiKeyResponse, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.CreateApiKeyRequest{
+				Name:          name,
+				Scopes:        scopes,
+				ExpiresInDays: &expiresInDays,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, name, arg.Name)
+						require.Equal(t, scopes, arg.Scopes)
+						require.NotEmpty(t, arg.Prefix)
+						require.Len(t, arg.HashedKey, 64)
+						require.WithinDuration(t, time.Now().AddDate(0, 0, 30), arg.ExpiredAt, time.Second)
+						return db.ApiKey{
+							ID:        arg.ID,
+							Username:  arg.Username,
+							Name:      arg.Name,
+							Prefix:    arg.Prefix,
+							HashedKey: arg.HashedKey,
+							Scopes:    arg.Scopes,
+							ExpiredAt: arg.ExpiredAt,
+						}, nil
+					})
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateApiKeyResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+				prefix, err := util.ParseApiKey(res.GetKey())
+				require.NoError(t, err)
+				require.Equal(t, prefix, res.GetApiKey().GetPrefix())
+				require.Equal(t, scopes, res.GetApiKey().GetScopes())
+			},
+		},
+		{
+			name: "InvalidInput",
+			req: &pb.CreateApiKeyRequest{
+				Name:          "",
+				Scopes:        []string{"admin"},
+				ExpiresInDays: &invalidExpiresInDays,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateApiKeyResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "ErrCreateApiKey",
+			req: &pb.CreateApiKeyRequest{
+				Name:   name,
+				Scopes: scopes,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, pgx.ErrTxClosed)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateApiKeyResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+		{
+			name: "ApiKeyNotAllowed",
+			req: &pb.CreateApiKeyRequest{
+				Name:   name,
+				Scopes: scopes,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				_, key := randomApiKey(t, user.Username, util.ScopeTransfersWrite)
+				return newContextWithApiKey(key)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateApiKeyResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			req: &pb.CreateApiKeyRequest{
+				Name:   name,
+				Scopes: scopes,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateApiKeyResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			ctx := tc.buildContext(t, server.tokenMaker)
+
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/CreateApiKey", tc.req, server.CreateApiKey)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func randomApiKey(t *testing.T, username string, scopes ...string) (db.ApiKey, string) {
+	key, prefix, err := util.GenerateApiKey()
+	require.NoError(t, err)
+
+	apiKey := db.ApiKey{
+		ID:        uuid.New(),
+		Username:  username,
+		Name:      util.RandomString(8),
+		Prefix:    prefix,
+		HashedKey: util.HashApiKey(key),
+		Scopes:    scopes,
+		ExpiredAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+	return apiKey, key
+}
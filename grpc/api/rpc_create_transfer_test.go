@@ -6,11 +6,13 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/labasubagia/simplebank/apperror"
 	mock_db "github.com/labasubagia/simplebank/db/mock"
 	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/grpc/pb"
 	"github.com/labasubagia/simplebank/util"
 	"github.com/labasubagia/simplebank/util/token"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc/codes"
@@ -232,6 +234,31 @@ func TestCreateTransfer(t *testing.T) {
 				require.Equal(t, codes.InvalidArgument, st.Code())
 			},
 		},
+		{
+			name: "ErrFromAccountClosed",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Currency:      currency,
+				Amount:        amount,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				closedAccount := account1
+				closedAccount.Status = util.AccountStatusClosed
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(closedAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user1.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.FailedPrecondition, st.Code())
+				requireErrorReason(t, st, apperror.CodeAccountClosed)
+			},
+		},
 		{
 			name: "ErrTransfer",
 			req: &pb.CreateTransferRequest{
@@ -253,6 +280,32 @@ func TestCreateTransfer(t *testing.T) {
 				st, ok := status.FromError(err)
 				require.True(t, ok)
 				require.Equal(t, codes.Internal, st.Code())
+				require.Equal(t, "internal server error", st.Message())
+				requireErrorReason(t, st, apperror.CodeInternal)
+			},
+		},
+		{
+			name: "ErrInsufficientFunds",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Currency:      currency,
+				Amount:        amount,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user1.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.FailedPrecondition, st.Code())
+				requireErrorReason(t, st, apperror.CodeInsufficientFunds)
 			},
 		},
 	}
@@ -270,7 +323,290 @@ func TestCreateTransfer(t *testing.T) {
 			server := newTestServer(t, store, nil)
 			ctx := tc.buildContext(t, server.tokenMaker)
 
-			res, err := server.CreateTransfer(ctx, tc.req)
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/CreateTransfer", tc.req, server.CreateTransfer)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func TestCreateTransferStepUp(t *testing.T) {
+	currency := util.USD
+	stepUpAmount := int64(100)
+
+	user1, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account1.Currency = currency
+
+	user2, _ := randomUser(t)
+	account2 := randomAccount(user2.Username)
+	account2.Currency = currency
+
+	userTotp := randomUserTotp(t, user1.Username, true)
+	validCode, err := totp.GenerateCode(userTotp.Secret, time.Now())
+	require.NoError(t, err)
+	invalidCode := "000000"
+	if invalidCode == validCode {
+		invalidCode = "111111"
+	}
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateTransferRequest
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, res *pb.CreateTransferResponse, err error)
+	}{
+		{
+			name: "BelowThreshold",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Currency:      currency,
+				Amount:        stepUpAmount,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+			},
+		},
+		{
+			name: "OK",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Currency:      currency,
+				Amount:        stepUpAmount + 1,
+				TotpCode:      &validCode,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().UseUserTotpStep(gomock.Any(), gomock.Any()).Times(1).Return(userTotp, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+			},
+		},
+		{
+			name: "TotpNotEnabled",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Currency:      currency,
+				Amount:        stepUpAmount + 1,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.FailedPrecondition, st.Code())
+			},
+		},
+		{
+			name: "MissingCode",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Currency:      currency,
+				Amount:        stepUpAmount + 1,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name: "InvalidCode",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Currency:      currency,
+				Amount:        stepUpAmount + 1,
+				TotpCode:      &invalidCode,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+
+			config := newTestConfig()
+			config.TransferStepUpAmount = stepUpAmount
+			// the step-up lockout is covered by the service tests
+			config.LoginMaxAttempts = 0
+			server, err := NewServer(config, store, nil, nil)
+			require.NoError(t, err)
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user1.Username, time.Minute)
+
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/CreateTransfer", tc.req, server.CreateTransfer)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func TestCreateTransferApiKey(t *testing.T) {
+	currency := util.USD
+	amount := int64(10)
+
+	user1, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account1.Currency = currency
+
+	user2, _ := randomUser(t)
+	account2 := randomAccount(user2.Username)
+	account2.Currency = currency
+
+	req := &pb.CreateTransferRequest{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Currency:      currency,
+		Amount:        amount,
+	}
+
+	testCases := []struct {
+		name          string
+		buildApiKey   func(apiKey *db.ApiKey)
+		scopes        []string
+		buildStubs    func(store *mock_db.MockStore, apiKey db.ApiKey)
+		checkResponse func(t *testing.T, res *pb.CreateTransferResponse, err error)
+	}{
+		{
+			name:   "OK",
+			scopes: []string{util.ScopeTransfersWrite},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+			},
+		},
+		{
+			name:   "MissingScope",
+			scopes: []string{util.ScopeTransfersRead},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name:   "Revoked",
+			scopes: []string{util.ScopeTransfersWrite},
+			buildApiKey: func(apiKey *db.ApiKey) {
+				apiKey.IsRevoked = true
+			},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+		{
+			name:   "Expired",
+			scopes: []string{util.ScopeTransfersWrite},
+			buildApiKey: func(apiKey *db.ApiKey) {
+				apiKey.ExpiredAt = time.Now().Add(-time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+		{
+			name:   "WrongSecret",
+			scopes: []string{util.ScopeTransfersWrite},
+			buildApiKey: func(apiKey *db.ApiKey) {
+				apiKey.HashedKey = util.HashApiKey("sb_other")
+			},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			apiKey, key := randomApiKey(t, user1.Username, tc.scopes...)
+			if tc.buildApiKey != nil {
+				tc.buildApiKey(&apiKey)
+			}
+			tc.buildStubs(store, apiKey)
+
+			server := newTestServer(t, store, nil)
+			ctx := newContextWithApiKey(key)
+
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/CreateTransfer", req, server.CreateTransfer)
 			tc.checkResponse(t, res, err)
 		})
 	}
@@ -2,74 +2,24 @@ package api
 
 import (
 	"context"
-	"time"
 
-	"github.com/hibiken/asynq"
-	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/grpc/pb"
-	"github.com/labasubagia/simplebank/util"
-	"github.com/labasubagia/simplebank/worker"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/labasubagia/simplebank/service"
 )
 
 func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
-	if violations := validateCreateUserRequest(req); violations != nil {
-		return nil, invalidArgumentError(violations)
-	}
-
-	hashedPassword, err := util.HashPassword(req.GetPassword())
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
-	}
-
-	arg := db.CreateUserTxParams{
-		CreateUserParams: db.CreateUserParams{
-			Username:       req.GetUsername(),
-			Email:          req.GetEmail(),
-			FullName:       req.GetFullName(),
-			HashedPassword: hashedPassword,
-		},
-		AfterCreate: func(user db.User) error {
-			taskPayload := &worker.PayloadSendVerifyEmail{
-				Username: user.Username,
-			}
-			opts := []asynq.Option{
-				asynq.MaxRetry(10),
-				asynq.ProcessIn(10 * time.Second),
-				asynq.Queue(worker.QueueCritical),
-			}
-			return server.taskDistributor.DistributeTaskVerifyEmail(ctx, taskPayload, opts...)
-		},
-	}
-
-	result, err := server.store.CreateUserTx(ctx, arg)
+	user, err := server.userService.CreateUser(ctx, service.CreateUserParams{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+		FullName: req.GetFullName(),
+		Email:    req.GetEmail(),
+	})
 	if err != nil {
-		if db.ErrorCode(err) == db.UniqueViolation {
-			return nil, status.Errorf(codes.AlreadyExists, "username already exists: %s", err)
-		}
-		return nil, status.Errorf(codes.Internal, "failed to create user: %s", err)
+		return nil, err
 	}
 
 	res := &pb.CreateUserResponse{
-		User: convertUser(result.User),
+		User: convertUser(user),
 	}
 	return res, nil
 }
-
-func validateCreateUserRequest(req *pb.CreateUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
-	if err := util.ValidateEmail(req.GetEmail()); err != nil {
-		violations = append(violations, fieldValidation("email", err))
-	}
-	if err := util.ValidateUsername(req.GetUsername()); err != nil {
-		violations = append(violations, fieldValidation("username", err))
-	}
-	if err := util.ValidateFullName(req.GetFullName()); err != nil {
-		violations = append(violations, fieldValidation("full_name", err))
-	}
-	if err := util.ValidatePassword(req.GetPassword()); err != nil {
-		violations = append(violations, fieldValidation("password", err))
-	}
-	return violations
-}
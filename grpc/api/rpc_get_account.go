@@ -0,0 +1,22 @@
+package api
+
+import (
+	"context"
+
+	"github.com/labasubagia/simplebank/grpc/pb"
+)
+
+func (server *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := server.accountService.GetAccount(ctx, authPayload.Username, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.GetAccountResponse{Account: convertAccount(account)}
+	return res, nil
+}
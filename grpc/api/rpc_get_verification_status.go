@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (server *Server) GetVerificationStatus(ctx context.Context, req *pb.GetVerificationStatusRequest) (*pb.GetVerificationStatusResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	res := &pb.GetVerificationStatusResponse{
+		IsEmailVerified: user.IsEmailVerified,
+		Email:           user.Email,
+	}
+	if user.PendingEmail.Valid {
+		res.PendingEmail = &user.PendingEmail.String
+	}
+
+	verifyEmail, err := server.store.GetLatestVerifyEmail(ctx, user.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return res, nil
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get verify email: %s", err)
+	}
+	res.LastSentAt = timestamppb.New(verifyEmail.CreatedAt)
+	if !verifyEmail.IsUsed && verifyEmail.ExpiredAt.After(time.Now()) {
+		res.ExpiresAt = timestamppb.New(verifyEmail.ExpiredAt)
+	}
+	return res, nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetVerificationStatus(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsEmailVerified = false
+
+	activeCode := db.VerifyEmail{
+		ID:         util.RandomInt(1, 1000),
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+		CreatedAt:  time.Now().Add(-time.Minute),
+		ExpiredAt:  time.Now().Add(14 * time.Minute),
+	}
+	expiredCode := activeCode
+	expiredCode.ExpiredAt = time.Now().Add(-time.Minute)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mock_db.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.GetVerificationStatusResponse, err error)
+	}{
+		{
+			name: "ActiveCode",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetLatestVerifyEmail(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(activeCode, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetVerificationStatusResponse, err error) {
+				require.NoError(t, err)
+				require.False(t, res.GetIsEmailVerified())
+				require.Equal(t, user.Email, res.GetEmail())
+				require.WithinDuration(t, activeCode.CreatedAt, res.GetLastSentAt().AsTime(), time.Second)
+				require.WithinDuration(t, activeCode.ExpiredAt, res.GetExpiresAt().AsTime(), time.Second)
+			},
+		},
+		{
+			name: "ExpiredCode",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetLatestVerifyEmail(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(expiredCode, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetVerificationStatusResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res.GetLastSentAt())
+				require.Nil(t, res.GetExpiresAt())
+			},
+		},
+		{
+			name: "NoCode",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetLatestVerifyEmail(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.VerifyEmail{}, db.ErrRecordNotFound)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetVerificationStatusResponse, err error) {
+				require.NoError(t, err)
+				require.Nil(t, res.GetLastSentAt())
+				require.Nil(t, res.GetExpiresAt())
+			},
+		},
+		{
+			name: "Unauthenticated",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.GetVerificationStatusResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			ctx := tc.buildContext(t, server.tokenMaker)
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/GetVerificationStatus", &pb.GetVerificationStatusRequest{}, server.GetVerificationStatus)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/service"
+)
+
+func (server *Server) ListAccountEntries(ctx context.Context, req *pb.ListAccountEntriesRequest) (*pb.ListAccountEntriesResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := server.accountService.ListAccountEntries(ctx, authPayload.Username, service.HistoryParams{
+		AccountID: req.GetAccountId(),
+		PageSize:  req.GetPageSize(),
+		PageToken: req.GetPageToken(),
+		StartTime: toTime(req.GetStartTime()),
+		EndTime:   toTime(req.GetEndTime()),
+		Direction: req.Direction,
+		MinAmount: req.MinAmount,
+		MaxAmount: req.MaxAmount,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListAccountEntriesResponse{
+		Entries:       make([]*pb.Entry, 0, len(result.Entries)),
+		NextPageToken: result.NextPageToken,
+	}
+	for _, entry := range result.Entries {
+		res.Entries = append(res.Entries, convertEntry(entry))
+	}
+	return res, nil
+}
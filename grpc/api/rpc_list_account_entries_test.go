@@ -0,0 +1,295 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestListAccountEntries(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	n := 5
+	entries := make([]db.Entry, n)
+	for i := 0; i < n; i++ {
+		entries[i] = randomEntry(account.ID)
+	}
+
+	startTime := time.Now().Add(-24 * time.Hour)
+	endTime := time.Now()
+	direction := util.DirectionOut
+	minAmount := int64(10)
+	maxAmount := int64(100)
+	invalidDirection := "sideways"
+
+	testCases := []struct {
+		name          string
+		req           *pb.ListAccountEntriesRequest
+		buildStubs    func(store *mock_db.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.ListAccountEntriesResponse, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.ListAccountEntriesRequest{
+				AccountId: account.ID,
+				PageSize:  int32(n),
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				arg := db.ListAccountEntriesParams{
+					AccountID: account.ID,
+					Limit:     int32(n + 1),
+				}
+				store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(entries, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountEntriesResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetEntries(), n)
+				for i, entry := range res.GetEntries() {
+					require.Equal(t, entries[i].ID, entry.GetId())
+					require.Equal(t, entries[i].Amount, entry.GetAmount())
+				}
+				require.Empty(t, res.GetNextPageToken())
+			},
+		},
+		{
+			name: "HasNextPage",
+			req: &pb.ListAccountEntriesRequest{
+				AccountId: account.ID,
+				PageSize:  int32(n - 1),
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(entries, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountEntriesResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetEntries(), n-1)
+				require.NotEmpty(t, res.GetNextPageToken())
+			},
+		},
+		{
+			name: "InvalidPageToken",
+			req: &pb.ListAccountEntriesRequest{
+				AccountId: account.ID,
+				PageSize:  int32(n),
+				PageToken: "invalid",
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountEntriesResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "Filtered",
+			req: &pb.ListAccountEntriesRequest{
+				AccountId: account.ID,
+				PageSize:  int32(n),
+				StartTime: timestamppb.New(startTime),
+				EndTime:   timestamppb.New(endTime),
+				Direction: &direction,
+				MinAmount: &minAmount,
+				MaxAmount: &maxAmount,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListAccountEntriesParams) ([]db.Entry, error) {
+						require.Equal(t, int32(n+1), arg.Limit)
+						require.True(t, arg.StartTime.Time.Equal(startTime))
+						require.True(t, arg.EndTime.Time.Equal(endTime))
+						require.Equal(t, pgtype.Text{String: direction, Valid: true}, arg.Direction)
+						require.Equal(t, pgtype.Int8{Int64: minAmount, Valid: true}, arg.MinAmount)
+						require.Equal(t, pgtype.Int8{Int64: maxAmount, Valid: true}, arg.MaxAmount)
+						return []db.Entry{}, nil
+					})
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountEntriesResponse, err error) {
+				require.NoError(t, err)
+				require.Empty(t, res.GetEntries())
+			},
+		},
+		{
+			name: "InvalidFilter",
+			req: &pb.ListAccountEntriesRequest{
+				AccountId: account.ID,
+				PageSize:  int32(n),
+				StartTime: timestamppb.New(endTime),
+				EndTime:   timestamppb.New(startTime),
+				Direction: &invalidDirection,
+				MinAmount: &maxAmount,
+				MaxAmount: &minAmount,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountEntriesResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "PermissionDenied",
+			req: &pb.ListAccountEntriesRequest{
+				AccountId: account.ID,
+				PageSize:  int32(n),
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, util.RandomOwner(), time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountEntriesResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name: "AccountNotFound",
+			req: &pb.ListAccountEntriesRequest{
+				AccountId: account.ID,
+				PageSize:  int32(n),
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountEntriesResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.NotFound, st.Code())
+			},
+		},
+		{
+			name: "InternalError",
+			req: &pb.ListAccountEntriesRequest{
+				AccountId: account.ID,
+				PageSize:  int32(n),
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, pgx.ErrTxClosed)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountEntriesResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			req: &pb.ListAccountEntriesRequest{
+				AccountId: account.ID,
+				PageSize:  int32(n),
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountEntriesResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			ctx := tc.buildContext(t, server.tokenMaker)
+
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/ListAccountEntries", tc.req, server.ListAccountEntries)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func randomEntry(accountID int64) db.Entry {
+	return db.Entry{
+		ID:        util.RandomInt(1, 1000),
+		AccountID: accountID,
+		Amount:    util.RandomMoney(),
+		CreatedAt: time.Now(),
+	}
+}
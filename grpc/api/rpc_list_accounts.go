@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/service"
+)
+
+func (server *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := server.accountService.ListAccounts(ctx, authPayload.Username, service.ListAccountsParams{
+		PageSize:  req.GetPageSize(),
+		PageToken: req.GetPageToken(),
+		Currency:  req.Currency,
+		Status:    req.Status,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListAccountsResponse{
+		Accounts:      make([]*pb.Account, 0, len(result.Accounts)),
+		NextPageToken: result.NextPageToken,
+	}
+	for _, account := range result.Accounts {
+		res.Accounts = append(res.Accounts, convertAccount(account))
+	}
+	return res, nil
+}
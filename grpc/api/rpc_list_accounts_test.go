@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestListAccounts(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 5
+	accounts := make([]db.Account, n+1)
+	for i := 0; i < n+1; i++ {
+		accounts[i] = randomAccount(user.Username)
+	}
+
+	currency := util.USD
+	accountStatus := util.AccountStatusActive
+	invalidStatus := "frozen"
+
+	testCases := []struct {
+		name          string
+		req           *pb.ListAccountsRequest
+		buildStubs    func(store *mock_db.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.ListAccountsResponse, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.ListAccountsRequest{
+				PageSize: int32(n),
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				arg := db.ListAccountsParams{
+					Owner: user.Username,
+					Limit: int32(n + 1),
+				}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(accounts[:n], nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetAccounts(), n)
+				for i, account := range res.GetAccounts() {
+					require.Equal(t, accounts[i].ID, account.GetId())
+				}
+				require.Empty(t, res.GetNextPageToken())
+			},
+		},
+		{
+			name: "HasNextPage",
+			req: &pb.ListAccountsRequest{
+				PageSize: int32(n),
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(accounts, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetAccounts(), n)
+				require.NotEmpty(t, res.GetNextPageToken())
+			},
+		},
+		{
+			name: "InvalidPageToken",
+			req: &pb.ListAccountsRequest{
+				PageSize:  int32(n),
+				PageToken: "invalid",
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "Filtered",
+			req: &pb.ListAccountsRequest{
+				PageSize: int32(n),
+				Currency: &currency,
+				Status:   &accountStatus,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				arg := db.ListAccountsParams{
+					Owner:    user.Username,
+					Currency: pgtype.Text{String: currency, Valid: true},
+					Status:   pgtype.Text{String: accountStatus, Valid: true},
+					Limit:    int32(n + 1),
+				}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Account{}, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.NoError(t, err)
+				require.Empty(t, res.GetAccounts())
+			},
+		},
+		{
+			name: "InvalidInput",
+			req: &pb.ListAccountsRequest{
+				PageSize: 100,
+				Status:   &invalidStatus,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "InternalError",
+			req: &pb.ListAccountsRequest{
+				PageSize: int32(n),
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, pgx.ErrTxClosed)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			req: &pb.ListAccountsRequest{
+				PageSize: int32(n),
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			ctx := tc.buildContext(t, server.tokenMaker)
+
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/ListAccounts", tc.req, server.ListAccounts)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) ListApiKeys(ctx context.Context, req *pb.ListApiKeysRequest) (*pb.ListApiKeysResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := server.store.ListApiKeys(ctx, authPayload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list api keys: %s", err)
+	}
+
+	res := &pb.ListApiKeysResponse{
+		ApiKeys: make([]*pb.ApiKey, 0, len(apiKeys)),
+	}
+	for _, apiKey := range apiKeys {
+		res.ApiKeys = append(res.ApiKeys, convertApiKey(apiKey))
+	}
+	return res, nil
+}
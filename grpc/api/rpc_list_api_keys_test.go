@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestListApiKeys(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 3
+	apiKeys := make([]db.ApiKey, n)
+	for i := 0; i < n; i++ {
+		apiKeys[i], _ = randomApiKey(t, user.Username, util.ScopeAccountsRead)
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mock_db.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.ListApiKeysResponse, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListApiKeys(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(apiKeys, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListApiKeysResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetApiKeys(), n)
+				for i, apiKey := range res.GetApiKeys() {
+					require.Equal(t, apiKeys[i].ID.String(), apiKey.GetId())
+					require.Equal(t, apiKeys[i].Prefix, apiKey.GetPrefix())
+				}
+			},
+		},
+		{
+			name: "ErrListApiKeys",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListApiKeys(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, pgx.ErrTxClosed)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListApiKeysResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ListApiKeys(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.ListApiKeysResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			ctx := tc.buildContext(t, server.tokenMaker)
+
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/ListApiKeys", &pb.ListApiKeysRequest{}, server.ListApiKeys)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/service"
+)
+
+func (server *Server) ListTransfers(ctx context.Context, req *pb.ListTransfersRequest) (*pb.ListTransfersResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := server.transferService.ListTransfers(ctx, authPayload.Username, service.ListTransfersParams{
+		HistoryParams: service.HistoryParams{
+			AccountID: req.GetAccountId(),
+			PageSize:  req.GetPageSize(),
+			PageToken: req.GetPageToken(),
+			StartTime: toTime(req.GetStartTime()),
+			EndTime:   toTime(req.GetEndTime()),
+			Direction: req.Direction,
+			MinAmount: req.MinAmount,
+			MaxAmount: req.MaxAmount,
+		},
+		CounterpartyAccountID: req.CounterpartyAccountId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListTransfersResponse{
+		Transfers:     make([]*pb.Transfer, 0, len(result.Transfers)),
+		NextPageToken: result.NextPageToken,
+	}
+	for _, transfer := range result.Transfers {
+		res.Transfers = append(res.Transfers, convertTransfer(transfer))
+	}
+	return res, nil
+}
@@ -2,76 +2,46 @@ package api
 
 import (
 	"context"
-	"errors"
 
-	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/grpc/pb"
-	"github.com/labasubagia/simplebank/util"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/labasubagia/simplebank/service"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
-	if violations := validateLoginUserRequest(req); violations != nil {
-		return nil, invalidArgumentError(violations)
-	}
-
-	user, err := server.store.GetUser(ctx, req.GetUsername())
-	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) {
-			return nil, status.Error(codes.NotFound, "failed to find user")
-		}
-		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
-	}
-
-	err = util.CheckPassword(req.GetPassword(), user.HashedPassword)
-	if err != nil {
-		return nil, status.Error(codes.PermissionDenied, "username or password invalid")
-	}
-
-	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create access token: %s", err)
-	}
-
-	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.RefreshTokenDuration)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create refresh token: %s", err)
-	}
-
-	metadata := server.extractMetadata(ctx)
-	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshPayload.ID,
-		Username:     user.Username,
-		RefreshToken: refreshToken,
-		UserAgent:    metadata.UserAgent,
-		ClientIp:     metadata.ClientIP,
-		IsBlocked:    false,
-		ExpiredAt:    refreshPayload.ExpiredAt,
+	result, err := server.authService.Login(ctx, service.LoginParams{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+		Client:   server.loginClient(ctx),
 	})
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed create session: %s", err)
+		return nil, err
 	}
+	return convertLoginResult(result), nil
+}
 
-	res := &pb.LoginUserResponse{
-		SessionId:             session.ID.String(),
-		AccessToken:           accessToken,
-		AccessTokenExpiresAt:  timestamppb.New(accessPayload.ExpiredAt),
-		RefreshToken:          refreshToken,
-		RefreshTokenExpiresAt: timestamppb.New(refreshPayload.ExpiredAt),
-		User:                  convertUser(user),
+func (server *Server) loginClient(ctx context.Context) service.Client {
+	metadata := server.extractMetadata(ctx)
+	return service.Client{
+		UserAgent: metadata.UserAgent,
+		ClientIP:  metadata.ClientIP,
 	}
-	return res, nil
 }
 
-func validateLoginUserRequest(req *pb.LoginUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
-	if err := util.ValidateUsername(req.GetUsername()); err != nil {
-		violations = append(violations, fieldValidation("username", err))
+func convertLoginResult(result service.LoginResult) *pb.LoginUserResponse {
+	if result.Challenge != nil {
+		return &pb.LoginUserResponse{
+			TotpRequired:       true,
+			ChallengeId:        result.Challenge.ID.String(),
+			ChallengeExpiresAt: timestamppb.New(result.Challenge.ExpiredAt),
+		}
 	}
-	if err := util.ValidatePassword(req.GetPassword()); err != nil {
-		violations = append(violations, fieldValidation("password", err))
+	return &pb.LoginUserResponse{
+		SessionId:             result.Session.ID.String(),
+		AccessToken:           result.AccessToken,
+		AccessTokenExpiresAt:  timestamppb.New(result.AccessPayload.ExpiredAt),
+		RefreshToken:          result.RefreshToken,
+		RefreshTokenExpiresAt: timestamppb.New(result.RefreshPayload.ExpiredAt),
+		User:                  convertUser(result.User),
 	}
-	return violations
 }
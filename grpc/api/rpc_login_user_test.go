@@ -3,14 +3,17 @@ package api
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	mock_db "github.com/labasubagia/simplebank/db/mock"
 	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
 	mock_worker "github.com/labasubagia/simplebank/worker/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -21,7 +24,7 @@ func TestLoginUser(t *testing.T) {
 	testCases := []struct {
 		name          string
 		req           *pb.LoginUserRequest
-		buildStubs    func(store *mock_db.MockStore)
+		buildStubs    func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor)
 		checkResponse func(t *testing.T, res *pb.LoginUserResponse, err error)
 	}{
 		{
@@ -30,10 +33,19 @@ func TestLoginUser(t *testing.T) {
 				Username: user.Username,
 				Password: password,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
 				store.EXPECT().GetUser(gomock.Any(), user.Username).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().DeleteLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
 				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(db.Session{Username: user.Username}, nil)
@@ -49,10 +61,19 @@ func TestLoginUser(t *testing.T) {
 				Username: user.Username,
 				Password: password,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
 				store.EXPECT().GetUser(gomock.Any(), user.Username).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().DeleteLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
 				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(db.Session{}, pgx.ErrTxClosed)
@@ -64,13 +85,173 @@ func TestLoginUser(t *testing.T) {
 				require.Equal(t, codes.Internal, st.Code())
 			},
 		},
+		{
+			name: "TotpRequired",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().DeleteLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).
+					Times(1).
+					Return(db.UserTotp{Username: user.Username, IsEnabled: true}, nil)
+				store.EXPECT().CreateLoginChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateLoginChallengeParams) (db.LoginChallenge, error) {
+						return db.LoginChallenge{ID: arg.ID, Username: arg.Username, ExpiredAt: arg.ExpiredAt}, nil
+					})
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+				require.True(t, res.GetTotpRequired())
+				require.NotEmpty(t, res.GetChallengeId())
+				require.Empty(t, res.GetAccessToken())
+			},
+		},
+		{
+			name: "ErrGetUserTotp",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().DeleteLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).
+					Times(1).
+					Return(db.UserTotp{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+		{
+			name: "WrongPassword",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: "wrong_password",
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().RecordFailedLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{Key: util.LoginAttemptUserKey(user.Username), FailedCount: 1}, nil)
+				store.EXPECT().LockLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name: "WrongPasswordLockout",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: "wrong_password",
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				key := util.LoginAttemptUserKey(user.Username)
+				store.EXPECT().GetLoginAttempt(gomock.Any(), gomock.Eq(key)).
+					Times(1).
+					Return(db.LoginAttempt{Key: key, FailedCount: 4}, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().RecordFailedLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{Key: key, FailedCount: 5}, nil)
+				store.EXPECT().LockLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.LockLoginAttemptParams) (db.LoginAttempt, error) {
+						require.Equal(t, key, arg.Key)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.LockedUntil, time.Second)
+						return db.LoginAttempt{Key: key, FailedCount: 5, LockedUntil: arg.LockedUntil}, nil
+					})
+				taskDistributor.EXPECT().DistributeTaskSendLockoutEmail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireLoginLocked(t, err)
+			},
+		},
+		{
+			name: "Locked",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				key := util.LoginAttemptUserKey(user.Username)
+				store.EXPECT().GetLoginAttempt(gomock.Any(), gomock.Eq(key)).
+					Times(1).
+					Return(db.LoginAttempt{Key: key, FailedCount: 5, LockedUntil: time.Now().Add(time.Minute)}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireLoginLocked(t, err)
+			},
+		},
+		{
+			name: "ErrGetLoginAttempt",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{}, pgx.ErrTxClosed)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
 		{
 			name: "InvalidInput",
 			req: &pb.LoginUserRequest{
 				Username: "invalid_username_?#@",
 				Password: "",
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
 			},
 			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
 				require.Error(t, err)
@@ -85,10 +266,16 @@ func TestLoginUser(t *testing.T) {
 				Username: user.Username,
 				Password: password,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
 				store.EXPECT().GetUser(gomock.Any(), user.Username).
 					Times(1).
 					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().RecordFailedLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{Key: util.LoginAttemptUserKey(user.Username), FailedCount: 1}, nil)
 			},
 			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
 				require.Error(t, err)
@@ -103,7 +290,10 @@ func TestLoginUser(t *testing.T) {
 				Username: user.Username,
 				Password: "invalid_password",
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
 				store.EXPECT().GetUser(gomock.Any(), user.Username).
 					Times(1).
 					Return(db.User{}, pgx.ErrTxClosed)
@@ -127,7 +317,7 @@ func TestLoginUser(t *testing.T) {
 			taskCtrl := gomock.NewController(t)
 			defer taskCtrl.Finish()
 			taskDistributor := mock_worker.NewMockTaskDistributor(taskCtrl)
-			tc.buildStubs(store)
+			tc.buildStubs(store, taskDistributor)
 
 			server := newTestServer(t, store, taskDistributor)
 			res, err := server.LoginUser(context.Background(), tc.req)
@@ -135,3 +325,19 @@ func TestLoginUser(t *testing.T) {
 		})
 	}
 }
+
+func requireLoginLocked(t *testing.T, err error) {
+	require.Error(t, err)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+
+	var retryInfo *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retryInfo = info
+		}
+	}
+	require.NotNil(t, retryInfo)
+	require.Positive(t, retryInfo.GetRetryDelay().AsDuration())
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/labasubagia/simplebank/apperror"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/worker"
+)
+
+func (server *Server) ResendVerifyEmail(ctx context.Context, req *pb.ResendVerifyEmailRequest) (*pb.ResendVerifyEmailResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, apperror.New(apperror.CodeNotFound, "user not found")
+		}
+		return nil, apperror.Internal(fmt.Errorf("failed to get user: %w", err))
+	}
+
+	// a pending email change takes precedence, like in UpdateUser
+	taskPayload := &worker.PayloadSendVerifyEmail{Username: user.Username}
+	email := user.Email
+	switch {
+	case user.PendingEmail.Valid:
+		taskPayload.Email = user.PendingEmail.String
+		email = user.PendingEmail.String
+	case user.IsEmailVerified:
+		return nil, apperror.New(apperror.CodeFailedPrecondition, "email is already verified")
+	}
+
+	// only the code in the new email is usable
+	if err := server.store.InvalidateVerifyEmails(ctx, user.Username); err != nil {
+		return nil, apperror.Internal(fmt.Errorf("failed to invalidate verify emails: %w", err))
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical),
+	}
+	err = server.taskDistributor.DistributeTaskVerifyEmail(ctx, taskPayload, opts...)
+	if err != nil {
+		return nil, apperror.Internal(fmt.Errorf("failed to distribute verify email task: %w", err))
+	}
+
+	return &pb.ResendVerifyEmailResponse{Email: email}, nil
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordResponse, error) {
+	if violations := validateResetPasswordRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	hashedPassword, err := server.passwordHasher.Hash(req.GetNewPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
+	}
+
+	_, err = server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		ResetPasswordID: req.GetResetId(),
+		SecretCode:      req.GetSecretCode(),
+		HashedPassword:  hashedPassword,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Error(codes.PermissionDenied, "reset code is invalid, used or expired")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to reset password: %s", err)
+	}
+
+	res := &pb.ResetPasswordResponse{
+		IsReset: true,
+	}
+	return res, nil
+}
+
+func validateResetPasswordRequest(req *pb.ResetPasswordRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := util.ValidateID(req.GetResetId()); err != nil {
+		violations = append(violations, fieldValidation("reset_id", err))
+	}
+	if err := util.ValidateSecretCode(req.GetSecretCode()); err != nil {
+		violations = append(violations, fieldValidation("secret_code", err))
+	}
+	if err := util.ValidatePassword(req.GetNewPassword()); err != nil {
+		violations = append(violations, fieldValidation("new_password", err))
+	}
+	return violations
+}
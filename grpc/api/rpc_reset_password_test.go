@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type eqResetPasswordTxParamsMatcher struct {
+	arg      db.ResetPasswordTxParams
+	password string
+}
+
+func (expected eqResetPasswordTxParamsMatcher) Matches(x any) bool {
+	actual, ok := x.(db.ResetPasswordTxParams)
+	if !ok {
+		return false
+	}
+	if err := util.CheckPassword(expected.password, actual.HashedPassword); err != nil {
+		return false
+	}
+	return expected.arg.ResetPasswordID == actual.ResetPasswordID &&
+		expected.arg.SecretCode == actual.SecretCode
+}
+
+func (expected eqResetPasswordTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v and password %v", expected.arg, expected.password)
+}
+
+func TestResetPassword(t *testing.T) {
+	user, _ := randomUser(t)
+	resetPassword := db.ResetPassword{
+		ID:         util.RandomInt(1, 200),
+		Username:   user.Username,
+		SecretCode: util.RandomString(32),
+		CreatedAt:  time.Now(),
+		ExpiredAt:  time.Now().Add(time.Minute),
+	}
+	newPassword := util.RandomString(8)
+
+	testCases := []struct {
+		name          string
+		req           *pb.ResetPasswordRequest
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, res *pb.ResetPasswordResponse, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.ResetPasswordRequest{
+				ResetId:     resetPassword.ID,
+				SecretCode:  resetPassword.SecretCode,
+				NewPassword: newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				arg := db.ResetPasswordTxParams{
+					ResetPasswordID: resetPassword.ID,
+					SecretCode:      resetPassword.SecretCode,
+				}
+				store.EXPECT().ResetPasswordTx(gomock.Any(), eqResetPasswordTxParamsMatcher{arg, newPassword}).
+					Times(1).
+					Return(db.ResetPasswordTxResult{User: user, ResetPassword: resetPassword}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.ResetPasswordResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+				require.True(t, res.GetIsReset())
+			},
+		},
+		{
+			name: "InvalidCode",
+			req: &pb.ResetPasswordRequest{
+				ResetId:     resetPassword.ID,
+				SecretCode:  util.RandomString(32),
+				NewPassword: newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ResetPasswordTxResult{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, res *pb.ResetPasswordResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name: "InvalidInput",
+			req: &pb.ResetPasswordRequest{
+				ResetId:     -1,
+				SecretCode:  "",
+				NewPassword: "short",
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ResetPasswordResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "ErrResetPasswordTx",
+			req: &pb.ResetPasswordRequest{
+				ResetId:     resetPassword.ID,
+				SecretCode:  resetPassword.SecretCode,
+				NewPassword: newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ResetPasswordTxResult{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, res *pb.ResetPasswordResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+
+			res, err := server.ResetPassword(context.Background(), tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
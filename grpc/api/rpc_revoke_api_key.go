@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) RevokeApiKey(ctx context.Context, req *pb.RevokeApiKeyRequest) (*pb.RevokeApiKeyResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if violations := validateRevokeApiKeyRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	apiKey, err := server.store.RevokeApiKey(ctx, db.RevokeApiKeyParams{
+		ID:       uuid.MustParse(req.GetId()),
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "api key not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to revoke api key: %s", err)
+	}
+
+	res := &pb.RevokeApiKeyResponse{
+		ApiKey: convertApiKey(apiKey),
+	}
+	return res, nil
+}
+
+func validateRevokeApiKeyRequest(req *pb.RevokeApiKeyRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if _, err := uuid.Parse(req.GetId()); err != nil {
+		violations = append(violations, fieldValidation("id", errors.New("must be a valid uuid")))
+	}
+	return violations
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRevokeApiKey(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey, _ := randomApiKey(t, user.Username, util.ScopeAccountsRead)
+
+	testCases := []struct {
+		name          string
+		req           *pb.RevokeApiKeyRequest
+		buildStubs    func(store *mock_db.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.RevokeApiKeyResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.RevokeApiKeyRequest{Id: apiKey.ID.String()},
+			buildStubs: func(store *mock_db.MockStore) {
+				arg := db.RevokeApiKeyParams{
+					ID:       apiKey.ID,
+					Username: user.Username,
+				}
+				revoked := apiKey
+				revoked.IsRevoked = true
+				store.EXPECT().RevokeApiKey(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(revoked, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.RevokeApiKeyResponse, err error) {
+				require.NoError(t, err)
+				require.True(t, res.GetApiKey().GetIsRevoked())
+			},
+		},
+		{
+			name: "NotFound",
+			req:  &pb.RevokeApiKeyRequest{Id: apiKey.ID.String()},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().RevokeApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, db.ErrRecordNotFound)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, "other_user", time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.RevokeApiKeyResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.NotFound, st.Code())
+			},
+		},
+		{
+			name: "InvalidID",
+			req:  &pb.RevokeApiKeyRequest{Id: "invalid_id"},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().RevokeApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.RevokeApiKeyResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "ErrRevokeApiKey",
+			req:  &pb.RevokeApiKeyRequest{Id: apiKey.ID.String()},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().RevokeApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, pgx.ErrTxClosed)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.RevokeApiKeyResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			req:  &pb.RevokeApiKeyRequest{Id: apiKey.ID.String()},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().RevokeApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.RevokeApiKeyResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			ctx := tc.buildContext(t, server.tokenMaker)
+
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/RevokeApiKey", tc.req, server.RevokeApiKey)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
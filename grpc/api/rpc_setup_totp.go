@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) SetupTotp(ctx context.Context, req *pb.SetupTotpRequest) (*pb.SetupTotpResponse, error) {
+	authPayload, err := server.authenticatedPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userTotp, err := server.store.GetUserTotp(ctx, authPayload.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get user totp: %s", err)
+	}
+	if err == nil && userTotp.IsEnabled {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+
+	issuer := server.config.TOTPIssuer
+	if issuer == "" {
+		issuer = util.DefaultTOTPIssuer
+	}
+	secret, url, err := util.GenerateTOTP(issuer, authPayload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate totp: %s", err)
+	}
+
+	_, err = server.store.UpsertUserTotp(ctx, db.UpsertUserTotpParams{
+		Username: authPayload.Username,
+		Secret:   secret,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to save user totp: %s", err)
+	}
+
+	res := &pb.SetupTotpResponse{
+		Secret:     secret,
+		OtpauthUrl: url,
+	}
+	return res, nil
+}
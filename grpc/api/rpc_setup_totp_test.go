@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSetupTotp(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mock_db.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.SetupTotpResponse, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().UpsertUserTotp(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpsertUserTotpParams) (db.UserTotp, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotEmpty(t, arg.Secret)
+						return db.UserTotp{Username: arg.Username, Secret: arg.Secret}, nil
+					})
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.SetupTotpResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+				require.NotEmpty(t, res.GetSecret())
+				require.Contains(t, res.GetOtpauthUrl(), "otpauth://totp/")
+				require.Contains(t, res.GetOtpauthUrl(), res.GetSecret())
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(randomUserTotp(t, user.Username, true), nil)
+				store.EXPECT().UpsertUserTotp(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.SetupTotpResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.FailedPrecondition, st.Code())
+			},
+		},
+		{
+			name: "ErrUpsertUserTotp",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().UpsertUserTotp(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, pgx.ErrTxClosed)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.SetupTotpResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.SetupTotpResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			ctx := tc.buildContext(t, server.tokenMaker)
+
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/SetupTotp", &pb.SetupTotpRequest{}, server.SetupTotp)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func randomUserTotp(t *testing.T, username string, enabled bool) db.UserTotp {
+	secret, _, err := util.GenerateTOTP(util.DefaultTOTPIssuer, username)
+	require.NoError(t, err)
+
+	userTotp := db.UserTotp{
+		Username:  username,
+		Secret:    secret,
+		IsEnabled: enabled,
+		CreatedAt: time.Now(),
+	}
+	if enabled {
+		userTotp.EnabledAt = time.Now()
+	}
+	return userTotp
+}
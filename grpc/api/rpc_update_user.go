@@ -2,65 +2,43 @@ package api
 
 import (
 	"context"
-	"errors"
-	"time"
 
-	"github.com/jackc/pgx/v5/pgtype"
-	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/grpc/pb"
-	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/service"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
+// updateUserMaskPaths are the fields UpdateUser accepts in its update mask.
+var updateUserMaskPaths = []string{"full_name", "email", "password"}
+
 func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
-	authPayload, err := server.authorizeUser(ctx)
+	authPayload, err := server.authenticatedPayload(ctx)
 	if err != nil {
-		return nil, unauthenticatedError(err)
+		return nil, err
 	}
 
-	if violations := validateUpdateUserRequest(req); violations != nil {
-		return nil, invalidArgumentError(violations)
+	mask, violation := newUpdateMask(req, req.GetUpdateMask(), updateUserMaskPaths...)
+	if violation != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{violation})
 	}
 
-	if authPayload.Username != req.GetUsername() {
-		return nil, status.Errorf(codes.PermissionDenied, "cannot update other user's info")
+	arg := service.UpdateUserParams{
+		Username:        req.GetUsername(),
+		CurrentPassword: req.GetCurrentPassword(),
 	}
-
-	arg := db.UpdateUserParams{
-		Username: req.GetUsername(),
-		FullName: pgtype.Text{
-			String: req.GetFullName(),
-			Valid:  req.FullName != nil,
-		},
-		Email: pgtype.Text{
-			String: req.GetEmail(),
-			Valid:  req.Email != nil,
-		},
+	if mask.Has("full_name") {
+		arg.FullName = stringPointer(req.GetFullName())
 	}
-
-	if req.Password != nil {
-		hashedPassword, err := util.HashPassword(req.GetPassword())
-		if err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
-		}
-		arg.HashedPassword = pgtype.Text{
-			String: hashedPassword,
-			Valid:  true,
-		}
-		arg.PasswordChangedAt = pgtype.Timestamptz{
-			Time:  time.Now(),
-			Valid: true,
-		}
+	if mask.Has("email") {
+		arg.Email = stringPointer(req.GetEmail())
+	}
+	if mask.Has("password") {
+		arg.Password = stringPointer(req.GetPassword())
 	}
 
-	user, err := server.store.UpdateUser(ctx, arg)
+	user, err := server.userService.UpdateUser(ctx, authPayload.Username, arg)
 	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) {
-			return nil, status.Errorf(codes.NotFound, "user not found")
-		}
-		return nil, status.Errorf(codes.Internal, "failed to create user: %s", err)
+		return nil, err
 	}
 
 	res := &pb.UpdateUserResponse{
@@ -69,24 +47,6 @@ func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 	return res, nil
 }
 
-func validateUpdateUserRequest(req *pb.UpdateUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
-	if err := util.ValidateUsername(req.GetUsername()); err != nil {
-		violations = append(violations, fieldValidation("username", err))
-	}
-	if req.FullName != nil {
-		if err := util.ValidateFullName(req.GetFullName()); err != nil {
-			violations = append(violations, fieldValidation("full_name", err))
-		}
-	}
-	if req.Email != nil {
-		if err := util.ValidateEmail(req.GetEmail()); err != nil {
-			violations = append(violations, fieldValidation("email", err))
-		}
-	}
-	if req.Password != nil {
-		if err := util.ValidatePassword(req.GetPassword()); err != nil {
-			violations = append(violations, fieldValidation("password", err))
-		}
-	}
-	return violations
+func stringPointer(value string) *string {
+	return &value
 }
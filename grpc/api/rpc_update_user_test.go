@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -12,23 +13,28 @@ import (
 	"github.com/labasubagia/simplebank/grpc/pb"
 	"github.com/labasubagia/simplebank/util"
 	"github.com/labasubagia/simplebank/util/token"
+	"github.com/labasubagia/simplebank/worker"
+	mock_worker "github.com/labasubagia/simplebank/worker/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestUpdateUser(t *testing.T) {
-	user, _ := randomUser(t)
+	user, password := randomUser(t)
 
 	newName := util.RandomOwner()
 	newEmail := util.RandomEmail()
+	newPassword := util.RandomString(8)
+	wrongPassword := util.RandomString(9)
 	invalidEmail := "invalid_email"
 
 	testCases := []struct {
 		name          string
 		req           *pb.UpdateUserRequest
-		buildStubs    func(store *mock_db.MockStore)
+		buildStubs    func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor)
 		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
 		checkResponse func(t *testing.T, res *pb.UpdateUserResponse, err error)
 	}{
@@ -36,33 +42,23 @@ func TestUpdateUser(t *testing.T) {
 			name: "OK",
 			req: &pb.UpdateUserRequest{
 				Username: user.Username,
-				Email:    &newEmail,
 				FullName: &newName,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
-				arg := db.UpdateUserParams{
-					Username: user.Username,
-					Email: pgtype.Text{
-						String: newEmail,
-						Valid:  true,
-					},
-					FullName: pgtype.Text{
-						String: newName,
-						Valid:  true,
-					},
-				}
-				updatedUser := db.User{
-					Username:          arg.Username,
-					HashedPassword:    user.HashedPassword,
-					FullName:          newName,
-					Email:             newEmail,
-					PasswordChangedAt: user.PasswordChangedAt,
-					CreatedAt:         user.CreatedAt,
-					IsEmailVerified:   user.IsEmailVerified,
-				}
-				store.EXPECT().UpdateUser(gomock.Any(), gomock.Eq(arg)).
-					Times(1).
-					Return(updatedUser, nil)
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				updatedUser := user
+				updatedUser.FullName = newName
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, pgtype.Text{String: newName, Valid: true}, arg.FullName)
+						require.False(t, arg.PendingEmail.Valid)
+						require.False(t, arg.HashedPassword.Valid)
+						require.Nil(t, arg.AfterUpdate)
+						return db.UpdateUserTxResult{User: updatedUser}, nil
+					})
 			},
 			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
 				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
@@ -72,21 +68,240 @@ func TestUpdateUser(t *testing.T) {
 				require.NotNil(t, res)
 				updatedUser := res.GetUser()
 				require.Equal(t, user.Username, updatedUser.GetUsername())
-				require.Equal(t, newEmail, updatedUser.GetEmail())
+				require.Equal(t, user.Email, updatedUser.GetEmail())
 				require.Equal(t, newName, updatedUser.GetFullName())
 			},
 		},
+		{
+			name: "ChangeEmail",
+			req: &pb.UpdateUserRequest{
+				Username:        user.Username,
+				Email:           &newEmail,
+				CurrentPassword: &password,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, pgtype.Text{String: newEmail, Valid: true}, arg.PendingEmail)
+						require.False(t, arg.Email.Valid)
+
+						updatedUser := user
+						updatedUser.PendingEmail = arg.PendingEmail
+						err := arg.AfterUpdate(updatedUser)
+						return db.UpdateUserTxResult{User: updatedUser}, err
+					})
+				taskDistributor.EXPECT().
+					DistributeTaskVerifyEmail(gomock.Any(), gomock.Eq(&worker.PayloadSendVerifyEmail{
+						Username: user.Username,
+						Email:    newEmail,
+					}), gomock.Any()).
+					Times(1).
+					Return(nil)
+				taskDistributor.EXPECT().
+					DistributeTaskSendEmailChangeNotice(gomock.Any(), gomock.Eq(&worker.PayloadSendEmailChangeNotice{
+						Username: user.Username,
+						OldEmail: user.Email,
+						NewEmail: newEmail,
+					}), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateUserResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+				require.Equal(t, user.Email, res.GetUser().GetEmail())
+				require.Equal(t, newEmail, res.GetUser().GetPendingEmail())
+			},
+		},
+		{
+			name: "SameEmail",
+			req: &pb.UpdateUserRequest{
+				Username:        user.Username,
+				Email:           &user.Email,
+				CurrentPassword: &password,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.False(t, arg.PendingEmail.Valid)
+						require.Nil(t, arg.AfterUpdate)
+						return db.UpdateUserTxResult{User: user}, nil
+					})
+				taskDistributor.EXPECT().
+					DistributeTaskVerifyEmail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateUserResponse, err error) {
+				require.NoError(t, err)
+				require.Nil(t, res.GetUser().PendingEmail)
+			},
+		},
+		{
+			name: "ChangePassword",
+			req: &pb.UpdateUserRequest{
+				Username:        user.Username,
+				Password:        &newPassword,
+				CurrentPassword: &password,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.True(t, arg.HashedPassword.Valid)
+						require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword.String))
+						require.True(t, arg.PasswordChangedAt.Valid)
+						return db.UpdateUserTxResult{User: user}, nil
+					})
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateUserResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+			},
+		},
+		{
+			name: "UpdateMaskIgnoresUnmaskedFields",
+			req: &pb.UpdateUserRequest{
+				Username:   user.Username,
+				FullName:   &newName,
+				Email:      &newEmail,
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"full_name"}},
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, pgtype.Text{String: newName, Valid: true}, arg.FullName)
+						require.False(t, arg.PendingEmail.Valid)
+						require.Nil(t, arg.AfterUpdate)
+						return db.UpdateUserTxResult{User: user}, nil
+					})
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateUserResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+			},
+		},
+		{
+			name: "UpdateMaskUnsupportedPath",
+			req: &pb.UpdateUserRequest{
+				Username:   user.Username,
+				FullName:   &newName,
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"full_name", "username"}},
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "UpdateMaskCannotClearField",
+			req: &pb.UpdateUserRequest{
+				Username:   user.Username,
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"full_name"}},
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "MissingCurrentPassword",
+			req: &pb.UpdateUserRequest{
+				Username: user.Username,
+				Password: &newPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "WrongCurrentPassword",
+			req: &pb.UpdateUserRequest{
+				Username:        user.Username,
+				Email:           &newEmail,
+				CurrentPassword: &wrongPassword,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
 		{
 			name: "UserNotFound",
 			req: &pb.UpdateUserRequest{
 				Username: user.Username,
-				Email:    &newEmail,
 				FullName: &newName,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
-				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
 				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
@@ -102,13 +317,51 @@ func TestUpdateUser(t *testing.T) {
 			name: "InternalError",
 			req: &pb.UpdateUserRequest{
 				Username: user.Username,
-				Email:    &newEmail,
 				FullName: &newName,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
-				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdateUserTxResult{}, sql.ErrConnDone)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+		{
+			name: "DistributeTaskError",
+			req: &pb.UpdateUserRequest{
+				Username:        user.Username,
+				Email:           &newEmail,
+				CurrentPassword: &password,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.User{}, sql.ErrConnDone)
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						updatedUser := user
+						updatedUser.PendingEmail = arg.PendingEmail
+						return db.UpdateUserTxResult{}, arg.AfterUpdate(updatedUser)
+					})
+				taskDistributor.EXPECT().
+					DistributeTaskVerifyEmail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("enqueue failed"))
+				taskDistributor.EXPECT().
+					DistributeTaskSendEmailChangeNotice(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
 				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
@@ -123,12 +376,12 @@ func TestUpdateUser(t *testing.T) {
 		{
 			name: "InvalidEmail",
 			req: &pb.UpdateUserRequest{
-				Username: user.Username,
-				Email:    &invalidEmail,
-				FullName: &newName,
+				Username:        user.Username,
+				Email:           &invalidEmail,
+				CurrentPassword: &password,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
-				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
 				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
@@ -144,11 +397,11 @@ func TestUpdateUser(t *testing.T) {
 			name: "PermissionDenied",
 			req: &pb.UpdateUserRequest{
 				Username: user.Username,
-				Email:    &newEmail,
 				FullName: &newName,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
-				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
 				return newContextWithBearerToken(t, tokenMaker, invalidEmail, time.Minute)
@@ -164,11 +417,10 @@ func TestUpdateUser(t *testing.T) {
 			name: "ExpiredToken",
 			req: &pb.UpdateUserRequest{
 				Username: user.Username,
-				Email:    &newEmail,
 				FullName: &newName,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
-				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
 				return newContextWithBearerToken(t, tokenMaker, user.Username, -time.Minute)
@@ -184,11 +436,10 @@ func TestUpdateUser(t *testing.T) {
 			name: "NoAuthorization",
 			req: &pb.UpdateUserRequest{
 				Username: user.Username,
-				Email:    &newEmail,
 				FullName: &newName,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
-				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
 				return context.Background()
@@ -209,12 +460,16 @@ func TestUpdateUser(t *testing.T) {
 			defer storeCtrl.Finish()
 			store := mock_db.NewMockStore(storeCtrl)
 
-			tc.buildStubs(store)
+			taskCtrl := gomock.NewController(t)
+			defer taskCtrl.Finish()
+			taskDistributor := mock_worker.NewMockTaskDistributor(taskCtrl)
+
+			tc.buildStubs(store, taskDistributor)
 
-			server := newTestServer(t, store, nil)
+			server := newTestServer(t, store, taskDistributor)
 			ctx := tc.buildContext(t, server.tokenMaker)
 
-			res, err := server.UpdateUser(ctx, tc.req)
+			res, err := callRPC(ctx, server, "/pb.SimpleBank/UpdateUser", tc.req, server.UpdateUser)
 			tc.checkResponse(t, res, err)
 		})
 	}
@@ -3,39 +3,17 @@ package api
 import (
 	"context"
 
-	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/grpc/pb"
-	"github.com/labasubagia/simplebank/util"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (server *Server) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerifyEmailResponse, error) {
-	if violations := validateVerifyEmailRequest(req); violations != nil {
-		return nil, invalidArgumentError(violations)
-	}
-
-	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
-		EmailID:    req.GetEmailId(),
-		SecretCode: req.GetSecretCode(),
-	})
+	user, err := server.userService.VerifyEmail(ctx, req.GetEmailId(), req.GetSecretCode())
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to verify email")
+		return nil, err
 	}
 
 	res := &pb.VerifyEmailResponse{
-		IsVerified: result.User.IsEmailVerified,
+		IsVerified: user.IsEmailVerified,
 	}
 	return res, nil
 }
-
-func validateVerifyEmailRequest(req *pb.VerifyEmailRequest) (violations []*errdetails.BadRequest_FieldViolation) {
-	if err := util.ValidateEmailID(req.GetEmailId()); err != nil {
-		violations = append(violations, fieldValidation("email_id", err))
-	}
-	if err := util.ValidateSecretCode(req.GetSecretCode()); err != nil {
-		violations = append(violations, fieldValidation("secret_code", err))
-	}
-	return violations
-}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"context"
+
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/service"
+)
+
+func (server *Server) VerifyLoginTotp(ctx context.Context, req *pb.VerifyLoginTotpRequest) (*pb.LoginUserResponse, error) {
+	result, err := server.authService.VerifyLoginTotp(ctx, service.VerifyLoginTotpParams{
+		ChallengeID:  req.GetChallengeId(),
+		Code:         req.GetCode(),
+		RecoveryCode: req.GetRecoveryCode(),
+		Client:       server.loginClient(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return convertLoginResult(result), nil
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVerifyLoginTotp(t *testing.T) {
+	user, _ := randomUser(t)
+	userTotp := randomUserTotp(t, user.Username, true)
+	challenge := db.LoginChallenge{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiredAt: time.Now().Add(time.Minute),
+		CreatedAt: time.Now(),
+	}
+
+	validCode, err := totp.GenerateCode(userTotp.Secret, time.Now())
+	require.NoError(t, err)
+	invalidCode := "000000"
+	if invalidCode == validCode {
+		invalidCode = "111111"
+	}
+
+	recoveryCodes, err := util.GenerateRecoveryCodes(1)
+	require.NoError(t, err)
+	recoveryCode := recoveryCodes[0]
+
+	testCases := []struct {
+		name          string
+		req           *pb.VerifyLoginTotpRequest
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(t *testing.T, res *pb.LoginUserResponse, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.VerifyLoginTotpRequest{
+				ChallengeId: challenge.ID.String(),
+				Code:        validCode,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTotp, nil)
+				store.EXPECT().UseUserTotpStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseUserTotpStepParams) (db.UserTotp, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Positive(t, arg.LastUsedStep)
+						userTotp.LastUsedStep = arg.LastUsedStep
+						return userTotp, nil
+					})
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{Username: user.Username}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+				require.NotEmpty(t, res.GetAccessToken())
+				require.Equal(t, user.Username, res.GetUser().GetUsername())
+			},
+		},
+		{
+			name: "ReplayedCode",
+			req: &pb.VerifyLoginTotpRequest{
+				ChallengeId: challenge.ID.String(),
+				Code:        validCode,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTotp, nil)
+				store.EXPECT().UseUserTotpStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name: "OKRecoveryCode",
+			req: &pb.VerifyLoginTotpRequest{
+				ChallengeId:  challenge.ID.String(),
+				RecoveryCode: recoveryCode,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTotp, nil)
+				arg := db.UseRecoveryCodeParams{
+					Username:   user.Username,
+					HashedCode: util.HashRecoveryCode(recoveryCode),
+				}
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.RecoveryCode{Username: user.Username, IsUsed: true}, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{Username: user.Username}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res)
+				require.NotEmpty(t, res.GetAccessToken())
+			},
+		},
+		{
+			name: "InvalidCode",
+			req: &pb.VerifyLoginTotpRequest{
+				ChallengeId: challenge.ID.String(),
+				Code:        invalidCode,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTotp, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			req: &pb.VerifyLoginTotpRequest{
+				ChallengeId:  challenge.ID.String(),
+				RecoveryCode: recoveryCode,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTotp, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RecoveryCode{}, db.ErrRecordNotFound)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name: "ChallengeExpired",
+			req: &pb.VerifyLoginTotpRequest{
+				ChallengeId: challenge.ID.String(),
+				Code:        validCode,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).
+					Times(1).
+					Return(db.LoginChallenge{}, db.ErrRecordNotFound)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+		{
+			name: "ErrUseLoginChallenge",
+			req: &pb.VerifyLoginTotpRequest{
+				ChallengeId: challenge.ID.String(),
+				Code:        validCode,
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).
+					Times(1).
+					Return(db.LoginChallenge{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Internal, st.Code())
+			},
+		},
+		{
+			name: "InvalidInput",
+			req: &pb.VerifyLoginTotpRequest{
+				ChallengeId: "invalid_id",
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().UseLoginChallenge(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			res, err := server.VerifyLoginTotp(context.Background(), tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
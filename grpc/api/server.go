@@ -6,7 +6,10 @@ import (
 	"github.com/gin-gonic/gin"
 	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/ratelimit"
+	"github.com/labasubagia/simplebank/service"
 	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/certs"
 	"github.com/labasubagia/simplebank/util/token"
 	"github.com/labasubagia/simplebank/worker"
 )
@@ -17,19 +20,56 @@ type Server struct {
 	router          *gin.Engine
 	config          util.Config
 	tokenMaker      token.Maker
+	passwordHasher  util.PasswordHasher
 	taskDistributor worker.TaskDistributor
+	rateLimiter     ratelimit.Limiter
+	userService     *service.UserService
+	accountService  *service.AccountService
+	transferService *service.TransferService
+	authService     *service.AuthService
+	// clientIdentities maps client certificate subjects to service identities,
+	// including the server's own subject to gatewayIdentity.
+	clientIdentities map[string]string
 }
 
-func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) (*Server, error) {
+// NewServer creates the gRPC server. A nil rateLimiter disables rate limiting.
+func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor, rateLimiter ratelimit.Limiter) (*Server, error) {
 	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+	passwordHasher, err := util.NewPasswordHasher(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+	clientIdentities, err := certs.ParseIdentities(config.TLSClientIdentities)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse client identities: %w", err)
+	}
+	if config.IsTLSEnabled() {
+		// a proxying gateway dials with the server's own certificate
+		subject, err := certs.CertificateSubject(config.TLSCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read server certificate subject: %w", err)
+		}
+		if identity, ok := clientIdentities[subject]; ok && identity != gatewayIdentity {
+			return nil, fmt.Errorf("server certificate subject %q must map to %q, not %q", subject, gatewayIdentity, identity)
+		}
+		clientIdentities[subject] = gatewayIdentity
+	}
+	loginAttemptService := service.NewLoginAttemptService(config, store, taskDistributor)
 	server := &Server{
-		store:           store,
-		config:          config,
-		tokenMaker:      tokenMaker,
-		taskDistributor: taskDistributor,
+		store:            store,
+		config:           config,
+		tokenMaker:       tokenMaker,
+		passwordHasher:   passwordHasher,
+		taskDistributor:  taskDistributor,
+		rateLimiter:      rateLimiter,
+		clientIdentities: clientIdentities,
+		userService:      service.NewUserService(store, passwordHasher, taskDistributor),
+		accountService:   service.NewAccountService(config, store),
+		transferService:  service.NewTransferService(config, store, loginAttemptService),
+		authService:      service.NewAuthService(config, store, tokenMaker, passwordHasher, loginAttemptService),
 	}
 
 	return server, nil
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HttpTracing starts a server span for every request. It must run inside
+// HttpMetrics, whose route holder is used to rename the span after the
+// matched pattern once the request has been routed.
+func HttpTracing(server string, handler http.Handler) http.Handler {
+	named := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		handler.ServeHTTP(res, req)
+
+		if route, ok := req.Context().Value(httpRouteKey{}).(*httpRoute); ok && route.pattern != unmatchedRoute {
+			span := trace.SpanFromContext(req.Context())
+			span.SetName(req.Method + " " + route.pattern)
+			span.SetAttributes(semconv.HTTPRoute(route.pattern))
+		}
+	})
+	return otelhttp.NewHandler(named, server)
+}
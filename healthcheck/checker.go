@@ -0,0 +1,147 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// CheckFunc reports whether a dependency is usable.
+type CheckFunc func(ctx context.Context) error
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+func (report Report) IsOK() bool {
+	return report.Status == StatusOK
+}
+
+type Checker struct {
+	timeout time.Duration
+	names   []string
+	checks  map[string]CheckFunc
+}
+
+// NewChecker creates a checker that gives each dependency check at most timeout to finish.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]CheckFunc),
+	}
+}
+
+func (checker *Checker) Register(name string, check CheckFunc) {
+	if _, ok := checker.checks[name]; !ok {
+		checker.names = append(checker.names, name)
+	}
+	checker.checks[name] = check
+}
+
+// Check runs every registered check concurrently. The report is only ok when all of them pass.
+func (checker *Checker) Check(ctx context.Context) Report {
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(checker.names)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range checker.names {
+		name, check := name, checker.checks[name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := CheckResult{Status: StatusOK}
+			if err := checker.run(ctx, check); err != nil {
+				result = CheckResult{Status: StatusUnavailable, Error: err.Error()}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// run stops waiting once the timeout passes, even for checks that ignore ctx.
+func (checker *Checker) run(ctx context.Context, check CheckFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, checker.timeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- check(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out: %w", ctx.Err())
+	}
+}
+
+// Watch runs the checks every interval and passes each report to update,
+// until ctx is cancelled.
+func (checker *Checker) Watch(ctx context.Context, interval time.Duration, update func(Report)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		update(checker.Check(ctx))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LivenessHandler only reports that the process can serve requests, so a
+// dependency outage doesn't get the pod restarted.
+func (checker *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		writeReport(res, Report{Status: StatusOK})
+	})
+}
+
+// ReadinessHandler reports every dependency check, and fails with 503 when any of them does.
+func (checker *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		writeReport(res, checker.Check(req.Context()))
+	})
+}
+
+func writeReport(res http.ResponseWriter, report Report) {
+	statusCode := http.StatusOK
+	if !report.IsOK() {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(statusCode)
+	_ = json.NewEncoder(res).Encode(report)
+}
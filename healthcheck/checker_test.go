@@ -0,0 +1,143 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckerCheck(t *testing.T) {
+	checker := NewChecker(50 * time.Millisecond)
+	checker.Register("ok", func(ctx context.Context) error {
+		return nil
+	})
+
+	report := checker.Check(context.Background())
+	require.True(t, report.IsOK())
+	require.Equal(t, CheckResult{Status: StatusOK}, report.Checks["ok"])
+
+	checker.Register("failing", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	checker.Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	report = checker.Check(context.Background())
+	require.False(t, report.IsOK())
+	require.Len(t, report.Checks, 3)
+	require.Equal(t, StatusOK, report.Checks["ok"].Status)
+	require.Equal(t, CheckResult{Status: StatusUnavailable, Error: "connection refused"}, report.Checks["failing"])
+	require.Equal(t, StatusUnavailable, report.Checks["slow"].Status)
+	require.Contains(t, report.Checks["slow"].Error, "timed out")
+}
+
+func TestReadinessHandler(t *testing.T) {
+	healthy := true
+	checker := NewChecker(time.Second)
+	checker.Register("postgres", func(ctx context.Context) error {
+		if !healthy {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	testCases := []struct {
+		name          string
+		healthy       bool
+		handler       http.Handler
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "Ready",
+			healthy: true,
+			handler: checker.ReadinessHandler(),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				report := requireBodyReport(t, recorder)
+				require.Equal(t, StatusOK, report.Status)
+				require.Equal(t, StatusOK, report.Checks["postgres"].Status)
+			},
+		},
+		{
+			name:    "NotReady",
+			healthy: false,
+			handler: checker.ReadinessHandler(),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				report := requireBodyReport(t, recorder)
+				require.Equal(t, StatusUnavailable, report.Status)
+				require.Equal(t, "connection refused", report.Checks["postgres"].Error)
+			},
+		},
+		{
+			name:    "LiveWhileNotReady",
+			healthy: false,
+			handler: checker.LivenessHandler(),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				report := requireBodyReport(t, recorder)
+				require.Equal(t, StatusOK, report.Status)
+				require.Empty(t, report.Checks)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			healthy = tc.healthy
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			tc.handler.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+type fakeRow struct {
+	version int64
+	dirty   bool
+	err     error
+}
+
+func (row fakeRow) Scan(dest ...any) error {
+	if row.err != nil {
+		return row.err
+	}
+	*dest[0].(*int64) = row.version
+	*dest[1].(*bool) = row.dirty
+	return nil
+}
+
+type fakeQuerier struct {
+	row fakeRow
+}
+
+func (querier fakeQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return querier.row
+}
+
+func TestMigrationCheck(t *testing.T) {
+	ctx := context.Background()
+
+	require.NoError(t, MigrationCheck(fakeQuerier{fakeRow{version: 12}}, 12)(ctx))
+	require.ErrorContains(t, MigrationCheck(fakeQuerier{fakeRow{version: 12, dirty: true}}, 12)(ctx), "dirty")
+	require.ErrorContains(t, MigrationCheck(fakeQuerier{fakeRow{version: 11}}, 12)(ctx), "expected 12")
+	require.ErrorIs(t, MigrationCheck(fakeQuerier{fakeRow{err: pgx.ErrNoRows}}, 12)(ctx), pgx.ErrNoRows)
+}
+
+func requireBodyReport(t *testing.T, recorder *httptest.ResponseRecorder) Report {
+	var report Report
+	err := json.Unmarshal(recorder.Body.Bytes(), &report)
+	require.NoError(t, err)
+	return report
+}
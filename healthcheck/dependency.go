@@ -0,0 +1,52 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+)
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type RowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// PostgresCheck pings a connection from the pool.
+func PostgresCheck(pool Pinger) CheckFunc {
+	return func(ctx context.Context) error {
+		return pool.Ping(ctx)
+	}
+}
+
+// RedisCheck lists the asynq queues, which needs a working redis connection.
+func RedisCheck(inspector *asynq.Inspector) CheckFunc {
+	return func(ctx context.Context) error {
+		_, err := inspector.Queues()
+		return err
+	}
+}
+
+// MigrationCheck makes sure the schema is at the version this binary
+// migrated to, and that no migration was left dirty.
+func MigrationCheck(db RowQuerier, expectedVersion uint) CheckFunc {
+	return func(ctx context.Context) error {
+		var version int64
+		var dirty bool
+		err := db.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if err != nil {
+			return fmt.Errorf("cannot read migration version: %w", err)
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if version != int64(expectedVersion) {
+			return fmt.Errorf("schema version is %d, expected %d", version, expectedVersion)
+		}
+		return nil
+	}
+}
@@ -2,9 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -13,23 +20,55 @@ import (
 	"github.com/hibiken/asynq"
 	_ "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labasubagia/simplebank/db/memstore"
 	db "github.com/labasubagia/simplebank/db/sqlc"
 	_ "github.com/labasubagia/simplebank/doc/swagger/statik"
 	grpc_api "github.com/labasubagia/simplebank/grpc/api"
 	"github.com/labasubagia/simplebank/grpc/pb"
+	"github.com/labasubagia/simplebank/healthcheck"
 	"github.com/labasubagia/simplebank/mail"
+	"github.com/labasubagia/simplebank/metrics"
+	"github.com/labasubagia/simplebank/ratelimit"
 	"github.com/labasubagia/simplebank/restful_api"
+	"github.com/labasubagia/simplebank/tracing"
 	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/certs"
 	"github.com/labasubagia/simplebank/worker"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rakyll/statik/fs"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
+const (
+	healthCheckTimeout  = 2 * time.Second
+	healthCheckInterval = 10 * time.Second
+	certReloadInterval  = time.Minute
+)
+
+var interruptSignals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+	syscall.SIGINT,
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "gencert" {
+		runGenCert(os.Args[2:])
+		return
+	}
 
 	config, err := util.LoadConfig(".env")
 	if err != nil {
@@ -40,26 +79,127 @@ func main() {
 		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 	}
 
-	connPool, err := pgxpool.New(context.Background(), config.DBSource)
+	ctx, stop := signal.NotifyContext(context.Background(), interruptSignals...)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, config)
 	if err != nil {
-		log.Fatal().Msgf("cannot connect to db: %s", err)
+		log.Fatal().Err(err).Msg("cannot set up tracing")
 	}
 
-	runDBMigration(config.DBMigrationURL, config.DBSource)
-	store := db.NewStore(connPool)
+	store, connPool, migrationVersion := newStore(ctx, config)
 
 	redisOpt := asynq.RedisClientOpt{
 		Addr: config.RedisAddress,
 	}
-	taskDistributor := worker.NewRedisTaskDistributor(redisOpt)
+	taskDistributor, taskProcessor := newTaskWorker(config, redisOpt, store)
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: config.RedisAddress,
+	})
+	rateLimiter := newRateLimiter(config, redisClient)
+	if rateLimiter != nil && !config.IsGatewayProxy() {
+		// in-process gateway calls skip the interceptors, rate limiting included
+		log.Fatal().Msgf("rate limiting needs GATEWAY_MODE=%s, or RATE_LIMIT_BACKEND=%s to turn it off", util.GatewayModeProxy, ratelimit.BackendNone)
+	}
 
-	go runGinServer(config, store)
-	go runTaskProcessor(config, redisOpt, store)
-	go runGatewayServer(config, store, taskDistributor)
-	runGrpcServer(config, store, taskDistributor)
+	inspector := asynq.NewInspector(redisOpt)
+	checker := healthcheck.NewChecker(healthCheckTimeout)
+	if connPool != nil {
+		checker.Register("postgres", healthcheck.PostgresCheck(connPool))
+		checker.Register("migrations", healthcheck.MigrationCheck(connPool, migrationVersion))
+		prometheus.MustRegister(metrics.NewPoolCollector(connPool))
+	}
+
+	tlsConfig, certReloader := newServerTLSConfig(config)
+
+	if config.TaskQueueBackend != worker.BackendMemory {
+		checker.Register("redis", healthcheck.RedisCheck(inspector))
+		prometheus.MustRegister(metrics.NewQueueCollector(inspector))
+	}
+
+	waitGroup, ctx := errgroup.WithContext(ctx)
+
+	if certReloader != nil {
+		waitGroup.Go(func() error {
+			certReloader.Watch(ctx, certReloadInterval)
+			return nil
+		})
+	}
+
+	runGinServer(ctx, waitGroup, config, store, taskDistributor, rateLimiter)
+	runMetricsServer(ctx, waitGroup, config)
+	runTaskProcessor(ctx, waitGroup, taskProcessor)
+	grpcServer := runGrpcServer(ctx, waitGroup, config, store, taskDistributor, rateLimiter, tlsConfig, checker)
+
+	var gatewayConn *grpc.ClientConn
+	if config.IsGatewayProxy() {
+		gatewayConn = dialGrpcServer(config, certReloader)
+	}
+	runGatewayServer(ctx, waitGroup, config, store, taskDistributor, gatewayConn, grpcServer, tlsConfig, checker)
+
+	err = waitGroup.Wait()
+
+	if err := inspector.Close(); err != nil {
+		log.Error().Err(err).Msg("failed to close asynq inspector")
+	}
+	if err := redisClient.Close(); err != nil {
+		log.Error().Err(err).Msg("failed to close redis client")
+	}
+	if gatewayConn != nil {
+		if err := gatewayConn.Close(); err != nil {
+			log.Error().Err(err).Msg("failed to close gateway connection")
+		}
+	}
+
+	// every server has drained by now, so nothing uses the pool anymore
+	if connPool != nil {
+		connPool.Close()
+		log.Info().Msg("db connection pool closed")
+	}
+
+	// flush the spans of the requests that were drained above
+	tracingCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Error().Err(err).Msg("failed to shutdown tracing")
+	}
+	cancel()
+
+	if err != nil {
+		log.Fatal().Err(err).Msg("error from wait group")
+	}
 }
 
-func runDBMigration(migrationURL, dbSource string) {
+// newStore returns the store of the configured backend. For Postgres it
+// also returns the connection pool and the schema version it migrated to;
+// the memory backend has neither and loses its data on shutdown.
+func newStore(ctx context.Context, config util.Config) (db.Store, *pgxpool.Pool, uint) {
+	switch config.StoreBackend {
+	case "", db.BackendPostgres:
+		poolConfig, err := pgxpool.ParseConfig(config.DBSource)
+		if err != nil {
+			log.Fatal().Msgf("cannot parse db source: %s", err)
+		}
+		poolConfig.ConnConfig.Tracer = tracing.NewQueryTracer()
+
+		connPool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err != nil {
+			log.Fatal().Msgf("cannot connect to db: %s", err)
+		}
+
+		migrationVersion := runDBMigration(config.DBMigrationURL, config.DBSource)
+		return db.NewStore(connPool), connPool, migrationVersion
+	case db.BackendMemory:
+		log.Warn().Msg("using the in-memory store, data is lost on shutdown")
+		return memstore.NewStore(), nil, 0
+	default:
+		log.Fatal().Msgf("unsupported store backend %q", config.StoreBackend)
+		return nil, nil, 0
+	}
+}
+
+// runDBMigration migrates the db up and returns the resulting schema version.
+func runDBMigration(migrationURL, dbSource string) uint {
 	migration, err := migrate.New(migrationURL, dbSource)
 	if err != nil {
 		log.Fatal().Msgf("cannot create new migration instance: %s", err)
@@ -67,21 +207,141 @@ func runDBMigration(migrationURL, dbSource string) {
 	if err := migration.Up(); err != nil && err != migrate.ErrNoChange {
 		log.Fatal().Msgf("failed to run migrate up: %s", err)
 	}
-	log.Info().Msg("db migrated successfully")
+	version, _, err := migration.Version()
+	if err != nil {
+		log.Fatal().Msgf("cannot get migration version: %s", err)
+	}
+	log.Info().Uint("version", version).Msg("db migrated successfully")
+	return version
+}
+
+// newServerTLSConfig returns the TLS config shared by the gRPC server and
+// the gateway, and the reloader of its certificate, or nils when TLS is off.
+func newServerTLSConfig(config util.Config) (*tls.Config, *certs.Reloader) {
+	if !config.IsTLSEnabled() {
+		return nil, nil
+	}
+	reloader, err := certs.NewReloader(config.TLSCertFile, config.TLSKeyFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot load tls certificate")
+	}
+	tlsConfig, err := certs.ServerTLSConfig(reloader, config.TLSClientCAFile, config.TLSClientAuth)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create tls config")
+	}
+	return tlsConfig, reloader
+}
+
+// dialGrpcServer connects the proxying gateway to the gRPC server, over TLS
+// presenting the server's own certificate when TLS is on.
+func dialGrpcServer(config util.Config, certReloader *certs.Reloader) *grpc.ClientConn {
+	host, port, err := net.SplitHostPort(config.GRPCServerAddress)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid gRPC server address")
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+
+	transportCredentials := insecure.NewCredentials()
+	if certReloader != nil {
+		tlsConfig, err := certs.ClientTLSConfig(certReloader, config.TLSClientCAFile, host)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot create gateway tls config")
+		}
+		transportCredentials = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.Dial(
+		net.JoinHostPort(host, port),
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithChainUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(otelgrpc.StreamClientInterceptor()),
+	)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot dial gRPC server")
+	}
+	return conn
+}
+
+// runGenCert writes a development CA with a server and a client certificate.
+func runGenCert(args []string) {
+	flags := flag.NewFlagSet("gencert", flag.ExitOnError)
+	out := flags.String("out", "certs", "directory to write the PEM files to")
+	hosts := flags.String("hosts", "localhost,127.0.0.1", "comma separated names and ips of the server certificate")
+	client := flags.String("client", "simplebank-client", "common name of the client certificate")
+	validFor := flags.Duration("valid-for", 365*24*time.Hour, "validity of the certificates")
+	_ = flags.Parse(args)
+
+	certificates, err := certs.GenerateDev(strings.Split(*hosts, ","), *client, *validFor)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot generate certificates")
+	}
+	if err := certificates.WriteFiles(*out); err != nil {
+		log.Fatal().Err(err).Msg("cannot write certificates")
+	}
+	log.Info().Str("out", *out).Msg("development certificates written")
 }
 
-func runTaskProcessor(config util.Config, redisOpt asynq.RedisClientOpt, store db.Store) {
+// newRateLimiter returns the limiter for the configured backend, or nil when
+// rate limiting is disabled. The redis limiter falls back to process memory
+// while redis is unreachable.
+func newRateLimiter(config util.Config, redisClient *redis.Client) ratelimit.Limiter {
+	switch config.RateLimitBackend {
+	case ratelimit.BackendRedis:
+		return ratelimit.NewRedisLimiter(redisClient, ratelimit.NewMemoryLimiter())
+	case ratelimit.BackendMemory:
+		return ratelimit.NewMemoryLimiter()
+	case "", ratelimit.BackendNone:
+		return nil
+	default:
+		log.Fatal().Msgf("unsupported rate limit backend %q", config.RateLimitBackend)
+		return nil
+	}
+}
+
+// newTaskWorker returns the task distributor and processor of the configured
+// backend. The memory backend runs tasks in this process, so the tasks still
+// queued on shutdown are lost.
+func newTaskWorker(config util.Config, redisOpt asynq.RedisClientOpt, store db.Store) (worker.TaskDistributor, worker.TaskProcessor) {
 	mailer := mail.NewGmailSender(config.EmailSenderName, config.EmailSenderAddress, config.EmailSenderPassword)
-	taskProcessor := worker.NewRedisTaskProcessor(redisOpt, store, mailer)
+	switch config.TaskQueueBackend {
+	case "", worker.BackendRedis:
+		return worker.NewRedisTaskDistributor(redisOpt), worker.NewRedisTaskProcessor(redisOpt, store, mailer, config.PasswordResetURL, config.ShutdownTimeout)
+	case worker.BackendMemory:
+		queue := worker.NewInMemoryQueue()
+		return worker.NewInMemoryTaskDistributor(queue), worker.NewInMemoryTaskProcessor(queue, store, mailer, config.PasswordResetURL)
+	default:
+		log.Fatal().Msgf("unsupported task queue backend %q", config.TaskQueueBackend)
+		return nil, nil
+	}
+}
+
+func runTaskProcessor(ctx context.Context, waitGroup *errgroup.Group, taskProcessor worker.TaskProcessor) {
 	log.Info().Msg("start task processor")
 	err := taskProcessor.Start()
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot create server")
 	}
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msg("graceful shutdown task processor")
+
+		taskProcessor.Shutdown()
+		log.Info().Msg("task processor is stopped")
+		return nil
+	})
 }
 
-func runGatewayServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) {
-	server, err := grpc_api.NewServer(config, store, taskDistributor)
+// runGatewayServer serves the REST gateway. With gatewayConn it proxies every
+// request to the gRPC server over that connection, otherwise it calls the
+// handlers in process. grpcServer is also served on the gateway port when
+// the config asks for it.
+func runGatewayServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store, taskDistributor worker.TaskDistributor, gatewayConn *grpc.ClientConn, grpcServer *grpc.Server, tlsConfig *tls.Config, checker *healthcheck.Checker) {
+	// the in-process gateway bypasses the interceptors, so main only allows
+	// it with rate limiting turned off
+	server, err := grpc_api.NewServer(config, store, taskDistributor, nil)
 	if err != nil {
 		log.Fatal().Msgf("cannot create gateway server: %s", err)
 	}
@@ -95,47 +355,67 @@ func runGatewayServer(config util.Config, store db.Store, taskDistributor worker
 		},
 	})
 
-	grpcMux := runtime.NewServeMux(jsonOption)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	err = pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, server)
+	grpcMux := runtime.NewServeMux(
+		jsonOption,
+		runtime.WithMetadata(grpc_api.GatewayRouteAnnotator),
+		runtime.WithIncomingHeaderMatcher(grpc_api.GatewayIncomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(grpc_api.GatewayOutgoingHeaderMatcher),
+		runtime.WithErrorHandler(grpc_api.GatewayErrorHandler),
+	)
+	if gatewayConn != nil {
+		err = pb.RegisterSimpleBankHandler(ctx, grpcMux, gatewayConn)
+	} else {
+		err = pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, server)
+	}
 	if err != nil {
 		log.Fatal().Msgf("cannot register server handler: %s", err)
 	}
 
 	mux := http.NewServeMux()
 	mux.Handle("/", grpcMux)
+	mux.Handle("/healthz", grpc_api.HttpRoute("/healthz", checker.LivenessHandler()))
+	mux.Handle("/readyz", grpc_api.HttpRoute("/readyz", checker.ReadinessHandler()))
 
 	statikFS, err := fs.New()
 	if err != nil {
 		log.Fatal().Msg("cannot create statik fs")
 	}
 	swaggerHandler := http.StripPrefix("/swagger/", http.FileServer(statikFS))
-	mux.Handle("/swagger/", swaggerHandler)
+	mux.Handle("/swagger/", grpc_api.HttpRoute("/swagger/", swaggerHandler))
 
-	listener, err := net.Listen("tcp", config.HTTPGatewayServerAddress)
-	if err != nil {
-		log.Fatal().Msg("cannot create listener")
+	handler := grpc_api.HttpMetrics("gateway", grpc_api.HttpTracing("gateway", grpc_api.HttpLogger(server.HttpClientIdentity(mux))))
+	if config.GatewayServeGrpc {
+		handler = grpc_api.MultiplexHandler(grpcServer, handler)
+		if tlsConfig == nil {
+			handler = h2c.NewHandler(handler, &http2.Server{})
+		}
 	}
 
-	log.Info().Msgf("start HTTP Gateway server at %s", listener.Addr().String())
-	handler := grpc_api.HttpLogger(mux)
-	err = http.Serve(listener, handler)
-	if err != nil {
-		log.Fatal().Msg("cannot start HTTP Gateway server")
+	httpServer := &http.Server{
+		Addr:      config.HTTPGatewayServerAddress,
+		Handler:   handler,
+		TLSConfig: tlsConfig,
 	}
+	runHTTPServer(ctx, waitGroup, config, httpServer, "HTTP Gateway server")
 }
 
-func runGrpcServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) {
-
-	logger := grpc.UnaryInterceptor(grpc_api.GrpcLogger)
-	grpcServer := grpc.NewServer(logger)
-	server, err := grpc_api.NewServer(config, store, taskDistributor)
+func runGrpcServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store, taskDistributor worker.TaskDistributor, rateLimiter ratelimit.Limiter, tlsConfig *tls.Config, checker *healthcheck.Checker) *grpc.Server {
+	server, err := grpc_api.NewServer(config, store, taskDistributor, rateLimiter)
 	if err != nil {
 		log.Fatal().Msgf("cannot create server: %s", err)
 	}
+
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(server.UnaryInterceptors()...),
+		grpc.ChainStreamInterceptor(server.StreamInterceptors()...),
+	}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	grpcServer := grpc.NewServer(options...)
 	pb.RegisterSimpleBankServer(grpcServer, server)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
 	reflection.Register(grpcServer)
 
 	listener, err := net.Listen("tcp", config.GRPCServerAddress)
@@ -143,23 +423,122 @@ func runGrpcServer(config util.Config, store db.Store, taskDistributor worker.Ta
 		log.Fatal().Msg("cannot create listener")
 	}
 
-	log.Info().Msgf("start gRPC server at %s", listener.Addr().String())
-	err = grpcServer.Serve(listener)
-	if err != nil {
-		log.Fatal().Msg("cannot start grpc server")
-	}
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start gRPC server at %s", listener.Addr().String())
+		err := grpcServer.Serve(listener)
+		if err != nil {
+			if errors.Is(err, grpc.ErrServerStopped) {
+				return nil
+			}
+			log.Error().Err(err).Msg("gRPC server failed to serve")
+			return err
+		}
+		return nil
+	})
+
+	waitGroup.Go(func() error {
+		checker.Watch(ctx, healthCheckInterval, func(report healthcheck.Report) {
+			status := healthpb.HealthCheckResponse_SERVING
+			if !report.IsOK() {
+				status = healthpb.HealthCheckResponse_NOT_SERVING
+			}
+			healthServer.SetServingStatus("", status)
+			healthServer.SetServingStatus(pb.SimpleBank_ServiceDesc.ServiceName, status)
+		})
+		return nil
+	})
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msg("graceful shutdown gRPC server")
+
+		// report NOT_SERVING so clients stop routing new calls here
+		healthServer.Shutdown()
+
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		timer := time.NewTimer(config.ShutdownTimeout)
+		defer timer.Stop()
+		select {
+		case <-stopped:
+		case <-timer.C:
+			log.Warn().Msg("gRPC server shutdown timed out, closing remaining connections")
+			grpcServer.Stop()
+		}
+
+		log.Info().Msg("gRPC server is stopped")
+		return nil
+	})
+
+	return grpcServer
 }
 
-//nolint:golint,unused
-func runGinServer(config util.Config, store db.Store) {
-	server, err := restful_api.NewServer(config, store)
+func runGinServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store, taskDistributor worker.TaskDistributor, rateLimiter ratelimit.Limiter) {
+	server, err := restful_api.NewServer(config, store, taskDistributor, rateLimiter)
 	if err != nil {
 		log.Fatal().Msgf("cannot create server: %s", err)
 	}
 
-	log.Info().Msgf("start gin server at port %s", config.HTTPServerAddress)
-	err = server.Start(config.HTTPServerAddress)
-	if err != nil {
-		log.Fatal().Msgf("cannot start server: %s", err)
+	httpServer := &http.Server{
+		Addr:    config.HTTPServerAddress,
+		Handler: server.Handler(),
 	}
+	runHTTPServer(ctx, waitGroup, config, httpServer, "gin server")
+}
+
+// runMetricsServer serves /metrics on its own listener so that it is only
+// reachable by the scraper, not through the public gateway port.
+func runMetricsServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	httpServer := &http.Server{
+		Addr:    config.MetricsServerAddress,
+		Handler: mux,
+	}
+	runHTTPServer(ctx, waitGroup, config, httpServer, "metrics server")
+}
+
+// runHTTPServer serves httpServer, over TLS when it has a TLS config, until
+// ctx is cancelled, then gives in-flight requests up to the shutdown timeout
+// to complete.
+func runHTTPServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, httpServer *http.Server, name string) {
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start %s at %s", name, httpServer.Addr)
+		var err error
+		if httpServer.TLSConfig != nil {
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil {
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			log.Error().Err(err).Msgf("%s failed to serve", name)
+			return err
+		}
+		return nil
+	})
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msgf("graceful shutdown %s", name)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+
+		err := httpServer.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to shutdown %s", name)
+			return err
+		}
+
+		log.Info().Msgf("%s is stopped", name)
+		return nil
+	})
 }
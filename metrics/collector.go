@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+type PoolStater interface {
+	Stat() *pgxpool.Stat
+}
+
+// PoolCollector exports pgxpool statistics, read fresh on every scrape.
+type PoolCollector struct {
+	pool PoolStater
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+}
+
+func NewPoolCollector(pool PoolStater) *PoolCollector {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &PoolCollector{
+		pool:                 pool,
+		acquiredConns:        desc("acquired_connections", "Number of connections currently in use."),
+		idleConns:            desc("idle_connections", "Number of idle connections."),
+		constructingConns:    desc("constructing_connections", "Number of connections being established."),
+		totalConns:           desc("total_connections", "Total number of connections in the pool."),
+		maxConns:             desc("max_connections", "Maximum size of the pool."),
+		acquireCount:         desc("acquires_total", "Number of successful connection acquires."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		emptyAcquireCount:    desc("empty_acquires_total", "Number of acquires that had to wait for a connection."),
+		canceledAcquireCount: desc("canceled_acquires_total", "Number of acquires cancelled by their context."),
+	}
+}
+
+func (collector *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.acquiredConns
+	ch <- collector.idleConns
+	ch <- collector.constructingConns
+	ch <- collector.totalConns
+	ch <- collector.maxConns
+	ch <- collector.acquireCount
+	ch <- collector.acquireDuration
+	ch <- collector.emptyAcquireCount
+	ch <- collector.canceledAcquireCount
+}
+
+func (collector *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := collector.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(collector.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(collector.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(collector.constructingConns, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(collector.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(collector.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(collector.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(collector.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(collector.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(collector.canceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
+
+type QueueInspector interface {
+	Queues() ([]string, error)
+	GetQueueInfo(queue string) (*asynq.QueueInfo, error)
+}
+
+// QueueCollector exports the depth of every asynq queue by task state.
+type QueueCollector struct {
+	inspector QueueInspector
+	tasks     *prometheus.Desc
+}
+
+func NewQueueCollector(inspector QueueInspector) *QueueCollector {
+	return &QueueCollector{
+		inspector: inspector,
+		tasks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "worker", "queue_tasks"),
+			"Number of tasks in each queue by state.",
+			[]string{"queue", "state"},
+			nil,
+		),
+	}
+}
+
+func (collector *QueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.tasks
+}
+
+func (collector *QueueCollector) Collect(ch chan<- prometheus.Metric) {
+	queues, err := collector.inspector.Queues()
+	if err != nil {
+		log.Error().Err(err).Msg("cannot list queues for metrics")
+		return
+	}
+
+	for _, queue := range queues {
+		info, err := collector.inspector.GetQueueInfo(queue)
+		if err != nil {
+			log.Error().Err(err).Str("queue", queue).Msg("cannot get queue info for metrics")
+			continue
+		}
+
+		states := map[string]int{
+			"pending":   info.Pending,
+			"active":    info.Active,
+			"scheduled": info.Scheduled,
+			"retry":     info.Retry,
+			"archived":  info.Archived,
+		}
+		for state, count := range states {
+			ch <- prometheus.MustNewConstMetric(collector.tasks, prometheus.GaugeValue, float64(count), queue, state)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "simplebank"
+
+var (
+	grpcRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Number of gRPC requests by method and status code.",
+	}, []string{"method", "code"})
+
+	grpcRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of gRPC requests by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by server, method, route and status code.",
+	}, []string{"server", "method", "route", "code"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by server, method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"server", "method", "route", "code"})
+
+	tasksProcessedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "tasks_processed_total",
+		Help:      "Number of processed background tasks by type and outcome.",
+	}, []string{"type", "outcome"})
+
+	taskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "task_duration_seconds",
+		Help:      "Processing time of background tasks by type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type"})
+
+	transfersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Number of completed transfers by currency.",
+	}, []string{"currency"})
+
+	transferAmountTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_amount_total",
+		Help:      "Sum of completed transfer amounts by currency, in minor units.",
+	}, []string{"currency"})
+
+	failedLoginsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failed_logins_total",
+		Help:      "Number of rejected login attempts by reason.",
+	}, []string{"reason"})
+)
+
+const (
+	TaskOutcomeSuccess = "success"
+	TaskOutcomeFailure = "failure"
+
+	FailedLoginUserNotFound      = "user_not_found"
+	FailedLoginWrongPassword     = "wrong_password"
+	FailedLoginLockedOut         = "locked_out"
+	FailedLoginWrongSecondFactor = "wrong_second_factor"
+)
+
+// Handler serves every metric registered on the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func ObserveGrpcRequest(method string, code string, duration time.Duration) {
+	grpcRequestsTotal.WithLabelValues(method, code).Inc()
+	grpcRequestDuration.WithLabelValues(method, code).Observe(duration.Seconds())
+}
+
+// ObserveHTTPRequest records a request to server. route must be the matched
+// route pattern rather than the raw path, to keep label cardinality bounded.
+func ObserveHTTPRequest(server string, method string, route string, statusCode int, duration time.Duration) {
+	code := strconv.Itoa(statusCode)
+	httpRequestsTotal.WithLabelValues(server, method, route, code).Inc()
+	httpRequestDuration.WithLabelValues(server, method, route, code).Observe(duration.Seconds())
+}
+
+func ObserveTask(taskType string, err error, duration time.Duration) {
+	outcome := TaskOutcomeSuccess
+	if err != nil {
+		outcome = TaskOutcomeFailure
+	}
+	tasksProcessedTotal.WithLabelValues(taskType, outcome).Inc()
+	taskDuration.WithLabelValues(taskType).Observe(duration.Seconds())
+}
+
+func ObserveTransfer(currency string, amount int64) {
+	transfersTotal.WithLabelValues(currency).Inc()
+	transferAmountTotal.WithLabelValues(currency).Add(float64(amount))
+}
+
+func ObserveFailedLogin(reason string) {
+	failedLoginsTotal.WithLabelValues(reason).Inc()
+}
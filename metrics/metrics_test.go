@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveTransfer(t *testing.T) {
+	count := testutil.ToFloat64(transfersTotal.WithLabelValues("USD"))
+	amount := testutil.ToFloat64(transferAmountTotal.WithLabelValues("USD"))
+
+	ObserveTransfer("USD", 150)
+	ObserveTransfer("USD", 50)
+
+	require.Equal(t, count+2, testutil.ToFloat64(transfersTotal.WithLabelValues("USD")))
+	require.Equal(t, amount+200, testutil.ToFloat64(transferAmountTotal.WithLabelValues("USD")))
+}
+
+func TestObserveTask(t *testing.T) {
+	success := testutil.ToFloat64(tasksProcessedTotal.WithLabelValues("task:test", TaskOutcomeSuccess))
+	failure := testutil.ToFloat64(tasksProcessedTotal.WithLabelValues("task:test", TaskOutcomeFailure))
+
+	ObserveTask("task:test", nil, time.Millisecond)
+	ObserveTask("task:test", errors.New("smtp unavailable"), time.Millisecond)
+
+	require.Equal(t, success+1, testutil.ToFloat64(tasksProcessedTotal.WithLabelValues("task:test", TaskOutcomeSuccess)))
+	require.Equal(t, failure+1, testutil.ToFloat64(tasksProcessedTotal.WithLabelValues("task:test", TaskOutcomeFailure)))
+}
+
+type fakeInspector struct {
+	queues map[string]*asynq.QueueInfo
+	err    error
+}
+
+func (inspector fakeInspector) Queues() ([]string, error) {
+	if inspector.err != nil {
+		return nil, inspector.err
+	}
+	queues := make([]string, 0, len(inspector.queues))
+	for queue := range inspector.queues {
+		queues = append(queues, queue)
+	}
+	return queues, nil
+}
+
+func (inspector fakeInspector) GetQueueInfo(queue string) (*asynq.QueueInfo, error) {
+	return inspector.queues[queue], nil
+}
+
+func TestQueueCollector(t *testing.T) {
+	collector := NewQueueCollector(fakeInspector{
+		queues: map[string]*asynq.QueueInfo{
+			"critical": {Queue: "critical", Pending: 3, Active: 1, Retry: 2},
+		},
+	})
+
+	expected := `
+# HELP simplebank_worker_queue_tasks Number of tasks in each queue by state.
+# TYPE simplebank_worker_queue_tasks gauge
+simplebank_worker_queue_tasks{queue="critical",state="active"} 1
+simplebank_worker_queue_tasks{queue="critical",state="archived"} 0
+simplebank_worker_queue_tasks{queue="critical",state="pending"} 3
+simplebank_worker_queue_tasks{queue="critical",state="retry"} 2
+simplebank_worker_queue_tasks{queue="critical",state="scheduled"} 0
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected))
+	require.NoError(t, err)
+
+	unavailable := NewQueueCollector(fakeInspector{err: errors.New("connection refused")})
+	require.Equal(t, 0, testutil.CollectAndCount(unavailable))
+}
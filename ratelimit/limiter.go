@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+	BackendNone   = "none"
+)
+
+// Limit is a token bucket holding up to Burst tokens, refilled at Rate
+// tokens per second. Every request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute allows n requests a minute, all of which may arrive at once.
+func PerMinute(n int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: n}
+}
+
+// PerSecond allows n requests a second on average, with bursts of burst.
+func PerSecond(n float64, burst int) Limit {
+	return Limit{Rate: n, Burst: burst}
+}
+
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long to wait until the next token, when not allowed.
+	RetryAfter time.Duration
+}
+
+// RetryAfterSeconds rounds RetryAfter up for the Retry-After header.
+func (result Result) RetryAfterSeconds() int64 {
+	return int64(math.Ceil(result.RetryAfter.Seconds()))
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// take refills a bucket holding tokens since updatedAt and tries to take a
+// token from it, returning the tokens left.
+func take(tokens float64, updatedAt time.Time, now time.Time, limit Limit) (float64, Result) {
+	if elapsed := now.Sub(updatedAt); elapsed > 0 {
+		tokens = math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+	}
+	if tokens >= 1 {
+		return tokens - 1, Result{Allowed: true}
+	}
+	return tokens, Result{RetryAfter: retryAfter(tokens, limit)}
+}
+
+func retryAfter(tokens float64, limit Limit) time.Duration {
+	return time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+}
+
+// bucketTTL is how long a bucket takes to refill completely, after which it
+// is indistinguishable from a new one and can be dropped.
+func bucketTTL(limit Limit) time.Duration {
+	return time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	testLimiter(t, limiter, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	_, err := limiter.Allow(context.Background(), "idle", PerMinute(60))
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 1)
+
+	now = now.Add(2 * sweepInterval)
+	_, err = limiter.Allow(context.Background(), "active", PerMinute(60))
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 1)
+	require.Contains(t, limiter.buckets, "active")
+}
+
+func TestRedisLimiter(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	now := time.Now()
+	limiter := NewRedisLimiter(client, NewMemoryLimiter())
+	limiter.now = func() time.Time { return now }
+
+	testLimiter(t, limiter, func(d time.Duration) {
+		now = now.Add(d)
+		server.FastForward(d)
+	})
+	require.True(t, server.Exists(keyPrefix+"user:alice"))
+}
+
+func TestRedisLimiterFallback(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	server.Close()
+
+	now := time.Now()
+	fallback := NewMemoryLimiter()
+	fallback.now = func() time.Time { return now }
+	limiter := NewRedisLimiter(client, fallback)
+
+	testLimiter(t, limiter, func(d time.Duration) { now = now.Add(d) })
+}
+
+// testLimiter checks limiter against a burst of 2 refilled once a second;
+// advance moves the limiter clock forward.
+func testLimiter(t *testing.T, limiter Limiter, advance func(time.Duration)) {
+	ctx := context.Background()
+	limit := PerSecond(1, 2)
+
+	for i := 0; i < limit.Burst; i++ {
+		result, err := limiter.Allow(ctx, "user:alice", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := limiter.Allow(ctx, "user:alice", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.InDelta(t, time.Second, result.RetryAfter, float64(10*time.Millisecond))
+	require.EqualValues(t, 1, result.RetryAfterSeconds())
+
+	// other keys have their own bucket
+	result, err = limiter.Allow(ctx, "user:bob", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	advance(500 * time.Millisecond)
+	result, err = limiter.Allow(ctx, "user:alice", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.InDelta(t, 500*time.Millisecond, result.RetryAfter, float64(10*time.Millisecond))
+
+	advance(500 * time.Millisecond)
+	result, err = limiter.Allow(ctx, "user:alice", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+}
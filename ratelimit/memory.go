@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	expiresAt time.Time
+}
+
+// MemoryLimiter keeps buckets in process memory, so every replica enforces
+// its limits on its own.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		now:       time.Now,
+		lastSweep: time.Now(),
+	}
+}
+
+func (limiter *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	limiter.sweep(now)
+
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		limiter.buckets[key] = b
+	}
+
+	var result Result
+	b.tokens, result = take(b.tokens, b.updatedAt, now, limit)
+	b.updatedAt = now
+	b.expiresAt = now.Add(bucketTTL(limit))
+	return result, nil
+}
+
+// sweep drops the buckets that have refilled completely.
+func (limiter *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(limiter.lastSweep) < sweepInterval {
+		return
+	}
+	for key, b := range limiter.buckets {
+		if now.After(b.expiresAt) {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.lastSweep = now
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const keyPrefix = "ratelimit:"
+
+// takeScript is take run atomically inside redis, so every replica draws
+// from the same bucket.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated_at")
+local tokens = tonumber(bucket[1])
+local updated_at = tonumber(bucket[2])
+if tokens == nil or updated_at == nil then
+	tokens = burst
+	updated_at = now
+end
+
+if now > updated_at then
+	tokens = math.min(burst, tokens + (now - updated_at) / 1000 * rate)
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated_at", tostring(now))
+redis.call("PEXPIRE", KEYS[1], ttl)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisLimiter shares buckets between replicas through redis. When redis
+// cannot be reached it falls back to fallback, so an outage degrades to
+// per-replica limits instead of rejecting or admitting every request.
+type RedisLimiter struct {
+	client   redis.Scripter
+	fallback Limiter
+	now      func() time.Time
+}
+
+func NewRedisLimiter(client redis.Scripter, fallback Limiter) *RedisLimiter {
+	return &RedisLimiter{
+		client:   client,
+		fallback: fallback,
+		now:      time.Now,
+	}
+}
+
+func (limiter *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	ttl := bucketTTL(limit).Milliseconds() + 1
+	values, err := takeScript.Run(ctx, limiter.client, []string{keyPrefix + key},
+		limit.Rate, limit.Burst, limiter.now().UnixMilli(), ttl,
+	).Slice()
+	if err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("redis rate limiter unavailable, using fallback")
+		return limiter.fallback.Allow(ctx, key, limit)
+	}
+
+	allowed, _ := values[0].(int64)
+	if allowed == 1 {
+		return Result{Allowed: true}, nil
+	}
+	tokensText, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		tokens = 0
+	}
+	return Result{RetryAfter: retryAfter(tokens, limit)}, nil
+}
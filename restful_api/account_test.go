@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labasubagia/simplebank/apperror"
 	mock_db "github.com/labasubagia/simplebank/db/mock"
 	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/util"
@@ -63,7 +65,8 @@ func TestGetAccount(t *testing.T) {
 					Return(account, nil)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, apperror.CodePermissionDenied)
 			},
 		},
 		{
@@ -139,7 +142,7 @@ func TestGetAccount(t *testing.T) {
 			store := mock_db.NewMockStore(ctrl)
 			tc.buildStubs(store)
 
-			server := newTestServer(t, store)
+			server := newTestServer(t, store, nil)
 			recorder := httptest.NewRecorder()
 
 			url := fmt.Sprintf("/v1/accounts/%d", tc.accountID)
@@ -226,7 +229,8 @@ func TestCreateAccount(t *testing.T) {
 					Return(db.Account{}, db.ErrUniqueViolation)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireProblem(t, recorder, apperror.CodeAlreadyExists)
 			},
 		},
 		{
@@ -249,7 +253,8 @@ func TestCreateAccount(t *testing.T) {
 					Return(db.Account{}, db.ErrForeignKeyViolation)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireProblem(t, recorder, apperror.CodeNotFound)
 			},
 		},
 		{
@@ -297,7 +302,7 @@ func TestCreateAccount(t *testing.T) {
 			store := mock_db.NewMockStore(ctrl)
 			tc.buildStubs(store)
 
-			server := newTestServer(t, store)
+			server := newTestServer(t, store, nil)
 			recorder := httptest.NewRecorder()
 
 			data, err := json.Marshal(tc.body)
@@ -324,8 +329,10 @@ func TestListAccounts(t *testing.T) {
 	}
 
 	type Query struct {
-		PageID   int
-		PageSize int
+		PageToken string
+		PageSize  int
+		Currency  string
+		Status    string
 	}
 
 	testCases := []struct {
@@ -337,24 +344,64 @@ func TestListAccounts(t *testing.T) {
 	}{
 		{
 			name:  "OK",
-			query: &Query{PageID: 2, PageSize: 5},
+			query: &Query{PageSize: 5},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{Owner: user.Username, Limit: 6})).
+					Times(1).
+					Return(accounts[0:6], nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				rsp := requireBodyMatchAccounts(t, recorder.Body, accounts[0:5])
+				require.NotEmpty(t, rsp.NextPageToken)
+			},
+		},
+		{
+			name:  "Filtered",
+			query: &Query{PageSize: 5, Currency: util.USD, Status: util.AccountStatusActive},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
 			buildStubs: func(store *mock_db.MockStore) {
+				arg := db.ListAccountsParams{
+					Owner:    user.Username,
+					Currency: pgtype.Text{String: util.USD, Valid: true},
+					Status:   pgtype.Text{String: util.AccountStatusActive, Valid: true},
+					Limit:    6,
+				}
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{Owner: user.Username, Limit: 5, Offset: 5})).
+					ListAccounts(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
-					Return(accounts[5:10], nil)
+					Return(accounts[5:8], nil)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
-				requireBodyMatchAccounts(t, recorder.Body, accounts[5:10])
+				rsp := requireBodyMatchAccounts(t, recorder.Body, accounts[5:8])
+				require.Empty(t, rsp.NextPageToken)
+			},
+		},
+		{
+			name:  "InvalidPageToken",
+			query: &Query{PageToken: "invalid", PageSize: 5},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
 		{
 			name:  "NoAuthorization",
-			query: &Query{PageID: 2, PageSize: 5},
+			query: &Query{PageSize: 5},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 			},
 			buildStubs: func(store *mock_db.MockStore) {
@@ -368,7 +415,7 @@ func TestListAccounts(t *testing.T) {
 		},
 		{
 			name:  "InvalidQuery",
-			query: &Query{PageID: 0, PageSize: 10_000},
+			query: &Query{PageSize: 10_000},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
 			},
@@ -408,7 +455,7 @@ func TestListAccounts(t *testing.T) {
 			store := mock_db.NewMockStore(ctrl)
 			tc.buildStubs(store)
 
-			server := newTestServer(t, store)
+			server := newTestServer(t, store, nil)
 			recorder := httptest.NewRecorder()
 
 			url := "/v1/accounts"
@@ -417,8 +464,16 @@ func TestListAccounts(t *testing.T) {
 
 			if tc.query != nil {
 				query := request.URL.Query()
-				query.Add("page_id", strconv.Itoa(tc.query.PageID))
+				if tc.query.PageToken != "" {
+					query.Add("page_token", tc.query.PageToken)
+				}
 				query.Add("page_size", strconv.Itoa(tc.query.PageSize))
+				if tc.query.Currency != "" {
+					query.Add("currency", tc.query.Currency)
+				}
+				if tc.query.Status != "" {
+					query.Add("status", tc.query.Status)
+				}
 				request.URL.RawQuery = query.Encode()
 			}
 
@@ -430,14 +485,15 @@ func TestListAccounts(t *testing.T) {
 
 }
 
-func requireBodyMatchAccounts(t *testing.T, body *bytes.Buffer, accounts []db.Account) {
+func requireBodyMatchAccounts(t *testing.T, body *bytes.Buffer, accounts []db.Account) listAccountResponse {
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
 
-	var gotAccounts []db.Account
-	err = json.Unmarshal(data, &gotAccounts)
+	var rsp listAccountResponse
+	err = json.Unmarshal(data, &rsp)
 	require.NoError(t, err)
-	require.Equal(t, accounts, gotAccounts)
+	require.Equal(t, accounts, rsp.Accounts)
+	return rsp
 }
 
 func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Account) {
@@ -456,5 +512,6 @@ func randomAccount(owner string) db.Account {
 		Owner:    owner,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Status:   util.AccountStatusActive,
 	}
 }
@@ -0,0 +1,91 @@
+package restful_api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labasubagia/simplebank/apperror"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		url        string
+		body       gin.H
+		verified   bool
+		buildStubs func(store *mock_db.MockStore)
+		wantStatus int
+	}{
+		{
+			name:       "CreateAccountUnverified",
+			url:        "/v1/accounts",
+			body:       gin.H{"currency": util.USD},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "CreateTransferUnverified",
+			url:        "/v1/transfers",
+			body:       gin.H{"from_account_id": 1, "to_account_id": 2, "amount": 10, "currency": util.USD},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:     "CreateAccountVerified",
+			url:      "/v1/accounts",
+			body:     gin.H{"currency": util.USD},
+			verified: true,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock_db.NewMockStore(ctrl)
+
+			user := user
+			user.IsEmailVerified = tc.verified
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			if tc.buildStubs != nil {
+				tc.buildStubs(store)
+			}
+
+			config := newTestConfig()
+			config.RequireVerifiedEmail = true
+			server, err := NewServer(config, store, nil, nil)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			if tc.verified {
+				return
+			}
+
+			problem := requireProblem(t, recorder, apperror.CodeEmailNotVerified)
+			require.Len(t, problem.Preconditions, 1)
+			require.Equal(t, apperror.PreconditionEmailVerification, problem.Preconditions[0].Type)
+		})
+	}
+}
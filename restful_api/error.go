@@ -0,0 +1,77 @@
+package restful_api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/labasubagia/simplebank/apperror"
+)
+
+// writeError aborts the request with an RFC 7807 problem body. An
+// *apperror.Error decides its own status and Retry-After header; any other
+// error gets the generic code of status. Server errors are recorded on the
+// context for the logger and never shown to the client.
+func writeError(ctx *gin.Context, status int, err error) {
+	var appErr *apperror.Error
+	switch {
+	case errors.As(err, &appErr):
+		status = appErr.Code.HTTPStatus()
+	case status >= 500:
+		appErr = apperror.Internal(err)
+	default:
+		appErr = requestError(status, err)
+	}
+	if appErr.Code == apperror.CodeInternal {
+		_ = ctx.Error(err)
+	}
+	if appErr.RetryAfter > 0 {
+		ctx.Header("Retry-After", fmt.Sprint(int64(math.Ceil(appErr.RetryAfter.Seconds()))))
+	}
+
+	problem := appErr.Problem(ctx.Request.URL.Path)
+	problem.Status = status
+	ctx.Header("Content-Type", apperror.ProblemContentType)
+	ctx.AbortWithStatusJSON(status, problem)
+}
+
+// requestError turns binding errors into field violations instead of
+// exposing validator messages, which name the go request structs.
+func requestError(status int, err error) *apperror.Error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return apperror.New(apperror.CodeFromHTTPStatus(status), err.Error())
+	}
+
+	violations := make([]apperror.FieldViolation, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		description := fmt.Sprintf("failed on the %s rule", fieldErr.Tag())
+		if fieldErr.Param() != "" {
+			description = fmt.Sprintf("failed on the %s=%s rule", fieldErr.Tag(), fieldErr.Param())
+		}
+		violations = append(violations, apperror.FieldViolation{
+			Field:       fieldErr.Field(),
+			Description: description,
+		})
+	}
+	return apperror.InvalidArgument(violations)
+}
+
+// requestFieldName names validation errors after the json, form or uri
+// key of the field, the same names clients send.
+func requestFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -20,6 +20,9 @@ func Logger() gin.HandlerFunc {
 		logger := log.Info()
 		if c.Writer.Status() >= 500 {
 			logger = log.Error()
+			if err := c.Errors.Last(); err != nil {
+				logger.Err(err.Err)
+			}
 			if c.Request != nil && c.Request.Body != nil {
 				if body, err := io.ReadAll(c.Request.Body); err == nil {
 					logger.Bytes("body", body)
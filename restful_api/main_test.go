@@ -1,13 +1,17 @@
 package restful_api
 
 import (
+	"encoding/json"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/labasubagia/simplebank/apperror"
 	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/worker"
 	"github.com/stretchr/testify/require"
 )
 
@@ -16,13 +20,29 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
-func newTestServer(t *testing.T, store db.Store) *Server {
-	config := util.Config{
+func newTestConfig() util.Config {
+	return util.Config{
 		TokenSymmetricKey:    util.RandomString(32),
 		AccessTokenDuration:  time.Minute,
 		RefreshTokenDuration: time.Hour,
+		LoginMaxAttempts:     5,
+		LoginAttemptWindow:   time.Hour,
+		LoginLockoutDuration: time.Minute,
 	}
-	server, err := NewServer(config, store)
+}
+
+func newTestServer(t *testing.T, store db.Store, taskDistributor worker.TaskDistributor) *Server {
+	server, err := NewServer(newTestConfig(), store, taskDistributor, nil)
 	require.NoError(t, err)
 	return server
 }
+
+func requireProblem(t *testing.T, recorder *httptest.ResponseRecorder, code apperror.Code) apperror.Problem {
+	require.Equal(t, apperror.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+	var problem apperror.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	require.Equal(t, code, problem.Code)
+	require.Equal(t, recorder.Code, problem.Status)
+	return problem
+}
@@ -0,0 +1,23 @@
+package restful_api
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labasubagia/simplebank/metrics"
+)
+
+const unmatchedRoute = "unmatched"
+
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.ObserveHTTPRequest("gin", c.Request.Method, route, c.Writer.Status(), time.Since(startTime))
+	}
+}
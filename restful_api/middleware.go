@@ -7,46 +7,67 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/labasubagia/simplebank/service"
+	"github.com/labasubagia/simplebank/util"
 	"github.com/labasubagia/simplebank/util/token"
 )
 
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
+	authorizationTypeApiKey = "apikey"
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// routeScopes lists the api key scopes each route requires, keyed by method
+// and route pattern. Authenticated routes missing from it only accept access
+// tokens, like methods without scopes in the gRPC API.
+var routeScopes = map[string][]string{
+	"POST /v1/accounts":    {util.ScopeAccountsWrite},
+	"GET /v1/accounts/:id": {util.ScopeAccountsRead},
+	"GET /v1/accounts":     {util.ScopeAccountsRead},
+	"POST /v1/transfers":   {util.ScopeTransfersWrite},
+}
+
+func authMiddleware(tokenMaker token.Maker, authService *service.AuthService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
 			err := errors.New("authorization header is not provider")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, http.StatusUnauthorized, err)
 			return
 		}
 
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
 			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, http.StatusUnauthorized, err)
 			return
 		}
 
 		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
+		switch authorizationType {
+		case authorizationTypeBearer:
+			accessToken := fields[1]
+			payload, err := tokenMaker.VerifyToken(accessToken)
+			if err != nil {
+				writeError(ctx, http.StatusUnauthorized, err)
+				return
+			}
+			ctx.Set(authorizationPayloadKey, payload)
+		case authorizationTypeApiKey:
+			payload, err := authService.VerifyApiKey(ctx, fields[1], routeScopes[ctx.Request.Method+" "+ctx.FullPath()])
+			if err != nil {
+				writeError(ctx, http.StatusUnauthorized, err)
+				return
+			}
+			ctx.Set(authorizationPayloadKey, payload)
+		default:
 			err := fmt.Errorf("authorization type %s is not supported", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
-			return
-		}
-
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, http.StatusUnauthorized, err)
 			return
 		}
 
-		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next()
 	}
 }
@@ -1,6 +1,7 @@
 package restful_api
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,8 +9,13 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
 	"github.com/labasubagia/simplebank/util/token"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 )
 
 func addAuthorization(
@@ -85,11 +91,11 @@ func TestAuthMiddleware(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			server := newTestServer(t, nil)
+			server := newTestServer(t, nil, nil)
 			authPath := "/auth"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.tokenMaker, server.authService),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -105,3 +111,160 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func addApiKeyAuthorization(request *http.Request, key string) {
+	authorizationHeader := fmt.Sprintf("%s %s", authorizationTypeApiKey, key)
+	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+}
+
+func randomApiKey(t *testing.T, username string, scopes ...string) (db.ApiKey, string) {
+	key, prefix, err := util.GenerateApiKey()
+	require.NoError(t, err)
+
+	apiKey := db.ApiKey{
+		ID:        uuid.New(),
+		Username:  username,
+		Name:      util.RandomString(8),
+		Prefix:    prefix,
+		HashedKey: util.HashApiKey(key),
+		Scopes:    scopes,
+		ExpiredAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+	return apiKey, key
+}
+
+func TestAuthMiddlewareApiKey(t *testing.T) {
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		scopes        []string
+		buildApiKey   func(apiKey *db.ApiKey)
+		buildStubs    func(store *mock_db.MockStore, apiKey db.ApiKey)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			scopes: []string{util.ScopeAccountsRead},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingScope",
+			scopes: []string{util.ScopeTransfersWrite},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "RouteWithoutScopes",
+			method: http.MethodPatch,
+			path:   "/v1/users",
+			scopes: []string{util.ScopeAccountsRead, util.ScopeAccountsWrite, util.ScopeTransfersWrite},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "NotFound",
+			scopes: []string{util.ScopeAccountsRead},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, db.ErrRecordNotFound)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			scopes: []string{util.ScopeAccountsRead},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrConnDone)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), sql.ErrConnDone.Error())
+			},
+		},
+		{
+			name:   "Revoked",
+			scopes: []string{util.ScopeAccountsRead},
+			buildApiKey: func(apiKey *db.ApiKey) {
+				apiKey.IsRevoked = true
+			},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "Expired",
+			scopes: []string{util.ScopeAccountsRead},
+			buildApiKey: func(apiKey *db.ApiKey) {
+				apiKey.ExpiredAt = time.Now().Add(-time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mock_db.NewMockStore(ctrl)
+
+			apiKey, key := randomApiKey(t, "user", tc.scopes...)
+			if tc.buildApiKey != nil {
+				tc.buildApiKey(&apiKey)
+			}
+			tc.buildStubs(store, apiKey)
+
+			server := newTestServer(t, store, nil)
+			router := gin.New()
+			router.Use(authMiddleware(server.tokenMaker, server.authService))
+			ok := func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, gin.H{})
+			}
+			router.GET("/v1/accounts", ok)
+			router.PATCH("/v1/users", ok)
+
+			method, path := tc.method, tc.path
+			if method == "" {
+				method, path = http.MethodGet, "/v1/accounts"
+			}
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(method, path, nil)
+			require.NoError(t, err)
+
+			addApiKeyAuthorization(request, key)
+			router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
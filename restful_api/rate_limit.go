@@ -0,0 +1,66 @@
+package restful_api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labasubagia/simplebank/apperror"
+	"github.com/labasubagia/simplebank/ratelimit"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/rs/zerolog/log"
+)
+
+var errRateLimited = apperror.New(apperror.CodeRateLimited, "rate limit exceeded, try again later")
+
+// defaultRateLimit applies to every route missing from routeRateLimits.
+var defaultRateLimit = ratelimit.PerSecond(10, 20)
+
+// routeRateLimits are tighter limits for the routes that are cheap to abuse,
+// keyed by method and route pattern.
+var routeRateLimits = map[string]ratelimit.Limit{
+	"POST /v1/users":              ratelimit.PerMinute(5),
+	"POST /v1/users/login":        ratelimit.PerMinute(10),
+	"POST /v1/users/login/totp":   ratelimit.PerMinute(10),
+	"POST /v1/token/renew_access": ratelimit.PerMinute(30),
+	"GET /v1/verify_email":        ratelimit.PerMinute(10),
+	"POST /v1/transfers":          ratelimit.PerMinute(30),
+}
+
+// rateLimitMiddleware takes a token from the caller's bucket for the route.
+// Installed after authMiddleware it limits per user, otherwise per client ip.
+// Limiter failures let the request through.
+func (server *Server) rateLimitMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if server.rateLimiter == nil {
+			ctx.Next()
+			return
+		}
+
+		route := ctx.Request.Method + " " + ctx.FullPath()
+		limit, ok := routeRateLimits[route]
+		if !ok {
+			limit = defaultRateLimit
+		}
+
+		result, err := server.rateLimiter.Allow(ctx, route+":"+rateLimitCaller(ctx), limit)
+		if err != nil {
+			log.Error().Err(err).Str("route", route).Msg("cannot check rate limit")
+			ctx.Next()
+			return
+		}
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.FormatInt(result.RetryAfterSeconds(), 10))
+			writeError(ctx, http.StatusTooManyRequests, errRateLimited)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func rateLimitCaller(ctx *gin.Context) string {
+	if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+		return "user:" + payload.(*token.Payload).Username
+	}
+	return "ip:" + ctx.ClientIP()
+}
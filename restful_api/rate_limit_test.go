@@ -0,0 +1,87 @@
+package restful_api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labasubagia/simplebank/ratelimit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+	server.rateLimiter = ratelimit.NewMemoryLimiter()
+
+	// invalid bodies and ids are rejected before the store is used
+	login := func(remoteAddr string, forwardedFor ...string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/v1/users/login", nil)
+		request.RemoteAddr = remoteAddr
+		for _, value := range forwardedFor {
+			request.Header.Set("X-Forwarded-For", value)
+		}
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	getAccount := func(username string, remoteAddr string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/v1/accounts/0", nil)
+		request.RemoteAddr = remoteAddr
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// anonymous requests share a bucket per client ip
+	for i := 0; i < routeRateLimits["POST /v1/users/login"].Burst; i++ {
+		require.Equal(t, http.StatusBadRequest, login("10.0.0.1:40000").Code)
+	}
+	recorder := login("10.0.0.1:40001")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "6", recorder.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusBadRequest, login("10.0.0.2:40000").Code)
+	// X-Forwarded-For is ignored from untrusted clients
+	require.Equal(t, http.StatusTooManyRequests, login("10.0.0.1:40002", "203.0.113.7").Code)
+
+	// authenticated requests share a bucket per user, whatever their ip
+	user, _ := randomUser(t)
+	for i := 0; i < defaultRateLimit.Burst; i++ {
+		require.Equal(t, http.StatusBadRequest, getAccount(user.Username, "10.0.0.3:40000").Code)
+	}
+	recorder = getAccount(user.Username, "10.0.0.4:40000")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "1", recorder.Header().Get("Retry-After"))
+
+	other, _ := randomUser(t)
+	require.Equal(t, http.StatusBadRequest, getAccount(other.Username, "10.0.0.4:40000").Code)
+}
+
+func TestTrustedProxies(t *testing.T) {
+	config := newTestConfig()
+	config.TrustedProxies = "10.0.0.9, 10.1.0.0/16"
+	server, err := NewServer(config, nil, nil, nil)
+	require.NoError(t, err)
+
+	var clientIP string
+	server.router.GET("/client_ip", func(ctx *gin.Context) {
+		clientIP = ctx.ClientIP()
+	})
+	request := func(remoteAddr string) string {
+		request := httptest.NewRequest(http.MethodGet, "/client_ip", nil)
+		request.RemoteAddr = remoteAddr
+		request.Header.Set("X-Forwarded-For", "203.0.113.7")
+		server.router.ServeHTTP(httptest.NewRecorder(), request)
+		return clientIP
+	}
+
+	require.Equal(t, "203.0.113.7", request("10.0.0.9:40000"))
+	require.Equal(t, "203.0.113.7", request("10.1.2.3:40000"))
+	require.Equal(t, "10.0.0.8", request("10.0.0.8:40000"))
+
+	config.TrustedProxies = "not-an-ip"
+	_, err = NewServer(config, nil, nil, nil)
+	require.Error(t, err)
+}
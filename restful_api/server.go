@@ -8,35 +8,55 @@ import (
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/ratelimit"
+	"github.com/labasubagia/simplebank/service"
 	"github.com/labasubagia/simplebank/util"
 	"github.com/labasubagia/simplebank/util/token"
+	"github.com/labasubagia/simplebank/worker"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Server struct {
-	store      db.Store
-	router     *gin.Engine
-	config     util.Config
-	tokenMaker token.Maker
+	store           db.Store
+	router          *gin.Engine
+	config          util.Config
+	tokenMaker      token.Maker
+	rateLimiter     ratelimit.Limiter
+	userService     *service.UserService
+	accountService  *service.AccountService
+	transferService *service.TransferService
+	authService     *service.AuthService
 }
 
-func NewServer(config util.Config, store db.Store) (*Server, error) {
+// NewServer creates the gin server. A nil rateLimiter disables rate limiting.
+func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor, rateLimiter ratelimit.Limiter) (*Server, error) {
 	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+	passwordHasher, err := util.NewPasswordHasher(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+	loginAttemptService := service.NewLoginAttemptService(config, store, taskDistributor)
 	server := &Server{
-		store:      store,
-		config:     config,
-		tokenMaker: tokenMaker,
+		store:           store,
+		config:          config,
+		tokenMaker:      tokenMaker,
+		rateLimiter:     rateLimiter,
+		userService:     service.NewUserService(store, passwordHasher, taskDistributor),
+		accountService:  service.NewAccountService(config, store),
+		transferService: service.NewTransferService(config, store, loginAttemptService),
+		authService:     service.NewAuthService(config, store, tokenMaker, passwordHasher, loginAttemptService),
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		if err := v.RegisterValidation("currency", validCurrency); err != nil {
-			return nil, fmt.Errorf("failed to register currency validation: %w", err)
-		}
+		v.RegisterTagNameFunc(requestFieldName)
 	}
 
-	server.setupRouter()
+	if err := server.setupRouter(); err != nil {
+		return nil, err
+	}
 	return server, nil
 }
 
@@ -44,20 +64,35 @@ func (server *Server) Start(address string) error {
 	return server.router.Run(address)
 }
 
-func (server *Server) setupRouter() {
+// Handler returns the traced router so it can be served by a caller-managed http.Server.
+func (server *Server) Handler() http.Handler {
+	return otelhttp.NewHandler(server.router, "gin")
+}
+
+func (server *Server) setupRouter() error {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
-	router.Use(Logger(), gin.Recovery())
+	// ClientIP keys the login lockout and rate limits, so X-Forwarded-For is
+	// only believed from the configured proxies
+	if err := router.SetTrustedProxies(server.config.TrustedProxyList()); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	router.Use(Tracing(), Metrics(), Logger(), gin.Recovery())
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "Hello World"})
 	})
 
 	v1 := router.Group("/v1")
-	v1.POST("/users", server.createUser)
-	v1.POST("/users/login", server.loginUser)
-	v1.POST("/token/renew_access", server.renewAccessToken)
+	publicRoutes := v1.Group("/").Use(server.rateLimitMiddleware())
+	publicRoutes.POST("/users", server.createUser)
+	publicRoutes.POST("/users/login", server.loginUser)
+	publicRoutes.POST("/users/login/totp", server.verifyLoginTotp)
+	publicRoutes.POST("/token/renew_access", server.renewAccessToken)
+	publicRoutes.GET("/verify_email", server.verifyEmail)
+
+	authRoutes := v1.Group("/").Use(authMiddleware(server.tokenMaker, server.authService), server.rateLimitMiddleware())
+	authRoutes.PATCH("/users", server.updateUser)
 
-	authRoutes := v1.Group("/").Use(authMiddleware(server.tokenMaker))
 	authRoutes.POST("/accounts", server.createAccount)
 	authRoutes.GET("/accounts/:id", server.getAccount)
 	authRoutes.GET("/accounts", server.listAccount)
@@ -65,8 +100,5 @@ func (server *Server) setupRouter() {
 	authRoutes.POST("/transfers", server.createTransfer)
 
 	server.router = router
-}
-
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+	return nil
 }
@@ -25,7 +25,7 @@ func TestRefreshToken(t *testing.T) {
 
 	store := mock_db.NewMockStore(ctrl)
 
-	server := newTestServer(t, store)
+	server := newTestServer(t, store, nil)
 
 	user, _ := randomUser(t)
 	refreshToken, refreshTokenPayload, err := server.tokenMaker.CreateToken(user.Username, time.Minute)
@@ -0,0 +1,21 @@
+package restful_api
+
+import (
+	"github.com/gin-gonic/gin"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing renames the span Handler starts around the router after the
+// matched route, which is only known once gin has routed the request.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if route := c.FullPath(); route != "" {
+			span := trace.SpanFromContext(c.Request.Context())
+			span.SetName(c.Request.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+	}
+}
@@ -1,21 +1,21 @@
 package restful_api
 
 import (
-	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	db "github.com/labasubagia/simplebank/db/sqlc"
-	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/service"
+	"github.com/labasubagia/simplebank/util/token"
 )
 
 type createUserRequest struct {
-	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
-	FullName string `json:"full_name" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
 }
 
 type userResponse struct {
@@ -24,6 +24,7 @@ type userResponse struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
 }
 
 func newUserResponse(user db.User) userResponse {
@@ -33,43 +34,90 @@ func newUserResponse(user db.User) userResponse {
 		Email:             user.Email,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
+		IsEmailVerified:   user.IsEmailVerified,
 	}
 }
 
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	hashedPassword, err := util.HashPassword(req.Password)
+	user, err := server.userService.CreateUser(ctx, service.CreateUserParams{
+		Username: req.Username,
+		Password: req.Password,
+		FullName: req.FullName,
+		Email:    req.Email,
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		Email:          req.Email,
-		FullName:       req.FullName,
-		HashedPassword: hashedPassword,
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type updateUserRequest struct {
+	Username        string  `json:"username"`
+	FullName        *string `json:"full_name"`
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+func (server *Server) updateUser(ctx *gin.Context) {
+	var req updateUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
 	}
-	user, err := server.store.CreateUser(ctx, arg)
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.userService.UpdateUser(ctx, authPayload.Username, service.UpdateUserParams{
+		Username:        req.Username,
+		FullName:        req.FullName,
+		Email:           req.Email,
+		Password:        req.Password,
+		CurrentPassword: req.CurrentPassword,
+	})
 	if err != nil {
-		if db.ErrorCode(err) == db.UniqueViolation {
-			ctx.JSON(http.StatusForbidden, errorResponse(err))
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, newUserResponse(user))
 }
 
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"email_id"`
+	SecretCode string `form:"secret_code"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := server.userService.VerifyEmail(ctx, req.EmailID, req.SecretCode)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: user.IsEmailVerified})
+}
+
 type loginUserRequest struct {
-	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username string `json:"username"`
+	Password string `json:"password"`
 }
 
 type loginUserResponse struct {
@@ -81,61 +129,81 @@ type loginUserResponse struct {
 	User                  userResponse `json:"user"`
 }
 
-func (server *Server) loginUser(ctx *gin.Context) {
-	var req loginUserRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return
+type loginChallengeResponse struct {
+	TotpRequired       bool      `json:"totp_required"`
+	ChallengeID        uuid.UUID `json:"challenge_id"`
+	ChallengeExpiresAt time.Time `json:"challenge_expires_at"`
+}
+
+func loginClient(ctx *gin.Context) service.Client {
+	return service.Client{
+		UserAgent: ctx.Request.UserAgent(),
+		ClientIP:  ctx.ClientIP(),
 	}
-	user, err := server.store.GetUser(ctx, req.Username)
-	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+}
+
+func writeLoginResult(ctx *gin.Context, result service.LoginResult) {
+	if result.Challenge != nil {
+		ctx.JSON(http.StatusOK, loginChallengeResponse{
+			TotpRequired:       true,
+			ChallengeID:        result.Challenge.ID,
+			ChallengeExpiresAt: result.Challenge.ExpiredAt,
+		})
 		return
 	}
+	ctx.JSON(http.StatusOK, loginUserResponse{
+		SessionID:             result.Session.ID,
+		AccessToken:           result.AccessToken,
+		AccessTokenExpiresAt:  result.AccessPayload.ExpiredAt,
+		RefreshToken:          result.RefreshToken,
+		RefreshTokenExpiresAt: result.RefreshPayload.ExpiredAt,
+		User:                  newUserResponse(result.User),
+	})
+}
 
-	err = util.CheckPassword(req.Password, user.HashedPassword)
-	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+func (server *Server) loginUser(ctx *gin.Context) {
+	var req loginUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
+	result, err := server.authService.Login(ctx, service.LoginParams{
+		Username: req.Username,
+		Password: req.Password,
+		Client:   loginClient(ctx),
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
-	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.RefreshTokenDuration)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	writeLoginResult(ctx, result)
+}
+
+type verifyLoginTotpRequest struct {
+	ChallengeID  string `json:"challenge_id"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (server *Server) verifyLoginTotp(ctx *gin.Context) {
+	var req verifyLoginTotpRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshPayload.ID,
-		Username:     user.Username,
-		RefreshToken: refreshToken,
-		UserAgent:    ctx.Request.UserAgent(),
-		ClientIp:     ctx.ClientIP(),
-		IsBlocked:    false,
-		ExpiredAt:    refreshPayload.ExpiredAt,
+	result, err := server.authService.VerifyLoginTotp(ctx, service.VerifyLoginTotpParams{
+		ChallengeID:  req.ChallengeID,
+		Code:         req.Code,
+		RecoveryCode: req.RecoveryCode,
+		Client:       loginClient(ctx),
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
-	res := loginUserResponse{
-		SessionID:             session.ID,
-		AccessToken:           accessToken,
-		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
-		RefreshToken:          refreshToken,
-		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
-		User:                  newUserResponse(user),
-	}
-	ctx.JSON(http.StatusOK, res)
+	writeLoginResult(ctx, result)
 }
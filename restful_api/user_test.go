@@ -2,6 +2,7 @@ package restful_api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,22 +11,30 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labasubagia/simplebank/apperror"
 	mock_db "github.com/labasubagia/simplebank/db/mock"
 	db "github.com/labasubagia/simplebank/db/sqlc"
 	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/labasubagia/simplebank/worker"
+	mock_worker "github.com/labasubagia/simplebank/worker/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
-type eqCreateUserParamMatcher struct {
+type eqCreateUserTxParamMatcher struct {
 	arg      db.CreateUserParams
 	password string
+	user     db.User
 }
 
-func (e eqCreateUserParamMatcher) Matches(x any) bool {
-	arg, ok := x.(db.CreateUserParams)
+func (e eqCreateUserTxParamMatcher) Matches(x any) bool {
+	arg, ok := x.(db.CreateUserTxParams)
 	if !ok {
 		return false
 	}
@@ -36,15 +45,19 @@ func (e eqCreateUserParamMatcher) Matches(x any) bool {
 	}
 
 	e.arg.HashedPassword = arg.HashedPassword
-	return reflect.DeepEqual(e.arg, arg)
+	if !reflect.DeepEqual(e.arg, arg.CreateUserParams) {
+		return false
+	}
+
+	return arg.AfterCreate(e.user) == nil
 }
 
-func (e eqCreateUserParamMatcher) String() string {
+func (e eqCreateUserTxParamMatcher) String() string {
 	return fmt.Sprintf("matches arg %v and password %v", e.arg, e.password)
 }
 
-func EqCreateUserParams(arg db.CreateUserParams, password string) gomock.Matcher {
-	return eqCreateUserParamMatcher{arg: arg, password: password}
+func EqCreateUserTxParams(arg db.CreateUserParams, password string, user db.User) gomock.Matcher {
+	return eqCreateUserTxParamMatcher{arg: arg, password: password, user: user}
 }
 
 func TestCreateUser(t *testing.T) {
@@ -53,7 +66,7 @@ func TestCreateUser(t *testing.T) {
 	testCases := []struct {
 		name          string
 		body          gin.H
-		buildStubs    func(store *mock_db.MockStore)
+		buildStubs    func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor)
 		checkResponse func(recorder *httptest.ResponseRecorder)
 	}{
 		{
@@ -64,17 +77,24 @@ func TestCreateUser(t *testing.T) {
 				"password":  password,
 				"email":     user.Email,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
 				arg := db.CreateUserParams{
-					Username:       user.Username,
-					FullName:       user.FullName,
-					Email:          user.Email,
-					HashedPassword: user.HashedPassword,
+					Username: user.Username,
+					FullName: user.FullName,
+					Email:    user.Email,
 				}
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
+					CreateUserTx(gomock.Any(), EqCreateUserTxParams(arg, password, user)).
 					Times(1).
-					Return(user, nil)
+					Return(db.CreateUserTxResult{User: user}, nil)
+
+				taskPayload := &worker.PayloadSendVerifyEmail{
+					Username: user.Username,
+				}
+				taskDistributor.EXPECT().
+					DistributeTaskVerifyEmail(gomock.Any(), taskPayload, gomock.Any()).
+					Times(1).
+					Return(nil)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				res := userResponse{
@@ -96,20 +116,18 @@ func TestCreateUser(t *testing.T) {
 				"password":  password,
 				"email":     user.Email,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
-				arg := db.CreateUserParams{
-					Username:       user.Username,
-					FullName:       user.FullName,
-					Email:          user.Email,
-					HashedPassword: user.HashedPassword,
-				}
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.User{}, errors.New("unknown"))
+					Return(db.CreateUserTxResult{}, errors.New("unknown"))
+				taskDistributor.EXPECT().
+					DistributeTaskVerifyEmail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				requireProblem(t, recorder, apperror.CodeInternal)
 			},
 		},
 		{
@@ -120,14 +138,15 @@ func TestCreateUser(t *testing.T) {
 				"password":  password,
 				"email":     user.Email,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.User{}, db.ErrUniqueViolation)
+					Return(db.CreateUserTxResult{}, db.ErrUniqueViolation)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireProblem(t, recorder, apperror.CodeAlreadyExists)
 			},
 		},
 		{
@@ -138,13 +157,16 @@ func TestCreateUser(t *testing.T) {
 				"password":  password,
 				"email":     user.Email,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				problem := requireProblem(t, recorder, apperror.CodeInvalidArgument)
+				require.Len(t, problem.Errors, 1)
+				require.Equal(t, "username", problem.Errors[0].Field)
 			},
 		},
 		{
@@ -155,9 +177,26 @@ func TestCreateUser(t *testing.T) {
 				"password":  password,
 				"email":     "invalid-email",
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "TooShortPassword",
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": user.FullName,
+				"password":  "123",
+				"email":     user.Email,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
@@ -167,9 +206,9 @@ func TestCreateUser(t *testing.T) {
 		{
 			name: "InvalidBody",
 			body: gin.H{},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
@@ -181,13 +220,16 @@ func TestCreateUser(t *testing.T) {
 	for i := range testCases {
 		tc := testCases[i]
 		t.Run(tc.name, func(t *testing.T) {
-			ctrl := gomock.NewController(t)
-			defer ctrl.Finish()
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
 
-			store := mock_db.NewMockStore(ctrl)
-			tc.buildStubs(store)
+			taskCtrl := gomock.NewController(t)
+			defer taskCtrl.Finish()
+			taskDistributor := mock_worker.NewMockTaskDistributor(taskCtrl)
+			tc.buildStubs(store, taskDistributor)
 
-			server := newTestServer(t, store)
+			server := newTestServer(t, store, taskDistributor)
 			recorder := httptest.NewRecorder()
 
 			data, err := json.Marshal(tc.body)
@@ -209,7 +251,7 @@ func TestLoginUser(t *testing.T) {
 	testCases := []struct {
 		name          string
 		body          gin.H
-		buildStubs    func(store *mock_db.MockStore)
+		buildStubs    func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor)
 		checkResponse func(recorder *httptest.ResponseRecorder)
 	}{
 		{
@@ -218,11 +260,23 @@ func TestLoginUser(t *testing.T) {
 				"username": user.Username,
 				"password": password,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().
+					DeleteLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
 				store.EXPECT().
 					CreateSession(gomock.Any(), gomock.Any()).
 					Times(1)
@@ -231,20 +285,118 @@ func TestLoginUser(t *testing.T) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
+		{
+			name: "TotpRequired",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeleteLoginAttempt(gomock.Any(), gomock.Eq(util.LoginAttemptUserKey(user.Username))).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{Username: user.Username, IsEnabled: true}, nil)
+				store.EXPECT().
+					CreateLoginChallenge(gomock.Any(), gomock.Any()).
+					Times(1)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"totp_required":true`)
+			},
+		},
 		{
 			name: "WrongPassword",
 			body: gin.H{
 				"username": user.Username,
 				"password": "wrong_password",
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().
+					RecordFailedLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{FailedCount: 1}, nil)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "WrongPasswordLockout",
+			body: gin.H{
+				"username": user.Username,
+				"password": "wrong_password",
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{FailedCount: 4}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordFailedLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{FailedCount: 5}, nil)
+				store.EXPECT().
+					LockLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1)
+				taskDistributor.EXPECT().
+					DistributeTaskSendLockoutEmail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, payload *worker.PayloadSendLockoutEmail, _ ...asynq.Option) error {
+						require.Equal(t, user.Username, payload.Username)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "60", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "Locked",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{FailedCount: 5, LockedUntil: time.Now().Add(time.Minute)}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
 			},
 		},
 		{
@@ -253,11 +405,19 @@ func TestLoginUser(t *testing.T) {
 				"username": user.Username,
 				"password": password,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					RecordFailedLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{FailedCount: 1}, nil)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
@@ -269,7 +429,11 @@ func TestLoginUser(t *testing.T) {
 				"username": user.Username,
 				"password": password,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetLoginAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
@@ -285,7 +449,7 @@ func TestLoginUser(t *testing.T) {
 				"username": "123#",
 				"password": password,
 			},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
@@ -294,7 +458,7 @@ func TestLoginUser(t *testing.T) {
 		{
 			name: "InvalidBody",
 			body: gin.H{},
-			buildStubs: func(store *mock_db.MockStore) {
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
@@ -306,20 +470,324 @@ func TestLoginUser(t *testing.T) {
 		tc := testCases[i]
 		t.Run(tc.name, func(t *testing.T) {
 
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mock_db.NewMockStore(storeCtrl)
+
+			taskCtrl := gomock.NewController(t)
+			defer taskCtrl.Finish()
+			taskDistributor := mock_worker.NewMockTaskDistributor(taskCtrl)
+			tc.buildStubs(store, taskDistributor)
+
+			server := newTestServer(t, store, taskDistributor)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/v1/users/login"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestUpdateUserAPI(t *testing.T) {
+	user, password := randomUser(t)
+	newName := util.RandomOwner()
+	newEmail := util.RandomEmail()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": newName,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+
+				updated := user
+				updated.FullName = newName
+				arg := db.UpdateUserTxParams{
+					UpdateUserParams: db.UpdateUserParams{
+						Username: user.Username,
+						FullName: pgtype.Text{String: newName, Valid: true},
+					},
+				}
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.UpdateUserTxResult{User: updated}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, userResponse{
+					Username:          user.Username,
+					FullName:          newName,
+					Email:             user.Email,
+					PasswordChangedAt: user.PasswordChangedAt,
+					CreatedAt:         user.CreatedAt,
+				})
+			},
+		},
+		{
+			name: "ChangeEmail",
+			body: gin.H{
+				"username":         user.Username,
+				"email":            newEmail,
+				"current_password": password,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+
+				updated := user
+				updated.PendingEmail = pgtype.Text{String: newEmail, Valid: true}
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, newEmail, arg.PendingEmail.String)
+						require.NoError(t, arg.AfterUpdate(updated))
+						return db.UpdateUserTxResult{User: updated}, nil
+					})
+
+				taskDistributor.EXPECT().
+					DistributeTaskVerifyEmail(gomock.Any(), &worker.PayloadSendVerifyEmail{
+						Username: user.Username,
+						Email:    newEmail,
+					}, gomock.Any()).
+					Times(1).
+					Return(nil)
+				taskDistributor.EXPECT().
+					DistributeTaskSendEmailChangeNotice(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WrongCurrentPassword",
+			body: gin.H{
+				"username":         user.Username,
+				"email":            newEmail,
+				"current_password": "wrong-password",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, apperror.CodePermissionDenied)
+			},
+		},
+		{
+			name: "OtherUser",
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": newName,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "other_user", time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, apperror.CodePermissionDenied)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{
+				"username":         user.Username,
+				"email":            "invalid-email",
+				"current_password": password,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				problem := requireProblem(t, recorder, apperror.CodeInvalidArgument)
+				require.Len(t, problem.Errors, 1)
+				require.Equal(t, "email", problem.Errors[0].Field)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": newName,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mock_db.MockStore, taskDistributor *mock_worker.MockTaskDistributor) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
 			store := mock_db.NewMockStore(ctrl)
-			tc.buildStubs(store)
+			taskDistributor := mock_worker.NewMockTaskDistributor(ctrl)
+			tc.buildStubs(store, taskDistributor)
 
-			server := newTestServer(t, store)
+			server := newTestServer(t, store, taskDistributor)
 			recorder := httptest.NewRecorder()
 
 			data, err := json.Marshal(tc.body)
 			require.NoError(t, err)
 
-			url := "/v1/users/login"
-			request, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(data))
+			request, err := http.NewRequest(http.MethodPatch, "/v1/users", bytes.NewBuffer(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsEmailVerified = true
+	emailID := util.RandomInt(1, 1000)
+	secretCode := util.RandomString(32)
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mock_db.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: fmt.Sprintf("email_id=%d&secret_code=%s", emailID, secretCode),
+			buildStubs: func(store *mock_db.MockStore) {
+				arg := db.VerifyEmailTxParams{
+					EmailID:    emailID,
+					SecretCode: secretCode,
+				}
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.VerifyEmailTxResult{User: user}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res verifyEmailResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.True(t, res.IsVerified)
+			},
+		},
+		{
+			name:  "InvalidOrExpired",
+			query: fmt.Sprintf("email_id=%d&secret_code=%s", emailID, secretCode),
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireProblem(t, recorder, apperror.CodeNotFound)
+			},
+		},
+		{
+			name:  "InvalidSecretCode",
+			query: fmt.Sprintf("email_id=%d&secret_code=%s", emailID, "short"),
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				problem := requireProblem(t, recorder, apperror.CodeInvalidArgument)
+				require.Len(t, problem.Errors, 1)
+				require.Equal(t, "secret_code", problem.Errors[0].Field)
+			},
+		},
+		{
+			name:  "MissingQuery",
+			query: "",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mock_db.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/v1/verify_email?"+tc.query, nil)
 			require.NoError(t, err)
 
 			server.router.ServeHTTP(recorder, request)
@@ -1,13 +0,0 @@
-package restful_api
-
-import (
-	"github.com/go-playground/validator/v10"
-	"github.com/labasubagia/simplebank/util"
-)
-
-var validCurrency validator.Func = func(fl validator.FieldLevel) bool {
-	if currency, ok := fl.Field().Interface().(string); ok {
-		return util.IsSupportedCurrency(currency)
-	}
-	return false
-}
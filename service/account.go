@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/labasubagia/simplebank/apperror"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+)
+
+var (
+	errAccountNotFound = apperror.New(apperror.CodeNotFound, "account not found")
+	errNotAccountOwner = apperror.New(apperror.CodePermissionDenied, "this is not your account")
+)
+
+// AccountService holds the account use cases shared by the gRPC and REST APIs.
+type AccountService struct {
+	config         util.Config
+	store          db.Store
+	pageTokenMaker *util.PageTokenMaker
+}
+
+func NewAccountService(config util.Config, store db.Store) *AccountService {
+	return &AccountService{
+		config:         config,
+		store:          store,
+		pageTokenMaker: util.NewPageTokenMaker(config.TokenSymmetricKey),
+	}
+}
+
+// CreateAccount opens an empty account in currency for owner.
+func (service *AccountService) CreateAccount(ctx context.Context, owner string, currency string) (db.Account, error) {
+	var v violations
+	v.check("currency", util.ValidateCurrency(currency))
+	if err := v.err(); err != nil {
+		return db.Account{}, err
+	}
+
+	if err := requireVerifiedEmail(ctx, service.config, service.store, owner); err != nil {
+		return db.Account{}, err
+	}
+
+	account, err := service.store.CreateAccount(ctx, db.CreateAccountParams{
+		Owner:    owner,
+		Currency: currency,
+		Balance:  0,
+	})
+	if err != nil {
+		switch db.ErrorCode(err) {
+		case db.UniqueViolation:
+			return db.Account{}, apperror.Newf(apperror.CodeAlreadyExists, "account in %s already exists", currency)
+		case db.ForeignKeyViolation:
+			return db.Account{}, errUserNotFound
+		}
+		return db.Account{}, apperror.Internal(fmt.Errorf("failed to create account: %w", err))
+	}
+	return account, nil
+}
+
+// GetAccount returns the account if it belongs to caller.
+func (service *AccountService) GetAccount(ctx context.Context, caller string, accountID int64) (db.Account, error) {
+	var v violations
+	v.check("id", util.ValidateID(accountID))
+	if err := v.err(); err != nil {
+		return db.Account{}, err
+	}
+
+	return ownedAccount(ctx, service.store, caller, accountID)
+}
+
+// CloseAccount closes an active, empty account of caller.
+func (service *AccountService) CloseAccount(ctx context.Context, caller string, accountID int64) (db.Account, error) {
+	var v violations
+	v.check("id", util.ValidateID(accountID))
+	if err := v.err(); err != nil {
+		return db.Account{}, err
+	}
+
+	account, err := ownedAccount(ctx, service.store, caller, accountID)
+	if err != nil {
+		return db.Account{}, err
+	}
+
+	if account.Status != util.AccountStatusActive {
+		return db.Account{}, apperror.New(apperror.CodeFailedPrecondition, "account is already closed")
+	}
+	if account.Balance != 0 {
+		return db.Account{}, apperror.New(apperror.CodeFailedPrecondition, "account balance must be zero before closing")
+	}
+
+	// the query re-checks status and balance, so a transfer that lands in
+	// between surfaces as not found
+	account, err = service.store.CloseAccount(ctx, account.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.Account{}, apperror.New(apperror.CodeFailedPrecondition, "account changed while closing, please retry")
+		}
+		return db.Account{}, apperror.Internal(fmt.Errorf("failed to close account: %w", err))
+	}
+	return account, nil
+}
+
+// ListAccountsParams pages the accounts of the caller. Nil filters are not
+// applied.
+type ListAccountsParams struct {
+	PageSize  int32
+	PageToken string
+	Currency  *string
+	Status    *string
+}
+
+type ListAccountsResult struct {
+	Accounts      []db.Account
+	NextPageToken string
+}
+
+// ListAccounts returns a page of the accounts of caller.
+func (service *AccountService) ListAccounts(ctx context.Context, caller string, arg ListAccountsParams) (ListAccountsResult, error) {
+	var v violations
+	if arg.PageSize < 5 || arg.PageSize > 10 {
+		v.check("page_size", errors.New("must be between 5 and 10"))
+	}
+	if arg.Currency != nil {
+		v.check("currency", util.ValidateCurrency(*arg.Currency))
+	}
+	if arg.Status != nil {
+		v.check("status", util.ValidateAccountStatus(*arg.Status))
+	}
+	if err := v.err(); err != nil {
+		return ListAccountsResult{}, err
+	}
+
+	listArg := db.ListAccountsParams{
+		Owner:    caller,
+		Currency: toText(arg.Currency),
+		Status:   toText(arg.Status),
+		Limit:    arg.PageSize + 1,
+	}
+	scope := pageTokenScope("accounts", listArg.Owner, listArg.Currency, listArg.Status)
+	cursor, err := decodePageToken(service.pageTokenMaker, scope, arg.PageToken)
+	if err != nil {
+		return ListAccountsResult{}, err
+	}
+	listArg.CursorCreatedAt = cursor.CreatedAt
+	listArg.CursorID = cursor.ID
+
+	accounts, err := service.store.ListAccounts(ctx, listArg)
+	if err != nil {
+		return ListAccountsResult{}, apperror.Internal(fmt.Errorf("failed to list accounts: %w", err))
+	}
+
+	accounts, nextPageToken, err := nextPage(service.pageTokenMaker, scope, accounts, arg.PageSize, func(account db.Account) util.PageCursor {
+		return util.PageCursor{CreatedAt: account.CreatedAt, ID: account.ID}
+	})
+	if err != nil {
+		return ListAccountsResult{}, err
+	}
+	return ListAccountsResult{Accounts: accounts, NextPageToken: nextPageToken}, nil
+}
+
+type ListAccountEntriesResult struct {
+	Entries       []db.Entry
+	NextPageToken string
+}
+
+// ListAccountEntries returns a page of the entries of an account of caller.
+func (service *AccountService) ListAccountEntries(ctx context.Context, caller string, arg HistoryParams) (ListAccountEntriesResult, error) {
+	var v violations
+	arg.validate(&v)
+	if err := v.err(); err != nil {
+		return ListAccountEntriesResult{}, err
+	}
+
+	listArg := db.ListAccountEntriesParams{
+		AccountID: arg.AccountID,
+		StartTime: toTimestamptz(arg.StartTime),
+		EndTime:   toTimestamptz(arg.EndTime),
+		Direction: toText(arg.Direction),
+		MinAmount: toInt8(arg.MinAmount),
+		MaxAmount: toInt8(arg.MaxAmount),
+		Limit:     arg.PageSize + 1,
+	}
+	scope := pageTokenScope("entries", listArg.AccountID, listArg.StartTime, listArg.EndTime,
+		listArg.Direction, listArg.MinAmount, listArg.MaxAmount)
+	cursor, err := decodePageToken(service.pageTokenMaker, scope, arg.PageToken)
+	if err != nil {
+		return ListAccountEntriesResult{}, err
+	}
+	listArg.CursorCreatedAt = cursor.CreatedAt
+	listArg.CursorID = cursor.ID
+
+	if _, err := ownedAccount(ctx, service.store, caller, arg.AccountID); err != nil {
+		return ListAccountEntriesResult{}, err
+	}
+
+	entries, err := service.store.ListAccountEntries(ctx, listArg)
+	if err != nil {
+		return ListAccountEntriesResult{}, apperror.Internal(fmt.Errorf("failed to list entries: %w", err))
+	}
+
+	entries, nextPageToken, err := nextPage(service.pageTokenMaker, scope, entries, arg.PageSize, func(entry db.Entry) util.PageCursor {
+		return util.PageCursor{CreatedAt: entry.CreatedAt, ID: entry.ID}
+	})
+	if err != nil {
+		return ListAccountEntriesResult{}, err
+	}
+	return ListAccountEntriesResult{Entries: entries, NextPageToken: nextPageToken}, nil
+}
+
+// ownedAccount loads the account and makes sure it belongs to caller.
+func ownedAccount(ctx context.Context, store db.Store, caller string, accountID int64) (db.Account, error) {
+	account, err := store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.Account{}, errAccountNotFound
+		}
+		return db.Account{}, apperror.Internal(fmt.Errorf("failed to get account: %w", err))
+	}
+	if account.Owner != caller {
+		return db.Account{}, errNotAccountOwner
+	}
+	return account, nil
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labasubagia/simplebank/apperror"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomAccount(owner string) db.Account {
+	return db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    owner,
+		Balance:  util.RandomMoney(),
+		Currency: util.RandomCurrency(),
+		Status:   util.AccountStatusActive,
+	}
+}
+
+func TestCreateAccount(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	account.Balance = 0
+
+	testCases := []struct {
+		name       string
+		currency   string
+		config     util.Config
+		buildStubs func(store *mock_db.MockStore)
+		check      func(t *testing.T, account db.Account, err error)
+	}{
+		{
+			name:     "OK",
+			currency: account.Currency,
+			buildStubs: func(store *mock_db.MockStore) {
+				arg := db.CreateAccountParams{Owner: user.Username, Currency: account.Currency}
+				store.EXPECT().CreateAccount(gomock.Any(), arg).Times(1).Return(account, nil)
+			},
+			check: func(t *testing.T, created db.Account, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account, created)
+			},
+		},
+		{
+			name:     "InvalidCurrency",
+			currency: "CAD",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ db.Account, err error) {
+				appErr := requireCode(t, err, apperror.CodeInvalidArgument)
+				require.Equal(t, "currency", appErr.Violations[0].Field)
+			},
+		},
+		{
+			name:     "EmailNotVerified",
+			currency: account.Currency,
+			config:   util.Config{RequireVerifiedEmail: true},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, _ db.Account, err error) {
+				requireCode(t, err, apperror.CodeEmailNotVerified)
+			},
+		},
+		{
+			name:     "Duplicate",
+			currency: account.Currency,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, db.ErrUniqueViolation)
+			},
+			check: func(t *testing.T, _ db.Account, err error) {
+				requireCode(t, err, apperror.CodeAlreadyExists)
+			},
+		},
+		{
+			name:     "NoUser",
+			currency: account.Currency,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, db.ErrForeignKeyViolation)
+			},
+			check: func(t *testing.T, _ db.Account, err error) {
+				requireCode(t, err, apperror.CodeNotFound)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock_db.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			created, err := NewAccountService(tc.config, store).CreateAccount(context.Background(), user.Username, tc.currency)
+			tc.check(t, created, err)
+		})
+	}
+}
+
+func TestGetAccount(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+
+	testCases := []struct {
+		name       string
+		caller     string
+		buildStubs func(store *mock_db.MockStore)
+		code       apperror.Code
+	}{
+		{
+			name:   "OK",
+			caller: account.Owner,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+			},
+		},
+		{
+			name:   "OtherOwner",
+			caller: "other_user",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+			},
+			code: apperror.CodePermissionDenied,
+		},
+		{
+			name:   "NotFound",
+			caller: account.Owner,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			code: apperror.CodeNotFound,
+		},
+		{
+			name:   "InternalError",
+			caller: account.Owner,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(db.Account{}, errors.New("connection reset"))
+			},
+			code: apperror.CodeInternal,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock_db.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			got, err := NewAccountService(util.Config{}, store).GetAccount(context.Background(), tc.caller, account.ID)
+			if tc.code == "" {
+				require.NoError(t, err)
+				require.Equal(t, account, got)
+				return
+			}
+			requireCode(t, err, tc.code)
+		})
+	}
+}
+
+func TestCloseAccount(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	account.Balance = 0
+	closed := account
+	closed.Status = util.AccountStatusClosed
+
+	withBalance := account
+	withBalance.Balance = 10
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mock_db.MockStore)
+		code       apperror.Code
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().CloseAccount(gomock.Any(), account.ID).Times(1).Return(closed, nil)
+			},
+		},
+		{
+			name: "AlreadyClosed",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(closed, nil)
+				store.EXPECT().CloseAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodeFailedPrecondition,
+		},
+		{
+			name: "NonZeroBalance",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(withBalance, nil)
+				store.EXPECT().CloseAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodeFailedPrecondition,
+		},
+		{
+			name: "ChangedWhileClosing",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().CloseAccount(gomock.Any(), account.ID).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			code: apperror.CodeFailedPrecondition,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock_db.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			got, err := NewAccountService(util.Config{}, store).CloseAccount(context.Background(), account.Owner, account.ID)
+			if tc.code == "" {
+				require.NoError(t, err)
+				require.Equal(t, closed, got)
+				return
+			}
+			requireCode(t, err, tc.code)
+		})
+	}
+}
+
+func TestListAccountsPageToken(t *testing.T) {
+	owner := util.RandomOwner()
+	accounts := make([]db.Account, 6)
+	for i := range accounts {
+		accounts[i] = randomAccount(owner)
+		accounts[i].CreatedAt = time.Now().Add(-time.Duration(i) * time.Minute)
+	}
+
+	ctrl := gomock.NewController(t)
+	store := mock_db.NewMockStore(ctrl)
+	service := NewAccountService(util.Config{TokenSymmetricKey: util.RandomString(32)}, store)
+	ctx := context.Background()
+
+	store.EXPECT().ListAccounts(gomock.Any(), db.ListAccountsParams{Owner: owner, Limit: 6}).
+		Times(1).
+		Return(accounts, nil)
+	page, err := service.ListAccounts(ctx, owner, ListAccountsParams{PageSize: 5})
+	require.NoError(t, err)
+	require.Equal(t, accounts[:5], page.Accounts)
+	require.NotEmpty(t, page.NextPageToken)
+
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ListAccountsParams) ([]db.Account, error) {
+			require.True(t, arg.CursorCreatedAt.Time.Equal(accounts[4].CreatedAt))
+			require.Equal(t, pgtype.Int8{Int64: accounts[4].ID, Valid: true}, arg.CursorID)
+			return accounts[5:], nil
+		})
+	page, err = service.ListAccounts(ctx, owner, ListAccountsParams{PageSize: 5, PageToken: page.NextPageToken})
+	require.NoError(t, err)
+	require.Equal(t, accounts[5:], page.Accounts)
+	require.Empty(t, page.NextPageToken)
+
+	// the token only resumes the list of the same owner and filters
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(1).Return(accounts, nil)
+	page, err = service.ListAccounts(ctx, owner, ListAccountsParams{PageSize: 5})
+	require.NoError(t, err)
+
+	_, err = service.ListAccounts(ctx, util.RandomOwner(), ListAccountsParams{PageSize: 5, PageToken: page.NextPageToken})
+	requireCode(t, err, apperror.CodeInvalidArgument)
+
+	currency := util.USD
+	_, err = service.ListAccounts(ctx, owner, ListAccountsParams{PageSize: 5, PageToken: page.NextPageToken, Currency: &currency})
+	requireCode(t, err, apperror.CodeInvalidArgument)
+}
+
+func TestListAccountEntriesPageToken(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	otherAccount := randomAccount(account.Owner)
+	for otherAccount.ID == account.ID {
+		otherAccount.ID = util.RandomInt(1, 1000)
+	}
+	entries := make([]db.Entry, 3)
+	for i := range entries {
+		entries[i] = db.Entry{
+			ID:        util.RandomInt(1, 1000),
+			AccountID: account.ID,
+			Amount:    util.RandomMoney(),
+			CreatedAt: time.Now().Add(-time.Duration(i) * time.Minute),
+		}
+	}
+
+	ctrl := gomock.NewController(t)
+	store := mock_db.NewMockStore(ctrl)
+	service := NewAccountService(util.Config{TokenSymmetricKey: util.RandomString(32)}, store)
+	ctx := context.Background()
+
+	store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(2).Return(account, nil)
+	store.EXPECT().ListAccountEntries(gomock.Any(), db.ListAccountEntriesParams{AccountID: account.ID, Limit: 3}).
+		Times(1).
+		Return(entries, nil)
+	page, err := service.ListAccountEntries(ctx, account.Owner, HistoryParams{AccountID: account.ID, PageSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, entries[:2], page.Entries)
+	require.NotEmpty(t, page.NextPageToken)
+
+	store.EXPECT().ListAccountEntries(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ListAccountEntriesParams) ([]db.Entry, error) {
+			require.True(t, arg.CursorCreatedAt.Time.Equal(entries[1].CreatedAt))
+			require.Equal(t, pgtype.Int8{Int64: entries[1].ID, Valid: true}, arg.CursorID)
+			return entries[2:], nil
+		})
+	next, err := service.ListAccountEntries(ctx, account.Owner, HistoryParams{AccountID: account.ID, PageSize: 2, PageToken: page.NextPageToken})
+	require.NoError(t, err)
+	require.Equal(t, entries[2:], next.Entries)
+	require.Empty(t, next.NextPageToken)
+
+	// the token only resumes the list of the same account and filters
+	_, err = service.ListAccountEntries(ctx, account.Owner, HistoryParams{AccountID: otherAccount.ID, PageSize: 2, PageToken: page.NextPageToken})
+	requireCode(t, err, apperror.CodeInvalidArgument)
+
+	direction := util.DirectionIn
+	_, err = service.ListAccountEntries(ctx, account.Owner, HistoryParams{AccountID: account.ID, PageSize: 2, PageToken: page.NextPageToken, Direction: &direction})
+	requireCode(t, err, apperror.CodeInvalidArgument)
+}
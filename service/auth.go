@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labasubagia/simplebank/apperror"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/metrics"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/rs/zerolog/log"
+)
+
+var errTotpNotEnabled = apperror.New(apperror.CodeFailedPrecondition, "two-factor authentication is not enabled")
+
+// AuthService holds the login and api key checks shared by the gRPC and
+// REST APIs.
+type AuthService struct {
+	config         util.Config
+	store          db.Store
+	tokenMaker     token.Maker
+	passwordHasher util.PasswordHasher
+	loginAttempts  *LoginAttemptService
+}
+
+func NewAuthService(config util.Config, store db.Store, tokenMaker token.Maker, passwordHasher util.PasswordHasher, loginAttempts *LoginAttemptService) *AuthService {
+	return &AuthService{
+		config:         config,
+		store:          store,
+		tokenMaker:     tokenMaker,
+		passwordHasher: passwordHasher,
+		loginAttempts:  loginAttempts,
+	}
+}
+
+// Client describes where a login comes from. It is stored with the session
+// and ClientIP also keys the login lockout.
+type Client struct {
+	UserAgent string
+	ClientIP  string
+}
+
+type LoginParams struct {
+	Username string
+	Password string
+	Client
+}
+
+// LoginResult holds either a new session or, when the user has two-factor
+// authentication enabled, the Challenge to finish the login with.
+type LoginResult struct {
+	User           db.User
+	Challenge      *db.LoginChallenge
+	Session        db.Session
+	AccessToken    string
+	AccessPayload  *token.Payload
+	RefreshToken   string
+	RefreshPayload *token.Payload
+}
+
+// Login checks the password of a user, counting failures towards the login
+// lockout.
+func (service *AuthService) Login(ctx context.Context, arg LoginParams) (LoginResult, error) {
+	var v violations
+	v.check("username", util.ValidateUsername(arg.Username))
+	v.check("password", util.ValidatePassword(arg.Password))
+	if err := v.err(); err != nil {
+		return LoginResult{}, err
+	}
+
+	if err := service.loginAttempts.CheckLockout(ctx, arg.Username, arg.ClientIP); err != nil {
+		return LoginResult{}, err
+	}
+
+	user, err := service.store.GetUser(ctx, arg.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			metrics.ObserveFailedLogin(metrics.FailedLoginUserNotFound)
+			if err := service.loginAttempts.RecordFailure(ctx, arg.Username, arg.ClientIP, nil); err != nil {
+				return LoginResult{}, err
+			}
+			return LoginResult{}, errUserNotFound
+		}
+		return LoginResult{}, apperror.Internal(fmt.Errorf("failed to get user: %w", err))
+	}
+
+	if err := service.passwordHasher.Check(arg.Password, user.HashedPassword); err != nil {
+		metrics.ObserveFailedLogin(metrics.FailedLoginWrongPassword)
+		if err := service.loginAttempts.RecordFailure(ctx, arg.Username, arg.ClientIP, &user); err != nil {
+			return LoginResult{}, err
+		}
+		return LoginResult{}, apperror.New(apperror.CodePermissionDenied, "username or password invalid")
+	}
+
+	if err := service.loginAttempts.Reset(ctx, user.Username); err != nil {
+		return LoginResult{}, err
+	}
+
+	user = service.rehashPassword(ctx, user, arg.Password)
+
+	userTotp, err := service.store.GetUserTotp(ctx, user.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return LoginResult{}, apperror.Internal(fmt.Errorf("failed to get user totp: %w", err))
+	}
+	if err == nil && userTotp.IsEnabled {
+		return service.createLoginChallenge(ctx, user, arg.Client)
+	}
+
+	return service.createLoginSession(ctx, user, arg.Client)
+}
+
+// rehashPassword upgrades a stored hash made with an outdated algorithm or
+// parameters. Failing to do so must not block the login.
+func (service *AuthService) rehashPassword(ctx context.Context, user db.User, password string) db.User {
+	if !service.passwordHasher.NeedsRehash(user.HashedPassword) {
+		return user
+	}
+
+	hashedPassword, err := service.passwordHasher.Hash(password)
+	if err != nil {
+		log.Error().Err(err).Str("username", user.Username).Msg("failed to rehash password")
+		return user
+	}
+
+	updatedUser, err := service.store.UpdateUser(ctx, db.UpdateUserParams{
+		Username: user.Username,
+		HashedPassword: pgtype.Text{
+			String: hashedPassword,
+			Valid:  true,
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Str("username", user.Username).Msg("failed to save rehashed password")
+		return user
+	}
+	return updatedUser
+}
+
+func (service *AuthService) createLoginChallenge(ctx context.Context, user db.User, client Client) (LoginResult, error) {
+	challenge, err := service.store.CreateLoginChallenge(ctx, db.CreateLoginChallengeParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		UserAgent: client.UserAgent,
+		ClientIp:  client.ClientIP,
+		ExpiredAt: time.Now().Add(service.config.LoginChallengeDuration),
+	})
+	if err != nil {
+		return LoginResult{}, apperror.Internal(fmt.Errorf("failed to create login challenge: %w", err))
+	}
+	return LoginResult{User: user, Challenge: &challenge}, nil
+}
+
+func (service *AuthService) createLoginSession(ctx context.Context, user db.User, client Client) (LoginResult, error) {
+	accessToken, accessPayload, err := service.tokenMaker.CreateToken(user.Username, service.config.AccessTokenDuration)
+	if err != nil {
+		return LoginResult{}, apperror.Internal(fmt.Errorf("failed to create access token: %w", err))
+	}
+
+	refreshToken, refreshPayload, err := service.tokenMaker.CreateToken(user.Username, service.config.RefreshTokenDuration)
+	if err != nil {
+		return LoginResult{}, apperror.Internal(fmt.Errorf("failed to create refresh token: %w", err))
+	}
+
+	session, err := service.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    client.UserAgent,
+		ClientIp:     client.ClientIP,
+		IsBlocked:    false,
+		ExpiredAt:    refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		return LoginResult{}, apperror.Internal(fmt.Errorf("failed to create session: %w", err))
+	}
+
+	return LoginResult{
+		User:           user,
+		Session:        session,
+		AccessToken:    accessToken,
+		AccessPayload:  accessPayload,
+		RefreshToken:   refreshToken,
+		RefreshPayload: refreshPayload,
+	}, nil
+}
+
+// VerifyLoginTotpParams finishes a login with either a TOTP Code or a
+// RecoveryCode.
+type VerifyLoginTotpParams struct {
+	ChallengeID  string
+	Code         string
+	RecoveryCode string
+	Client
+}
+
+// VerifyLoginTotp creates the session of a login that needed a second
+// factor. A challenge can only be used once, a wrong code means logging in
+// again.
+func (service *AuthService) VerifyLoginTotp(ctx context.Context, arg VerifyLoginTotpParams) (LoginResult, error) {
+	var v violations
+	challengeID, err := uuid.Parse(arg.ChallengeID)
+	if err != nil {
+		v.check("challenge_id", errors.New("must be a valid uuid"))
+	}
+	switch {
+	case arg.Code != "":
+		v.check("code", util.ValidateTOTPCode(arg.Code))
+	case arg.RecoveryCode != "":
+		v.check("recovery_code", util.ValidateRecoveryCode(arg.RecoveryCode))
+	default:
+		v.check("code", errors.New("totp code or recovery code is required"))
+	}
+	if err := v.err(); err != nil {
+		return LoginResult{}, err
+	}
+
+	challenge, err := service.store.UseLoginChallenge(ctx, challengeID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return LoginResult{}, apperror.New(apperror.CodeUnauthenticated, "login challenge is invalid or expired")
+		}
+		return LoginResult{}, apperror.Internal(fmt.Errorf("failed to use login challenge: %w", err))
+	}
+
+	user, err := service.store.GetUser(ctx, challenge.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return LoginResult{}, errUserNotFound
+		}
+		return LoginResult{}, apperror.Internal(fmt.Errorf("failed to get user: %w", err))
+	}
+
+	userTotp, err := service.store.GetUserTotp(ctx, user.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return LoginResult{}, errTotpNotEnabled
+		}
+		return LoginResult{}, apperror.Internal(fmt.Errorf("failed to get user totp: %w", err))
+	}
+	if !userTotp.IsEnabled {
+		return LoginResult{}, errTotpNotEnabled
+	}
+
+	if arg.Code != "" {
+		step, ok := util.CheckTOTP(arg.Code, userTotp.Secret)
+		if !ok {
+			metrics.ObserveFailedLogin(metrics.FailedLoginWrongSecondFactor)
+			return LoginResult{}, apperror.New(apperror.CodePermissionDenied, "invalid totp code")
+		}
+		_, err = service.store.UseUserTotpStep(ctx, db.UseUserTotpStepParams{
+			Username:     user.Username,
+			LastUsedStep: step,
+		})
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				metrics.ObserveFailedLogin(metrics.FailedLoginWrongSecondFactor)
+				return LoginResult{}, apperror.New(apperror.CodePermissionDenied, "totp code has already been used")
+			}
+			return LoginResult{}, apperror.Internal(fmt.Errorf("failed to use totp code: %w", err))
+		}
+	} else {
+		_, err = service.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username:   user.Username,
+			HashedCode: util.HashRecoveryCode(arg.RecoveryCode),
+		})
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				metrics.ObserveFailedLogin(metrics.FailedLoginWrongSecondFactor)
+				return LoginResult{}, apperror.New(apperror.CodePermissionDenied, "invalid recovery code")
+			}
+			return LoginResult{}, apperror.Internal(fmt.Errorf("failed to use recovery code: %w", err))
+		}
+	}
+
+	return service.createLoginSession(ctx, user, arg.Client)
+}
+
+// VerifyApiKey authenticates an api key that holds every given scope.
+// Endpoints that require no scopes can only be used with an access token,
+// so the key is refused there.
+func (service *AuthService) VerifyApiKey(ctx context.Context, key string, scopes []string) (*token.Payload, error) {
+	if len(scopes) == 0 {
+		return nil, apperror.New(apperror.CodePermissionDenied, "api keys are not allowed for this endpoint")
+	}
+
+	errInvalidApiKey := apperror.New(apperror.CodeUnauthenticated, "invalid api key")
+
+	prefix, err := util.ParseApiKey(key)
+	if err != nil {
+		return nil, errInvalidApiKey
+	}
+
+	apiKey, err := service.store.GetApiKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, errInvalidApiKey
+		}
+		return nil, apperror.Internal(fmt.Errorf("failed to get api key: %w", err))
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.HashedKey), []byte(util.HashApiKey(key))) != 1 {
+		return nil, errInvalidApiKey
+	}
+	if apiKey.IsRevoked {
+		return nil, apperror.New(apperror.CodeUnauthenticated, "api key has been revoked")
+	}
+	if time.Now().After(apiKey.ExpiredAt) {
+		return nil, apperror.New(apperror.CodeUnauthenticated, "api key has expired")
+	}
+	if !util.HasScopes(apiKey.Scopes, scopes...) {
+		return nil, apperror.Newf(apperror.CodePermissionDenied, "api key is missing scope: %s", strings.Join(scopes, ", "))
+	}
+
+	if err := service.store.UpdateApiKeyLastUsed(ctx, apiKey.ID); err != nil {
+		log.Error().Err(err).Str("api_key_id", apiKey.ID.String()).Msg("failed to update api key last used")
+	}
+
+	return &token.Payload{
+		ID:        apiKey.ID,
+		Username:  apiKey.Username,
+		IssuedAt:  apiKey.CreatedAt,
+		ExpiredAt: apiKey.ExpiredAt,
+	}, nil
+}
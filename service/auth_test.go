@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labasubagia/simplebank/apperror"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/util/token"
+	"github.com/labasubagia/simplebank/worker"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestAuthService(t *testing.T, store db.Store, passwordHasher util.PasswordHasher) *AuthService {
+	config := util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+	}
+	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	require.NoError(t, err)
+	loginAttempts := NewLoginAttemptService(config, store, worker.NewInMemoryTaskDistributor(worker.NewInMemoryQueue()))
+	return NewAuthService(config, store, tokenMaker, passwordHasher, loginAttempts)
+}
+
+func TestLoginUsernameWithUnderscore(t *testing.T) {
+	user, password := randomUser(t)
+	user.Username = user.Username + "_" + util.RandomOwner()
+
+	ctrl := gomock.NewController(t)
+	store := mock_db.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), user.Username).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().GetUserTotp(gomock.Any(), user.Username).
+		Times(1).
+		Return(db.UserTotp{}, db.ErrRecordNotFound)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.Session{Username: user.Username}, nil)
+
+	passwordHasher, err := util.NewPasswordHasher(util.Config{})
+	require.NoError(t, err)
+	result, err := newTestAuthService(t, store, passwordHasher).Login(context.Background(), LoginParams{
+		Username: user.Username,
+		Password: password,
+	})
+	require.NoError(t, err)
+	require.Nil(t, result.Challenge)
+	require.Equal(t, user.Username, result.User.Username)
+}
+
+func TestLoginRehashPassword(t *testing.T) {
+	user, password := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	store := mock_db.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), user.Username).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpdateUserParams) (db.User, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.True(t, arg.HashedPassword.Valid)
+			require.True(t, strings.HasPrefix(arg.HashedPassword.String, "$argon2id$"))
+			require.NoError(t, util.CheckPassword(password, arg.HashedPassword.String))
+			require.False(t, arg.PasswordChangedAt.Valid)
+
+			updatedUser := user
+			updatedUser.HashedPassword = arg.HashedPassword.String
+			return updatedUser, nil
+		})
+	store.EXPECT().GetUserTotp(gomock.Any(), user.Username).
+		Times(1).
+		Return(db.UserTotp{}, db.ErrRecordNotFound)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.Session{Username: user.Username}, nil)
+
+	passwordHasher := util.NewArgon2idHasher(util.Argon2idParams{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	result, err := newTestAuthService(t, store, passwordHasher).Login(context.Background(), LoginParams{
+		Username: user.Username,
+		Password: password,
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(result.User.HashedPassword, "$argon2id$"))
+}
+
+func TestVerifyApiKey(t *testing.T) {
+	key, prefix, err := util.GenerateApiKey()
+	require.NoError(t, err)
+	apiKey := db.ApiKey{
+		ID:        uuid.New(),
+		Username:  util.RandomOwner(),
+		Prefix:    prefix,
+		HashedKey: util.HashApiKey(key),
+		Scopes:    []string{util.ScopeAccountsRead},
+		ExpiredAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+
+	testCases := []struct {
+		name       string
+		scopes     []string
+		buildStubs func(store *mock_db.MockStore)
+		code       apperror.Code
+	}{
+		{
+			name:   "OK",
+			scopes: []string{util.ScopeAccountsRead},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), prefix).Times(1).Return(apiKey, nil)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), apiKey.ID).Times(1).Return(nil)
+			},
+		},
+		{
+			name:   "MissingScope",
+			scopes: []string{util.ScopeTransfersWrite},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), prefix).Times(1).Return(apiKey, nil)
+				store.EXPECT().UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodePermissionDenied,
+		},
+		{
+			name: "NoScopes",
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodePermissionDenied,
+		},
+		{
+			name:   "NotFound",
+			scopes: []string{util.ScopeAccountsRead},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), prefix).Times(1).Return(db.ApiKey{}, db.ErrRecordNotFound)
+			},
+			code: apperror.CodeUnauthenticated,
+		},
+		{
+			name:   "InternalError",
+			scopes: []string{util.ScopeAccountsRead},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), prefix).Times(1).Return(db.ApiKey{}, errors.New("connection reset"))
+			},
+			code: apperror.CodeInternal,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock_db.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			payload, err := newTestAuthService(t, store, nil).VerifyApiKey(context.Background(), key, tc.scopes)
+			if tc.code == "" {
+				require.NoError(t, err)
+				require.Equal(t, apiKey.Username, payload.Username)
+				return
+			}
+			requireCode(t, err, tc.code)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/labasubagia/simplebank/apperror"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+)
+
+// requireVerifiedEmail enforces the REQUIRE_VERIFIED_EMAIL policy for
+// operations that move or hold money.
+func requireVerifiedEmail(ctx context.Context, config util.Config, store db.Store, username string) error {
+	if !config.RequireVerifiedEmail {
+		return nil
+	}
+
+	user, err := store.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return errUserNotFound
+		}
+		return apperror.Internal(fmt.Errorf("failed to get user: %w", err))
+	}
+	if !user.IsEmailVerified {
+		return apperror.EmailNotVerified(username)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/labasubagia/simplebank/apperror"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/metrics"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/worker"
+	"github.com/rs/zerolog/log"
+)
+
+// LoginAttemptService locks out a username or client IP after too many
+// failed logins, for both the gRPC and REST APIs. It does nothing when
+// LoginMaxAttempts is not positive.
+type LoginAttemptService struct {
+	config          util.Config
+	store           db.Store
+	taskDistributor worker.TaskDistributor
+}
+
+func NewLoginAttemptService(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) *LoginAttemptService {
+	return &LoginAttemptService{
+		config:          config,
+		store:           store,
+		taskDistributor: taskDistributor,
+	}
+}
+
+func loginAttemptKeys(username, clientIP string) []string {
+	keys := []string{util.LoginAttemptUserKey(username)}
+	if clientIP != "" {
+		keys = append(keys, util.LoginAttemptIPKey(clientIP))
+	}
+	return keys
+}
+
+func loginLockedError(retryDelay time.Duration) error {
+	return apperror.New(apperror.CodeLoginLocked, "too many failed login attempts, try again later").WithRetryAfter(retryDelay)
+}
+
+// CheckLockout fails with CodeLoginLocked while the username or the client
+// IP is locked.
+func (service *LoginAttemptService) CheckLockout(ctx context.Context, username, clientIP string) error {
+	if service.config.LoginMaxAttempts <= 0 {
+		return nil
+	}
+
+	for _, key := range loginAttemptKeys(username, clientIP) {
+		loginAttempt, err := service.store.GetLoginAttempt(ctx, key)
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				continue
+			}
+			return apperror.Internal(fmt.Errorf("failed to get login attempt: %w", err))
+		}
+		if retryDelay := time.Until(loginAttempt.LockedUntil); retryDelay > 0 {
+			metrics.ObserveFailedLogin(metrics.FailedLoginLockedOut)
+			return loginLockedError(retryDelay)
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login for the username and the client IP
+// and locks the ones that reached the limit. user is nil when the username
+// doesn't exist; otherwise the user is emailed when their username gets
+// locked. It fails with CodeLoginLocked when any key got locked so the
+// caller can report it instead of the login error.
+func (service *LoginAttemptService) RecordFailure(ctx context.Context, username, clientIP string, user *db.User) error {
+	if service.config.LoginMaxAttempts <= 0 {
+		return nil
+	}
+
+	var retryDelay time.Duration
+	for _, key := range loginAttemptKeys(username, clientIP) {
+		loginAttempt, err := service.store.RecordFailedLoginAttempt(ctx, db.RecordFailedLoginAttemptParams{
+			Key:         key,
+			ResetBefore: time.Now().Add(-service.config.LoginAttemptWindow),
+		})
+		if err != nil {
+			return apperror.Internal(fmt.Errorf("failed to record login attempt: %w", err))
+		}
+
+		lockout := util.LoginLockoutDuration(loginAttempt.FailedCount, service.config.LoginMaxAttempts, service.config.LoginLockoutDuration)
+		if lockout <= 0 {
+			continue
+		}
+
+		lockedUntil := time.Now().Add(lockout)
+		_, err = service.store.LockLoginAttempt(ctx, db.LockLoginAttemptParams{
+			Key:         key,
+			LockedUntil: lockedUntil,
+		})
+		if err != nil {
+			return apperror.Internal(fmt.Errorf("failed to lock login attempt: %w", err))
+		}
+		if lockout > retryDelay {
+			retryDelay = lockout
+		}
+
+		if user != nil && key == util.LoginAttemptUserKey(user.Username) {
+			service.sendLockoutEmail(ctx, user.Username, lockedUntil)
+		}
+	}
+
+	if retryDelay > 0 {
+		return loginLockedError(retryDelay)
+	}
+	return nil
+}
+
+// sendLockoutEmail only logs a failure, the lockout itself already happened.
+func (service *LoginAttemptService) sendLockoutEmail(ctx context.Context, username string, lockedUntil time.Time) {
+	taskPayload := &worker.PayloadSendLockoutEmail{
+		Username:    username,
+		LockedUntil: lockedUntil,
+	}
+	opts := []asynq.Option{
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical),
+	}
+	err := service.taskDistributor.DistributeTaskSendLockoutEmail(ctx, taskPayload, opts...)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("failed to distribute lockout email task")
+	}
+}
+
+// Reset clears the failed logins of username after a successful login. The
+// client IP keeps its count so one account can't be used to reset it.
+func (service *LoginAttemptService) Reset(ctx context.Context, username string) error {
+	if service.config.LoginMaxAttempts <= 0 {
+		return nil
+	}
+
+	if err := service.store.DeleteLoginAttempt(ctx, util.LoginAttemptUserKey(username)); err != nil {
+		return apperror.Internal(fmt.Errorf("failed to reset login attempts: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/labasubagia/simplebank/apperror"
+	"github.com/labasubagia/simplebank/db/memstore"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/worker"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginAttemptLockout(t *testing.T) {
+	store := memstore.NewStore()
+	queue := worker.NewInMemoryQueue()
+	config := util.Config{
+		LoginMaxAttempts:     3,
+		LoginAttemptWindow:   time.Hour,
+		LoginLockoutDuration: time.Minute,
+	}
+	service := NewLoginAttemptService(config, store, worker.NewInMemoryTaskDistributor(queue))
+
+	user, _ := randomUser(t)
+	clientIP := "203.0.113.7"
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, service.CheckLockout(ctx, user.Username, clientIP))
+		require.NoError(t, service.RecordFailure(ctx, user.Username, clientIP, &user))
+	}
+	require.Empty(t, queue.Tasks())
+
+	err := service.RecordFailure(ctx, user.Username, clientIP, &user)
+	appErr := requireCode(t, err, apperror.CodeLoginLocked)
+	require.Equal(t, time.Minute, appErr.RetryAfter)
+
+	// only the username lockout is emailed, to the user it belongs to
+	tasks := queue.Tasks()
+	require.Len(t, tasks, 1)
+	require.Equal(t, worker.TaskSendLockoutEmail, tasks[0].Type)
+	var payload worker.PayloadSendLockoutEmail
+	require.NoError(t, json.Unmarshal(tasks[0].Payload, &payload))
+	require.Equal(t, user.Username, payload.Username)
+	require.WithinDuration(t, time.Now().Add(time.Minute), payload.LockedUntil, time.Second)
+
+	requireCode(t, service.CheckLockout(ctx, user.Username, ""), apperror.CodeLoginLocked)
+	requireCode(t, service.CheckLockout(ctx, util.RandomOwner(), clientIP), apperror.CodeLoginLocked)
+
+	// a successful login unlocks the username but not the client IP
+	require.NoError(t, service.Reset(ctx, user.Username))
+	require.NoError(t, service.CheckLockout(ctx, user.Username, ""))
+	requireCode(t, service.CheckLockout(ctx, user.Username, clientIP), apperror.CodeLoginLocked)
+}
+
+func TestLoginAttemptUnknownUser(t *testing.T) {
+	store := memstore.NewStore()
+	queue := worker.NewInMemoryQueue()
+	config := util.Config{
+		LoginMaxAttempts:     1,
+		LoginAttemptWindow:   time.Hour,
+		LoginLockoutDuration: time.Minute,
+	}
+	service := NewLoginAttemptService(config, store, worker.NewInMemoryTaskDistributor(queue))
+
+	username := util.RandomOwner()
+	err := service.RecordFailure(context.Background(), username, "", nil)
+	requireCode(t, err, apperror.CodeLoginLocked)
+	require.Empty(t, queue.Tasks())
+}
+
+func TestLoginAttemptDisabled(t *testing.T) {
+	service := NewLoginAttemptService(util.Config{}, nil, nil)
+
+	username := util.RandomOwner()
+	require.NoError(t, service.CheckLockout(context.Background(), username, ""))
+	require.NoError(t, service.RecordFailure(context.Background(), username, "", nil))
+	require.NoError(t, service.Reset(context.Background(), username))
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labasubagia/simplebank/apperror"
+	"github.com/labasubagia/simplebank/util"
+)
+
+const (
+	minHistoryPageSize = 1
+	maxHistoryPageSize = 100
+)
+
+// pageCursor is the keyset position a list query resumes from.
+// Both fields are invalid for the first page.
+type pageCursor struct {
+	CreatedAt pgtype.Timestamptz
+	ID        pgtype.Int8
+}
+
+// pageTokenScope ties a page token to the list, its parent and the filters
+// it was issued for, so a cursor can't be resumed under different filters.
+// The filters are hashed to keep the token short.
+func pageTokenScope(list string, id any, filters ...any) string {
+	hash := sha256.New()
+	for _, filter := range filters {
+		fmt.Fprintf(hash, "%v\x00", filter)
+	}
+	return fmt.Sprintf("%s:%v:%x", list, id, hash.Sum(nil))
+}
+
+func decodePageToken(maker *util.PageTokenMaker, scope string, token string) (pageCursor, error) {
+	if token == "" {
+		return pageCursor{}, nil
+	}
+	cursor, err := maker.Decode(scope, token)
+	if err != nil {
+		var v violations
+		v.check("page_token", err)
+		return pageCursor{}, v.err()
+	}
+	return pageCursor{
+		CreatedAt: pgtype.Timestamptz{Time: cursor.CreatedAt, Valid: true},
+		ID:        pgtype.Int8{Int64: cursor.ID, Valid: true},
+	}, nil
+}
+
+// nextPage trims rows, queried with one row more than pageSize, to the page
+// and returns the token of the next page, or an empty token on the last one.
+func nextPage[T any](maker *util.PageTokenMaker, scope string, rows []T, pageSize int32, cursor func(T) util.PageCursor) ([]T, string, error) {
+	if len(rows) <= int(pageSize) {
+		return rows, "", nil
+	}
+	rows = rows[:pageSize]
+	token, err := maker.Encode(scope, cursor(rows[len(rows)-1]))
+	if err != nil {
+		return nil, "", apperror.Internal(fmt.Errorf("failed to create page token: %w", err))
+	}
+	return rows, token, nil
+}
+
+// HistoryParams filters and pages the entries or transfers of one account.
+// Nil filters are not applied.
+type HistoryParams struct {
+	AccountID int64
+	PageSize  int32
+	PageToken string
+	StartTime *time.Time
+	EndTime   *time.Time
+	Direction *string
+	MinAmount *int64
+	MaxAmount *int64
+}
+
+func (arg HistoryParams) validate(v *violations) {
+	v.check("account_id", util.ValidateID(arg.AccountID))
+	if arg.PageSize < minHistoryPageSize || arg.PageSize > maxHistoryPageSize {
+		v.check("page_size", errors.New("must be between 1 and 100"))
+	}
+	if arg.StartTime != nil && arg.EndTime != nil && !arg.StartTime.Before(*arg.EndTime) {
+		v.check("end_time", errors.New("must be after start_time"))
+	}
+	if arg.Direction != nil {
+		v.check("direction", util.ValidateDirection(*arg.Direction))
+	}
+	if arg.MinAmount != nil && *arg.MinAmount < 0 {
+		v.check("min_amount", errors.New("must not be negative"))
+	}
+	if arg.MaxAmount != nil && *arg.MaxAmount < 0 {
+		v.check("max_amount", errors.New("must not be negative"))
+	}
+	if arg.MinAmount != nil && arg.MaxAmount != nil && *arg.MinAmount > *arg.MaxAmount {
+		v.check("max_amount", errors.New("must not be less than min_amount"))
+	}
+}
+
+func toTimestamptz(value *time.Time) pgtype.Timestamptz {
+	if value == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *value, Valid: true}
+}
+
+func toText(value *string) pgtype.Text {
+	if value == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *value, Valid: true}
+}
+
+func toInt8(value *int64) pgtype.Int8 {
+	if value == nil {
+		return pgtype.Int8{}
+	}
+	return pgtype.Int8{Int64: *value, Valid: true}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/labasubagia/simplebank/apperror"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/metrics"
+	"github.com/labasubagia/simplebank/util"
+)
+
+// TransferService holds the transfer use cases shared by the gRPC and REST APIs.
+type TransferService struct {
+	config         util.Config
+	store          db.Store
+	loginAttempts  *LoginAttemptService
+	pageTokenMaker *util.PageTokenMaker
+}
+
+// NewTransferService counts wrong step-up codes with loginAttempts, so they
+// share the lockout of failed logins.
+func NewTransferService(config util.Config, store db.Store, loginAttempts *LoginAttemptService) *TransferService {
+	return &TransferService{
+		config:         config,
+		store:          store,
+		loginAttempts:  loginAttempts,
+		pageTokenMaker: util.NewPageTokenMaker(config.TokenSymmetricKey),
+	}
+}
+
+// CreateTransferParams moves Amount between two accounts in Currency.
+// TotpCode is needed above TRANSFER_STEP_UP_AMOUNT.
+type CreateTransferParams struct {
+	FromAccountID int64
+	ToAccountID   int64
+	Amount        int64
+	Currency      string
+	TotpCode      *string
+}
+
+// CreateTransfer moves money out of an account of caller.
+func (service *TransferService) CreateTransfer(ctx context.Context, caller string, arg CreateTransferParams) (db.TransferTxResult, error) {
+	var v violations
+	v.check("from_account_id", util.ValidateID(arg.FromAccountID))
+	v.check("to_account_id", util.ValidateID(arg.ToAccountID))
+	if arg.Amount < 1 {
+		v.check("amount", errors.New("amount minimal 1"))
+	}
+	v.check("currency", util.ValidateCurrency(arg.Currency))
+	if arg.TotpCode != nil {
+		v.check("totp_code", util.ValidateTOTPCode(*arg.TotpCode))
+	}
+	if err := v.err(); err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	if err := requireVerifiedEmail(ctx, service.config, service.store, caller); err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	fromAccount, err := service.validAccount(ctx, "from", arg.FromAccountID, arg.Currency)
+	if err != nil {
+		return db.TransferTxResult{}, err
+	}
+	if fromAccount.Owner != caller {
+		return db.TransferTxResult{}, errNotAccountOwner
+	}
+
+	if err := service.verifyStepUp(ctx, caller, arg); err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	toAccount, err := service.validAccount(ctx, "to", arg.ToAccountID, arg.Currency)
+	if err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	result, err := service.store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        arg.Amount,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			return db.TransferTxResult{}, apperror.New(apperror.CodeInsufficientFunds, "from account has insufficient funds")
+		}
+		if errors.Is(err, db.ErrAccountClosed) {
+			return db.TransferTxResult{}, apperror.New(apperror.CodeAccountClosed, "account is closed")
+		}
+		return db.TransferTxResult{}, apperror.Internal(fmt.Errorf("failed to transfer: %w", err))
+	}
+	metrics.ObserveTransfer(fromAccount.Currency, result.Transfer.Amount)
+	return result, nil
+}
+
+// validAccount loads the from or to side of a transfer and makes sure it
+// can take part in it.
+func (service *TransferService) validAccount(ctx context.Context, side string, accountID int64, currency string) (db.Account, error) {
+	account, err := service.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.Account{}, apperror.Newf(apperror.CodeAccountNotFound, "%s account not found", side)
+		}
+		return db.Account{}, apperror.Internal(fmt.Errorf("failed to get %s account: %w", side, err))
+	}
+
+	if account.Status != util.AccountStatusActive {
+		return db.Account{}, apperror.Newf(apperror.CodeAccountClosed, "%s account is closed", side)
+	}
+
+	if account.Currency != currency {
+		return db.Account{}, apperror.Newf(apperror.CodeCurrencyMismatch, "mismatch currency %s and %s", account.Currency, currency)
+	}
+
+	return account, nil
+}
+
+// verifyStepUp requires a totp code for transfers above the configured
+// amount. Wrong codes count as failed logins of username.
+func (service *TransferService) verifyStepUp(ctx context.Context, username string, arg CreateTransferParams) error {
+	if service.config.TransferStepUpAmount <= 0 || arg.Amount <= service.config.TransferStepUpAmount {
+		return nil
+	}
+
+	userTotp, err := service.store.GetUserTotp(ctx, username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return apperror.Internal(fmt.Errorf("failed to get user totp: %w", err))
+	}
+	if err != nil || !userTotp.IsEnabled {
+		return apperror.New(apperror.CodeFailedPrecondition, "two-factor authentication is required for this transfer amount")
+	}
+
+	if arg.TotpCode == nil {
+		return apperror.New(apperror.CodeSecondFactorRequired, "totp code is required for this transfer amount")
+	}
+	if err := service.loginAttempts.CheckLockout(ctx, username, ""); err != nil {
+		return err
+	}
+	step, ok := util.CheckTOTP(*arg.TotpCode, userTotp.Secret)
+	if !ok {
+		return service.recordStepUpFailure(ctx, username)
+	}
+	_, err = service.store.UseUserTotpStep(ctx, db.UseUserTotpStepParams{
+		Username:     username,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return apperror.New(apperror.CodePermissionDenied, "totp code has already been used")
+		}
+		return apperror.Internal(fmt.Errorf("failed to use totp code: %w", err))
+	}
+	return nil
+}
+
+func (service *TransferService) recordStepUpFailure(ctx context.Context, username string) error {
+	errInvalidCode := apperror.New(apperror.CodePermissionDenied, "invalid totp code")
+	if service.config.LoginMaxAttempts <= 0 {
+		return errInvalidCode
+	}
+
+	// the user is needed to email them when the username gets locked
+	user, err := service.store.GetUser(ctx, username)
+	if err != nil {
+		return apperror.Internal(fmt.Errorf("failed to get user: %w", err))
+	}
+	if err := service.loginAttempts.RecordFailure(ctx, username, "", &user); err != nil {
+		return err
+	}
+	return errInvalidCode
+}
+
+// ListTransfersParams pages the transfers of an account, optionally only
+// those with CounterpartyAccountID.
+type ListTransfersParams struct {
+	HistoryParams
+	CounterpartyAccountID *int64
+}
+
+type ListTransfersResult struct {
+	Transfers     []db.Transfer
+	NextPageToken string
+}
+
+// ListTransfers returns a page of the transfers of an account of caller.
+func (service *TransferService) ListTransfers(ctx context.Context, caller string, arg ListTransfersParams) (ListTransfersResult, error) {
+	var v violations
+	arg.validate(&v)
+	if arg.CounterpartyAccountID != nil {
+		v.check("counterparty_account_id", util.ValidateID(*arg.CounterpartyAccountID))
+	}
+	if err := v.err(); err != nil {
+		return ListTransfersResult{}, err
+	}
+
+	listArg := db.ListAccountTransfersParams{
+		AccountID:             arg.AccountID,
+		Direction:             toText(arg.Direction),
+		CounterpartyAccountID: toInt8(arg.CounterpartyAccountID),
+		StartTime:             toTimestamptz(arg.StartTime),
+		EndTime:               toTimestamptz(arg.EndTime),
+		MinAmount:             toInt8(arg.MinAmount),
+		MaxAmount:             toInt8(arg.MaxAmount),
+		Limit:                 arg.PageSize + 1,
+	}
+	scope := pageTokenScope("transfers", listArg.AccountID, listArg.Direction, listArg.CounterpartyAccountID,
+		listArg.StartTime, listArg.EndTime, listArg.MinAmount, listArg.MaxAmount)
+	cursor, err := decodePageToken(service.pageTokenMaker, scope, arg.PageToken)
+	if err != nil {
+		return ListTransfersResult{}, err
+	}
+	listArg.CursorCreatedAt = cursor.CreatedAt
+	listArg.CursorID = cursor.ID
+
+	if _, err := ownedAccount(ctx, service.store, caller, arg.AccountID); err != nil {
+		return ListTransfersResult{}, err
+	}
+
+	transfers, err := service.store.ListAccountTransfers(ctx, listArg)
+	if err != nil {
+		return ListTransfersResult{}, apperror.Internal(fmt.Errorf("failed to list transfers: %w", err))
+	}
+
+	transfers, nextPageToken, err := nextPage(service.pageTokenMaker, scope, transfers, arg.PageSize, func(transfer db.Transfer) util.PageCursor {
+		return util.PageCursor{CreatedAt: transfer.CreatedAt, ID: transfer.ID}
+	})
+	if err != nil {
+		return ListTransfersResult{}, err
+	}
+	return ListTransfersResult{Transfers: transfers, NextPageToken: nextPageToken}, nil
+}
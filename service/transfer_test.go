@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labasubagia/simplebank/apperror"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/worker"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateTransfer(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsEmailVerified = true
+	account1 := randomAccount(user.Username)
+	account2 := randomAccount(util.RandomOwner())
+	account2.Currency = account1.Currency
+	for account2.ID == account1.ID {
+		account2.ID = util.RandomInt(1, 1000)
+	}
+
+	closed := account2
+	closed.Status = util.AccountStatusClosed
+	mismatch := account2
+	for mismatch.Currency == account1.Currency {
+		mismatch.Currency = util.RandomCurrency()
+	}
+
+	secret, _, err := util.GenerateTOTP("simplebank", user.Email)
+	require.NoError(t, err)
+	validCode, err := totp.GenerateCode(secret, time.Now())
+	require.NoError(t, err)
+	invalidCode := "000000"
+	if invalidCode == validCode {
+		invalidCode = "111111"
+	}
+
+	const stepUpAmount = 100
+	arg := CreateTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Currency:      account1.Currency,
+	}
+	bigArg := arg
+	bigArg.Amount = stepUpAmount + 1
+
+	// one wrong code locks the username
+	lockoutConfig := util.Config{
+		TransferStepUpAmount: stepUpAmount,
+		LoginMaxAttempts:     1,
+		LoginAttemptWindow:   time.Hour,
+		LoginLockoutDuration: time.Minute,
+	}
+
+	withCode := func(arg CreateTransferParams, code string) CreateTransferParams {
+		arg.TotpCode = &code
+		return arg
+	}
+
+	expectTransfer := func(store *mock_db.MockStore, amount int64) {
+		store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+		store.EXPECT().GetAccount(gomock.Any(), account2.ID).Times(1).Return(account2, nil)
+		txArg := db.TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: amount}
+		store.EXPECT().TransferTx(gomock.Any(), txArg).Times(1).
+			Return(db.TransferTxResult{Transfer: db.Transfer{Amount: amount}}, nil)
+	}
+
+	testCases := []struct {
+		name       string
+		caller     string
+		arg        CreateTransferParams
+		config     util.Config
+		buildStubs func(store *mock_db.MockStore)
+		code       apperror.Code
+	}{
+		{
+			name:   "OK",
+			caller: user.Username,
+			arg:    arg,
+			config: util.Config{RequireVerifiedEmail: true},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				expectTransfer(store, arg.Amount)
+			},
+		},
+		{
+			name:   "InvalidArgument",
+			caller: user.Username,
+			arg:    withCode(CreateTransferParams{Currency: "CAD"}, "12"),
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodeInvalidArgument,
+		},
+		{
+			name:   "EmailNotVerified",
+			caller: user.Username,
+			arg:    arg,
+			config: util.Config{RequireVerifiedEmail: true},
+			buildStubs: func(store *mock_db.MockStore) {
+				unverified := user
+				unverified.IsEmailVerified = false
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(unverified, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodeEmailNotVerified,
+		},
+		{
+			name:   "NotOwner",
+			caller: "other_user",
+			arg:    arg,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodePermissionDenied,
+		},
+		{
+			name:   "FromAccountNotFound",
+			caller: user.Username,
+			arg:    arg,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			code: apperror.CodeAccountNotFound,
+		},
+		{
+			name:   "ToAccountClosed",
+			caller: user.Username,
+			arg:    arg,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), account2.ID).Times(1).Return(closed, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodeAccountClosed,
+		},
+		{
+			name:   "ToCurrencyMismatch",
+			caller: user.Username,
+			arg:    arg,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), account2.ID).Times(1).Return(mismatch, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodeCurrencyMismatch,
+		},
+		{
+			name:   "InsufficientFunds",
+			caller: user.Username,
+			arg:    arg,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), account2.ID).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			code: apperror.CodeInsufficientFunds,
+		},
+		{
+			name:   "AccountClosedDuringTransfer",
+			caller: user.Username,
+			arg:    arg,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), account2.ID).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferTxResult{}, db.ErrAccountClosed)
+			},
+			code: apperror.CodeAccountClosed,
+		},
+		{
+			name:   "TransferError",
+			caller: user.Username,
+			arg:    arg,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), account2.ID).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferTxResult{}, errors.New("connection reset"))
+			},
+			code: apperror.CodeInternal,
+		},
+		{
+			name:   "StepUpOK",
+			caller: user.Username,
+			arg:    withCode(bigArg, validCode),
+			config: util.Config{TransferStepUpAmount: stepUpAmount},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).Times(1).
+					Return(db.UserTotp{Username: user.Username, Secret: secret, IsEnabled: true}, nil)
+				store.EXPECT().UseUserTotpStep(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseUserTotpStepParams) (db.UserTotp, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Positive(t, arg.LastUsedStep)
+						return db.UserTotp{Username: user.Username, LastUsedStep: arg.LastUsedStep}, nil
+					})
+				expectTransfer(store, bigArg.Amount)
+			},
+		},
+		{
+			name:   "StepUpReplayedCode",
+			caller: user.Username,
+			arg:    withCode(bigArg, validCode),
+			config: util.Config{TransferStepUpAmount: stepUpAmount},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).Times(1).
+					Return(db.UserTotp{Username: user.Username, Secret: secret, IsEnabled: true}, nil)
+				store.EXPECT().UseUserTotpStep(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodePermissionDenied,
+		},
+		{
+			name:   "StepUpTotpNotEnabled",
+			caller: user.Username,
+			arg:    withCode(bigArg, validCode),
+			config: util.Config{TransferStepUpAmount: stepUpAmount},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+			},
+			code: apperror.CodeFailedPrecondition,
+		},
+		{
+			name:   "StepUpMissingCode",
+			caller: user.Username,
+			arg:    bigArg,
+			config: util.Config{TransferStepUpAmount: stepUpAmount},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).Times(1).
+					Return(db.UserTotp{Username: user.Username, Secret: secret, IsEnabled: true}, nil)
+			},
+			code: apperror.CodeSecondFactorRequired,
+		},
+		{
+			name:   "StepUpInvalidCode",
+			caller: user.Username,
+			arg:    withCode(bigArg, invalidCode),
+			config: util.Config{TransferStepUpAmount: stepUpAmount},
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).Times(1).
+					Return(db.UserTotp{Username: user.Username, Secret: secret, IsEnabled: true}, nil)
+			},
+			code: apperror.CodePermissionDenied,
+		},
+		{
+			name:   "StepUpInvalidCodeLocksOut",
+			caller: user.Username,
+			arg:    withCode(bigArg, invalidCode),
+			config: lockoutConfig,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).Times(1).
+					Return(db.UserTotp{Username: user.Username, Secret: secret, IsEnabled: true}, nil)
+				store.EXPECT().GetLoginAttempt(gomock.Any(), util.LoginAttemptUserKey(user.Username)).Times(1).
+					Return(db.LoginAttempt{}, db.ErrRecordNotFound)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().RecordFailedLoginAttempt(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.LoginAttempt{Key: util.LoginAttemptUserKey(user.Username), FailedCount: 1}, nil)
+				store.EXPECT().LockLoginAttempt(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.LoginAttempt{}, nil)
+			},
+			code: apperror.CodeLoginLocked,
+		},
+		{
+			name:   "StepUpLockedOut",
+			caller: user.Username,
+			arg:    withCode(bigArg, validCode),
+			config: lockoutConfig,
+			buildStubs: func(store *mock_db.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), user.Username).Times(1).
+					Return(db.UserTotp{Username: user.Username, Secret: secret, IsEnabled: true}, nil)
+				store.EXPECT().GetLoginAttempt(gomock.Any(), util.LoginAttemptUserKey(user.Username)).Times(1).
+					Return(db.LoginAttempt{LockedUntil: time.Now().Add(time.Minute)}, nil)
+				store.EXPECT().UseUserTotpStep(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: apperror.CodeLoginLocked,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock_db.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			result, err := NewTransferService(tc.config, store, NewLoginAttemptService(tc.config, store, worker.NewInMemoryTaskDistributor(worker.NewInMemoryQueue()))).CreateTransfer(context.Background(), tc.caller, tc.arg)
+			if tc.code == "" {
+				require.NoError(t, err)
+				require.Equal(t, tc.arg.Amount, result.Transfer.Amount)
+				return
+			}
+			requireCode(t, err, tc.code)
+		})
+	}
+}
+
+func TestListTransfersPageToken(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	counterparty := randomAccount(util.RandomOwner())
+	transfers := make([]db.Transfer, 3)
+	for i := range transfers {
+		transfers[i] = db.Transfer{
+			ID:            util.RandomInt(1, 1000),
+			FromAccountID: account.ID,
+			ToAccountID:   counterparty.ID,
+			Amount:        util.RandomMoney(),
+			CreatedAt:     time.Now().Add(-time.Duration(i) * time.Minute),
+		}
+	}
+
+	ctrl := gomock.NewController(t)
+	store := mock_db.NewMockStore(ctrl)
+	service := NewTransferService(util.Config{TokenSymmetricKey: util.RandomString(32)}, store, nil)
+	ctx := context.Background()
+	arg := ListTransfersParams{HistoryParams: HistoryParams{AccountID: account.ID, PageSize: 2}}
+
+	store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(2).Return(account, nil)
+	store.EXPECT().ListAccountTransfers(gomock.Any(), db.ListAccountTransfersParams{AccountID: account.ID, Limit: 3}).
+		Times(1).
+		Return(transfers, nil)
+	page, err := service.ListTransfers(ctx, account.Owner, arg)
+	require.NoError(t, err)
+	require.Equal(t, transfers[:2], page.Transfers)
+	require.NotEmpty(t, page.NextPageToken)
+
+	store.EXPECT().ListAccountTransfers(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, listArg db.ListAccountTransfersParams) ([]db.Transfer, error) {
+			require.True(t, listArg.CursorCreatedAt.Time.Equal(transfers[1].CreatedAt))
+			require.Equal(t, pgtype.Int8{Int64: transfers[1].ID, Valid: true}, listArg.CursorID)
+			return transfers[2:], nil
+		})
+	arg.PageToken = page.NextPageToken
+	next, err := service.ListTransfers(ctx, account.Owner, arg)
+	require.NoError(t, err)
+	require.Equal(t, transfers[2:], next.Transfers)
+	require.Empty(t, next.NextPageToken)
+
+	// the token only resumes the list with the same filters
+	arg.CounterpartyAccountID = &counterparty.ID
+	_, err = service.ListTransfers(ctx, account.Owner, arg)
+	requireCode(t, err, apperror.CodeInvalidArgument)
+}
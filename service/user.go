@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labasubagia/simplebank/apperror"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/worker"
+)
+
+var errUserNotFound = apperror.New(apperror.CodeNotFound, "user not found")
+
+var errEmailExists = apperror.New(apperror.CodeAlreadyExists, "email already belongs to another user")
+
+// UserService holds the user use cases shared by the gRPC and REST APIs.
+type UserService struct {
+	store           db.Store
+	passwordHasher  util.PasswordHasher
+	taskDistributor worker.TaskDistributor
+}
+
+func NewUserService(store db.Store, passwordHasher util.PasswordHasher, taskDistributor worker.TaskDistributor) *UserService {
+	return &UserService{
+		store:           store,
+		passwordHasher:  passwordHasher,
+		taskDistributor: taskDistributor,
+	}
+}
+
+type CreateUserParams struct {
+	Username string
+	Password string
+	FullName string
+	Email    string
+}
+
+// CreateUser signs up a user and sends the email verification link.
+func (service *UserService) CreateUser(ctx context.Context, arg CreateUserParams) (db.User, error) {
+	var v violations
+	v.check("email", util.ValidateEmail(arg.Email))
+	v.check("username", util.ValidateUsername(arg.Username))
+	v.check("full_name", util.ValidateFullName(arg.FullName))
+	v.check("password", util.ValidatePassword(arg.Password))
+	if err := v.err(); err != nil {
+		return db.User{}, err
+	}
+
+	hashedPassword, err := service.passwordHasher.Hash(arg.Password)
+	if err != nil {
+		return db.User{}, apperror.Internal(fmt.Errorf("failed to hash password: %w", err))
+	}
+
+	result, err := service.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       arg.Username,
+			Email:          arg.Email,
+			FullName:       arg.FullName,
+			HashedPassword: hashedPassword,
+		},
+		AfterCreate: func(user db.User) error {
+			taskPayload := &worker.PayloadSendVerifyEmail{
+				Username: user.Username,
+			}
+			opts := []asynq.Option{
+				asynq.MaxRetry(10),
+				asynq.ProcessIn(10 * time.Second),
+				asynq.Queue(worker.QueueCritical),
+			}
+			return service.taskDistributor.DistributeTaskVerifyEmail(ctx, taskPayload, opts...)
+		},
+	})
+	if err != nil {
+		if db.ErrorCode(err) == db.UniqueViolation {
+			return db.User{}, apperror.New(apperror.CodeAlreadyExists, "username or email already exists")
+		}
+		return db.User{}, apperror.Internal(fmt.Errorf("failed to create user: %w", err))
+	}
+	return result.User, nil
+}
+
+// UpdateUserParams changes the non-nil fields. Changing the email or the
+// password needs the current password.
+type UpdateUserParams struct {
+	Username        string
+	FullName        *string
+	Email           *string
+	Password        *string
+	CurrentPassword string
+}
+
+// UpdateUser lets caller update their own profile. A new email stays
+// pending until it is confirmed through the link sent to it, and the
+// current address is told about the request.
+func (service *UserService) UpdateUser(ctx context.Context, caller string, arg UpdateUserParams) (db.User, error) {
+	var v violations
+	v.check("username", util.ValidateUsername(arg.Username))
+	if arg.FullName != nil {
+		v.check("full_name", util.ValidateFullName(*arg.FullName))
+	}
+	if arg.Email != nil {
+		v.check("email", util.ValidateEmail(*arg.Email))
+	}
+	if arg.Password != nil {
+		v.check("password", util.ValidatePassword(*arg.Password))
+	}
+	if arg.Email != nil || arg.Password != nil {
+		v.check("current_password", util.ValidatePassword(arg.CurrentPassword))
+	}
+	if err := v.err(); err != nil {
+		return db.User{}, err
+	}
+
+	if caller != arg.Username {
+		return db.User{}, apperror.New(apperror.CodePermissionDenied, "cannot update other user's info")
+	}
+
+	user, err := service.store.GetUser(ctx, arg.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.User{}, errUserNotFound
+		}
+		return db.User{}, apperror.Internal(fmt.Errorf("failed to get user: %w", err))
+	}
+
+	if arg.Email != nil || arg.Password != nil {
+		if err := service.passwordHasher.Check(arg.CurrentPassword, user.HashedPassword); err != nil {
+			return db.User{}, apperror.New(apperror.CodePermissionDenied, "incorrect current password")
+		}
+	}
+
+	txArg := db.UpdateUserTxParams{
+		UpdateUserParams: db.UpdateUserParams{
+			Username: arg.Username,
+		},
+	}
+	if arg.FullName != nil {
+		txArg.FullName = pgtype.Text{String: *arg.FullName, Valid: true}
+	}
+
+	if arg.Email != nil && *arg.Email != user.Email {
+		txArg.PendingEmail = pgtype.Text{String: *arg.Email, Valid: true}
+		txArg.AfterUpdate = func(updated db.User) error {
+			opts := []asynq.Option{
+				asynq.MaxRetry(10),
+				asynq.ProcessIn(10 * time.Second),
+				asynq.Queue(worker.QueueCritical),
+			}
+			err := service.taskDistributor.DistributeTaskVerifyEmail(ctx, &worker.PayloadSendVerifyEmail{
+				Username: updated.Username,
+				Email:    updated.PendingEmail.String,
+			}, opts...)
+			if err != nil {
+				return err
+			}
+			return service.taskDistributor.DistributeTaskSendEmailChangeNotice(ctx, &worker.PayloadSendEmailChangeNotice{
+				Username: updated.Username,
+				OldEmail: updated.Email,
+				NewEmail: updated.PendingEmail.String,
+			}, opts...)
+		}
+	}
+
+	if arg.Password != nil {
+		hashedPassword, err := service.passwordHasher.Hash(*arg.Password)
+		if err != nil {
+			return db.User{}, apperror.Internal(fmt.Errorf("failed to hash password: %w", err))
+		}
+		txArg.HashedPassword = pgtype.Text{String: hashedPassword, Valid: true}
+		txArg.PasswordChangedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	result, err := service.store.UpdateUserTx(ctx, txArg)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.User{}, errUserNotFound
+		}
+		if db.ErrorCode(err) == db.UniqueViolation {
+			return db.User{}, errEmailExists
+		}
+		return db.User{}, apperror.Internal(fmt.Errorf("failed to update user: %w", err))
+	}
+	return result.User, nil
+}
+
+// VerifyEmail confirms the address a verification link was sent to.
+func (service *UserService) VerifyEmail(ctx context.Context, emailID int64, secretCode string) (db.User, error) {
+	var v violations
+	v.check("email_id", util.ValidateEmailID(emailID))
+	v.check("secret_code", util.ValidateSecretCode(secretCode))
+	if err := v.err(); err != nil {
+		return db.User{}, err
+	}
+
+	result, err := service.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:    emailID,
+		SecretCode: secretCode,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.User{}, apperror.New(apperror.CodeNotFound, "verification link is invalid or expired")
+		}
+		if db.ErrorCode(err) == db.UniqueViolation {
+			return db.User{}, errEmailExists
+		}
+		return db.User{}, apperror.Internal(fmt.Errorf("failed to verify email: %w", err))
+	}
+	return result.User, nil
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/labasubagia/simplebank/apperror"
+	"github.com/labasubagia/simplebank/db/memstore"
+	mock_db "github.com/labasubagia/simplebank/db/mock"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/labasubagia/simplebank/worker"
+	mock_worker "github.com/labasubagia/simplebank/worker/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestUserService(t *testing.T, store db.Store, taskDistributor worker.TaskDistributor) *UserService {
+	passwordHasher, err := util.NewPasswordHasher(util.Config{})
+	require.NoError(t, err)
+	return NewUserService(store, passwordHasher, taskDistributor)
+}
+
+func randomUser(t *testing.T) (db.User, string) {
+	password := util.RandomString(8)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user := db.User{
+		Username:       util.RandomOwner(),
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+		HashedPassword: hashedPassword,
+	}
+	return user, password
+}
+
+func requireCode(t *testing.T, err error, code apperror.Code) *apperror.Error {
+	var appErr *apperror.Error
+	require.True(t, errors.As(err, &appErr), "expected an apperror, got %v", err)
+	require.Equal(t, code, appErr.Code)
+	return appErr
+}
+
+func TestCreateUser(t *testing.T) {
+	user, password := randomUser(t)
+	arg := CreateUserParams{
+		Username: user.Username,
+		Password: password,
+		FullName: user.FullName,
+		Email:    user.Email,
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		taskDistributor := mock_worker.NewMockTaskDistributor(ctrl)
+
+		store.EXPECT().
+			CreateUserTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, txArg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+				require.Equal(t, user.Username, txArg.Username)
+				require.NoError(t, util.CheckPassword(password, txArg.HashedPassword))
+				require.NoError(t, txArg.AfterCreate(user))
+				return db.CreateUserTxResult{User: user}, nil
+			})
+		taskDistributor.EXPECT().
+			DistributeTaskVerifyEmail(gomock.Any(), &worker.PayloadSendVerifyEmail{Username: user.Username}, gomock.Any()).
+			Times(1).
+			Return(nil)
+
+		created, err := newTestUserService(t, store, taskDistributor).CreateUser(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, user, created)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+
+		invalid := arg
+		invalid.Username = "Invalid#"
+		invalid.Password = "123"
+		_, err := newTestUserService(t, store, nil).CreateUser(context.Background(), invalid)
+		appErr := requireCode(t, err, apperror.CodeInvalidArgument)
+		require.Len(t, appErr.Violations, 2)
+		require.Equal(t, "username", appErr.Violations[0].Field)
+		require.Equal(t, "password", appErr.Violations[1].Field)
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().
+			CreateUserTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.CreateUserTxResult{}, db.ErrUniqueViolation)
+
+		_, err := newTestUserService(t, store, nil).CreateUser(context.Background(), arg)
+		requireCode(t, err, apperror.CodeAlreadyExists)
+	})
+
+	t.Run("InternalError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().
+			CreateUserTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.CreateUserTxResult{}, errors.New("connection reset"))
+
+		_, err := newTestUserService(t, store, nil).CreateUser(context.Background(), arg)
+		appErr := requireCode(t, err, apperror.CodeInternal)
+		require.NotContains(t, appErr.Message, "connection reset")
+	})
+}
+
+func TestCreateUserEnqueuesVerifyEmail(t *testing.T) {
+	store := memstore.NewStore()
+	queue := worker.NewInMemoryQueue()
+	service := newTestUserService(t, store, worker.NewInMemoryTaskDistributor(queue))
+
+	user, password := randomUser(t)
+	_, err := service.CreateUser(context.Background(), CreateUserParams{
+		Username: user.Username,
+		Password: password,
+		FullName: user.FullName,
+		Email:    user.Email,
+	})
+	require.NoError(t, err)
+
+	tasks := queue.Tasks()
+	require.Len(t, tasks, 1)
+	require.Equal(t, worker.TaskSendVerifyEmail, tasks[0].Type)
+	require.Equal(t, worker.QueueCritical, tasks[0].Queue)
+	require.Equal(t, 10, tasks[0].MaxRetry)
+	require.Equal(t, asynq.TaskStateScheduled, tasks[0].State)
+	require.WithinDuration(t, time.Now().Add(10*time.Second), tasks[0].NextProcessAt, time.Second)
+
+	var payload worker.PayloadSendVerifyEmail
+	require.NoError(t, json.Unmarshal(tasks[0].Payload, &payload))
+	require.Equal(t, user.Username, payload.Username)
+
+	// a duplicate rolls back without enqueueing another task
+	_, err = service.CreateUser(context.Background(), CreateUserParams{
+		Username: user.Username,
+		Password: password,
+		FullName: user.FullName,
+		Email:    user.Email,
+	})
+	requireCode(t, err, apperror.CodeAlreadyExists)
+	require.Len(t, queue.Tasks(), 1)
+}
+
+func TestUpdateUser(t *testing.T) {
+	user, password := randomUser(t)
+	newEmail := util.RandomEmail()
+	sameEmail := user.Email
+	newPassword := util.RandomString(8)
+
+	t.Run("SameEmailSendsNothing", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+		store.EXPECT().
+			UpdateUserTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, txArg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+				require.False(t, txArg.PendingEmail.Valid)
+				require.Nil(t, txArg.AfterUpdate)
+				return db.UpdateUserTxResult{User: user}, nil
+			})
+
+		_, err := newTestUserService(t, store, nil).UpdateUser(context.Background(), user.Username, UpdateUserParams{
+			Username:        user.Username,
+			Email:           &sameEmail,
+			CurrentPassword: password,
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("ChangePassword", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+		store.EXPECT().
+			UpdateUserTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, txArg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+				require.NoError(t, util.CheckPassword(newPassword, txArg.HashedPassword.String))
+				require.True(t, txArg.PasswordChangedAt.Valid)
+				return db.UpdateUserTxResult{User: user}, nil
+			})
+
+		_, err := newTestUserService(t, store, nil).UpdateUser(context.Background(), user.Username, UpdateUserParams{
+			Username:        user.Username,
+			Password:        &newPassword,
+			CurrentPassword: password,
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("MissingCurrentPassword", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := newTestUserService(t, store, nil).UpdateUser(context.Background(), user.Username, UpdateUserParams{
+			Username: user.Username,
+			Email:    &newEmail,
+		})
+		appErr := requireCode(t, err, apperror.CodeInvalidArgument)
+		require.Equal(t, "current_password", appErr.Violations[0].Field)
+	})
+
+	t.Run("OtherUser", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := newTestUserService(t, store, nil).UpdateUser(context.Background(), "other_user", UpdateUserParams{
+			Username: user.Username,
+		})
+		requireCode(t, err, apperror.CodePermissionDenied)
+	})
+
+	t.Run("UserNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(db.User{}, db.ErrRecordNotFound)
+
+		_, err := newTestUserService(t, store, nil).UpdateUser(context.Background(), user.Username, UpdateUserParams{
+			Username: user.Username,
+		})
+		requireCode(t, err, apperror.CodeNotFound)
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	secretCode := util.RandomString(32)
+
+	t.Run("InvalidOrExpired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().
+			VerifyEmailTx(gomock.Any(), db.VerifyEmailTxParams{EmailID: 1, SecretCode: secretCode}).
+			Times(1).
+			Return(db.VerifyEmailTxResult{}, db.ErrRecordNotFound)
+
+		_, err := newTestUserService(t, store, nil).VerifyEmail(context.Background(), 1, secretCode)
+		requireCode(t, err, apperror.CodeNotFound)
+	})
+
+	t.Run("EmailTaken", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().
+			VerifyEmailTx(gomock.Any(), db.VerifyEmailTxParams{EmailID: 1, SecretCode: secretCode}).
+			Times(1).
+			Return(db.VerifyEmailTxResult{}, db.ErrUniqueViolation)
+
+		_, err := newTestUserService(t, store, nil).VerifyEmail(context.Background(), 1, secretCode)
+		requireCode(t, err, apperror.CodeAlreadyExists)
+	})
+
+	t.Run("InvalidArgument", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock_db.NewMockStore(ctrl)
+		store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := newTestUserService(t, store, nil).VerifyEmail(context.Background(), 0, "short")
+		appErr := requireCode(t, err, apperror.CodeInvalidArgument)
+		require.Len(t, appErr.Violations, 2)
+	})
+}
@@ -0,0 +1,20 @@
+package service
+
+import "github.com/labasubagia/simplebank/apperror"
+
+// violations collects the failed checks of one request.
+type violations []apperror.FieldViolation
+
+func (v *violations) check(field string, err error) {
+	if err != nil {
+		*v = append(*v, apperror.FieldViolation{Field: field, Description: err.Error()})
+	}
+}
+
+// err returns an INVALID_ARGUMENT error listing the violations, or nil.
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return apperror.InvalidArgument(v)
+}
@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestQuerySpanName(t *testing.T) {
+	require.Equal(t, "db GetUser", querySpanName("-- name: GetUser :one\nSELECT * FROM users WHERE username = $1"))
+	require.Equal(t, "db SELECT", querySpanName("  select version, dirty FROM schema_migrations"))
+	require.Equal(t, "db query", querySpanName(""))
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	queryTracer := &QueryTracer{tracer: provider.Tracer(instrumentationName)}
+
+	ctx, parent := provider.Tracer(instrumentationName).Start(context.Background(), "parent")
+
+	queryCtx := queryTracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "-- name: GetUser :one\nSELECT 1"})
+	queryTracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{})
+
+	queryCtx = queryTracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "-- name: CreateUser :one\nINSERT"})
+	queryTracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: errors.New("duplicate key")})
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	getUser, createUser := spans[0], spans[1]
+	require.Equal(t, "db GetUser", getUser.Name())
+	require.Equal(t, trace.SpanKindClient, getUser.SpanKind())
+	require.Equal(t, parent.SpanContext().SpanID(), getUser.Parent().SpanID())
+	require.Equal(t, codes.Unset, getUser.Status().Code)
+
+	require.Equal(t, "db CreateUser", createUser.Name())
+	require.Equal(t, codes.Error, createUser.Status().Code)
+	require.Equal(t, "duplicate key", createUser.Status().Description)
+}
@@ -0,0 +1,34 @@
+package util
+
+import "errors"
+
+const (
+	AccountStatusActive = "active"
+	AccountStatusClosed = "closed"
+)
+
+var (
+	ErrInvalidAccountStatus = errors.New("account status invalid")
+	ErrAccountClosed        = errors.New("account closed")
+)
+
+func IsSupportedAccountStatus(status string) bool {
+	switch status {
+	case AccountStatusActive, AccountStatusClosed:
+		return true
+	}
+	return false
+}
+
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+)
+
+func IsSupportedDirection(direction string) bool {
+	switch direction {
+	case DirectionIn, DirectionOut:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	apiKeyPrefix        = "sb"
+	apiKeyIDBytes       = 6
+	apiKeySecretBytes   = 24
+	DefaultApiKeyDays   = 90
+	MaxApiKeyDays       = 365
+	ScopeAccountsRead   = "accounts:read"
+	ScopeAccountsWrite  = "accounts:write"
+	ScopeTransfersRead  = "transfers:read"
+	ScopeTransfersWrite = "transfers:write"
+)
+
+var ErrInvalidApiKey = errors.New("api key has invalid format")
+
+func IsSupportedScope(scope string) bool {
+	switch scope {
+	case ScopeAccountsRead, ScopeAccountsWrite, ScopeTransfersRead, ScopeTransfersWrite:
+		return true
+	}
+	return false
+}
+
+// GenerateApiKey returns a key formatted as sb_<prefix>_<secret>. Only the
+// prefix is stored in clear so the key can be looked up.
+func GenerateApiKey() (key string, prefix string, err error) {
+	id := make([]byte, apiKeyIDBytes)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	prefix = hex.EncodeToString(id)
+	key = fmt.Sprintf("%s_%s_%s", apiKeyPrefix, prefix, hex.EncodeToString(secret))
+	return key, prefix, nil
+}
+
+func ParseApiKey(key string) (prefix string, err error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix ||
+		len(parts[1]) != apiKeyIDBytes*2 || len(parts[2]) != apiKeySecretBytes*2 {
+		return "", ErrInvalidApiKey
+	}
+	return parts[1], nil
+}
+
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasScopes reports whether granted contains every required scope.
+func HasScopes(granted []string, required ...string) bool {
+	for _, scope := range required {
+		found := false
+		for _, g := range granted {
+			if g == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
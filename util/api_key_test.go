@@ -0,0 +1,41 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiKey(t *testing.T) {
+	key, prefix, err := GenerateApiKey()
+	require.NoError(t, err)
+	require.NotEmpty(t, prefix)
+
+	parsedPrefix, err := ParseApiKey(key)
+	require.NoError(t, err)
+	require.Equal(t, prefix, parsedPrefix)
+
+	otherKey, otherPrefix, err := GenerateApiKey()
+	require.NoError(t, err)
+	require.NotEqual(t, key, otherKey)
+	require.NotEqual(t, prefix, otherPrefix)
+	require.NotEqual(t, HashApiKey(key), HashApiKey(otherKey))
+	require.Len(t, HashApiKey(key), 64)
+
+	for _, invalid := range []string{"", "sb_abc", "xx_" + prefix + "_" + RandomString(48), "sb_" + prefix + "_short"} {
+		_, err = ParseApiKey(invalid)
+		require.ErrorIs(t, err, ErrInvalidApiKey)
+	}
+}
+
+func TestHasScopes(t *testing.T) {
+	granted := []string{ScopeAccountsRead, ScopeTransfersWrite}
+	require.True(t, HasScopes(granted))
+	require.True(t, HasScopes(granted, ScopeTransfersWrite))
+	require.True(t, HasScopes(granted, ScopeAccountsRead, ScopeTransfersWrite))
+	require.False(t, HasScopes(granted, ScopeAccountsWrite))
+	require.False(t, HasScopes(nil, ScopeAccountsRead))
+
+	require.True(t, IsSupportedScope(ScopeAccountsWrite))
+	require.False(t, IsSupportedScope("admin"))
+}
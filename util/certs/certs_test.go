@@ -0,0 +1,130 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certificates, err := GenerateDev([]string{"localhost", "127.0.0.1"}, "worker.internal", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, certificates.WriteFiles(dir))
+
+	reloader, err := NewReloader(filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem"))
+	require.NoError(t, err)
+
+	_, err = ServerTLSConfig(reloader, "", ClientAuthRequire)
+	require.Error(t, err)
+	_, err = ServerTLSConfig(reloader, filepath.Join(dir, "ca.pem"), "sometimes")
+	require.Error(t, err)
+
+	config, err := ServerTLSConfig(reloader, filepath.Join(dir, "ca.pem"), ClientAuthRequire)
+	require.NoError(t, err)
+
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem"))
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(certificates.CACert))
+
+	// mutual handshake, verified on both sides
+	state, err := handshake(t, config, &tls.Config{
+		ServerName:   "127.0.0.1",
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientCert},
+	})
+	require.NoError(t, err)
+	subject, ok := ClientSubject(state)
+	require.True(t, ok)
+	require.Equal(t, "worker.internal", subject)
+
+	subject, err = CertificateSubject(filepath.Join(dir, "server.pem"))
+	require.NoError(t, err)
+	require.Equal(t, "localhost", subject)
+	_, err = CertificateSubject(filepath.Join(dir, "server-key.pem"))
+	require.Error(t, err)
+
+	// clients without a certificate are refused
+	_, err = handshake(t, config, &tls.Config{ServerName: "localhost", RootCAs: roots})
+	require.Error(t, err)
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	first, err := GenerateDev([]string{"localhost"}, "client", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, first.WriteFiles(dir))
+
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")
+	reloader, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	requireServing(t, reloader, first.ServerCert)
+
+	second, err := GenerateDev([]string{"localhost"}, "client", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, second.WriteFiles(dir))
+	require.NoError(t, reloader.Reload())
+	requireServing(t, reloader, second.ServerCert)
+
+	// a broken key pair keeps the previous certificate
+	require.NoError(t, os.WriteFile(keyFile, first.ServerKey, 0o600))
+	require.Error(t, reloader.Reload())
+	requireServing(t, reloader, second.ServerCert)
+}
+
+func TestParseIdentities(t *testing.T) {
+	identities, err := ParseIdentities(" worker.internal=worker, reports.internal = reports ,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"worker.internal":  "worker",
+		"reports.internal": "reports",
+	}, identities)
+
+	identities, err = ParseIdentities("")
+	require.NoError(t, err)
+	require.Empty(t, identities)
+
+	_, err = ParseIdentities("worker.internal")
+	require.Error(t, err)
+	_, err = ParseIdentities("=worker")
+	require.Error(t, err)
+}
+
+func handshake(t *testing.T, serverConfig *tls.Config, clientConfig *tls.Config) (tls.ConnectionState, error) {
+	serverConn, clientConn := net.Pipe()
+	t.Cleanup(func() {
+		serverConn.Close()
+		clientConn.Close()
+	})
+
+	server := tls.Server(serverConn, serverConfig)
+	errs := make(chan error, 1)
+	go func() {
+		err := tls.Client(clientConn, clientConfig).Handshake()
+		clientConn.Close()
+		errs <- err
+	}()
+
+	err := server.Handshake()
+	if clientErr := <-errs; err == nil {
+		err = clientErr
+	}
+	return server.ConnectionState(), err
+}
+
+func requireServing(t *testing.T, reloader *Reloader, certPEM []byte) {
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	require.Equal(t, block.Bytes, cert.Certificate[0])
+}
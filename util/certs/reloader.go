@@ -0,0 +1,114 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Reloader serves the key pair in certFile and keyFile, picking up renewed
+// files without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func NewReloader(certFile string, keyFile string) (*Reloader, error) {
+	reloader := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload loads the key pair from disk, keeping the current one on failure.
+func (reloader *Reloader) Reload() error {
+	modTime, err := reloader.filesModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load key pair: %w", err)
+	}
+
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+	reloader.cert = &cert
+	reloader.modTime = modTime
+	return nil
+}
+
+// GetCertificate is meant for tls.Config.GetCertificate.
+func (reloader *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mu.RLock()
+	defer reloader.mu.RUnlock()
+	return reloader.cert, nil
+}
+
+// GetClientCertificate is meant for tls.Config.GetClientCertificate, for
+// services presenting their own certificate when dialing each other.
+func (reloader *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return reloader.GetCertificate(nil)
+}
+
+// Watch reloads the key pair every interval when either file has changed,
+// until ctx is done.
+func (reloader *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modTime, err := reloader.filesModTime()
+		if err != nil {
+			log.Error().Err(err).Msg("cannot check certificate files")
+			continue
+		}
+
+		reloader.mu.RLock()
+		changed := modTime.After(reloader.modTime)
+		reloader.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err := reloader.Reload(); err != nil {
+			log.Error().Err(err).Msg("cannot reload certificate, keep serving the previous one")
+			continue
+		}
+		log.Info().Str("cert_file", reloader.certFile).Msg("certificate reloaded")
+	}
+}
+
+// filesModTime returns the latest modification time of the key pair files.
+func (reloader *Reloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{reloader.certFile, reloader.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cannot stat %s: %w", file, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
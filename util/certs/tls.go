@@ -0,0 +1,120 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// ServerTLSConfig serves the reloader's certificate. With a clientCAFile,
+// client certificates signed by that CA are verified when clients present
+// one (optional) or demanded from every client (require).
+func ServerTLSConfig(reloader *Reloader, clientCAFile string, clientAuth string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	switch clientAuth {
+	case "", ClientAuthNone:
+		return config, nil
+	case ClientAuthOptional:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unsupported client auth %q", clientAuth)
+	}
+
+	if clientCAFile == "" {
+		return nil, fmt.Errorf("client auth %q needs a client CA file", clientAuth)
+	}
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read client CA file: %w", err)
+	}
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificate found in %s", clientCAFile)
+	}
+	return config, nil
+}
+
+// ClientTLSConfig verifies servers against the CA in caFile, or the system
+// roots without one, and presents the reloader's certificate when a server
+// asks for a client certificate.
+func ClientTLSConfig(reloader *Reloader, caFile string, serverName string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		ServerName:           serverName,
+		GetClientCertificate: reloader.GetClientCertificate,
+	}
+	if caFile == "" {
+		return config, nil
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CA file: %w", err)
+	}
+	config.RootCAs = x509.NewCertPool()
+	if !config.RootCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificate found in %s", caFile)
+	}
+	return config, nil
+}
+
+// ParseIdentities parses comma separated "subject=identity" pairs, mapping
+// client certificate common names to service identities.
+func ParseIdentities(value string) (map[string]string, error) {
+	identities := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		subject, identity, ok := strings.Cut(pair, "=")
+		subject, identity = strings.TrimSpace(subject), strings.TrimSpace(identity)
+		if !ok || subject == "" || identity == "" {
+			return nil, fmt.Errorf("invalid client identity %q, expected subject=identity", pair)
+		}
+		identities[subject] = identity
+	}
+	return identities, nil
+}
+
+// CertificateSubject returns the common name of the first certificate in
+// certFile.
+func CertificateSubject(certFile string) (string, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return "", fmt.Errorf("cannot read certificate file: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", fmt.Errorf("no certificate found in %s", certFile)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse certificate: %w", err)
+	}
+	return cert.Subject.CommonName, nil
+}
+
+// ClientSubject returns the common name of the verified client certificate
+// of a connection, if the client presented one.
+func ClientSubject(state tls.ConnectionState) (string, bool) {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return state.VerifiedChains[0][0].Subject.CommonName, true
+}
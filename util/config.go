@@ -1,11 +1,20 @@
 package util
 
 import (
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
+const (
+	// GatewayModeInProcess calls the gRPC handlers directly from the gateway.
+	GatewayModeInProcess = "in_process"
+	// GatewayModeProxy dials the gRPC server, so every gateway request goes
+	// through the interceptor chain.
+	GatewayModeProxy = "proxy"
+)
+
 type Config struct {
 	Environment              string        `mapstructure:"ENVIRONMENT"`
 	DBSource                 string        `mapstructure:"DB_SOURCE"`
@@ -14,18 +23,71 @@ type Config struct {
 	HTTPServerAddress        string        `mapstructure:"HTTP_SERVER_ADDRESS"`
 	HTTPGatewayServerAddress string        `mapstructure:"HTTP_GATEWAY_SERVER_ADDRESS"`
 	GRPCServerAddress        string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	MetricsServerAddress     string        `mapstructure:"METRICS_SERVER_ADDRESS"`
 	TokenSymmetricKey        string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	AccessTokenDuration      time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration     time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
 	EmailSenderName          string        `mapstructure:"EMAIL_SENDER_NAME"`
 	EmailSenderAddress       string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
 	EmailSenderPassword      string        `mapstructure:"EMAIL_SENDER_PASSWORD"`
+	PasswordResetURL         string        `mapstructure:"PASSWORD_RESET_URL"`
+	TOTPIssuer               string        `mapstructure:"TOTP_ISSUER"`
+	LoginChallengeDuration   time.Duration `mapstructure:"LOGIN_CHALLENGE_DURATION"`
+	TransferStepUpAmount     int64         `mapstructure:"TRANSFER_STEP_UP_AMOUNT"`
+	RequireVerifiedEmail     bool          `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	LoginMaxAttempts         int32         `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginAttemptWindow       time.Duration `mapstructure:"LOGIN_ATTEMPT_WINDOW"`
+	LoginLockoutDuration     time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	PasswordHashAlgorithm    string        `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	BcryptCost               int           `mapstructure:"BCRYPT_COST"`
+	Argon2Memory             uint32        `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations         uint32        `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism        uint8         `mapstructure:"ARGON2_PARALLELISM"`
+	Argon2MemoryBudget       uint32        `mapstructure:"ARGON2_MEMORY_BUDGET"`
+	ShutdownTimeout          time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	OTelExporter             string        `mapstructure:"OTEL_EXPORTER"`
+	OTelExporterOTLPEndpoint string        `mapstructure:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTelExporterOTLPInsecure bool          `mapstructure:"OTEL_EXPORTER_OTLP_INSECURE"`
+	OTelServiceName          string        `mapstructure:"OTEL_SERVICE_NAME"`
+	OTelSampleRatio          float64       `mapstructure:"OTEL_SAMPLE_RATIO"`
+	RateLimitBackend         string        `mapstructure:"RATE_LIMIT_BACKEND"`
+	TaskQueueBackend         string        `mapstructure:"TASK_QUEUE_BACKEND"`
+	StoreBackend             string        `mapstructure:"STORE_BACKEND"`
+	TLSCertFile              string        `mapstructure:"TLS_CERT_FILE"`
+	TLSKeyFile               string        `mapstructure:"TLS_KEY_FILE"`
+	TLSClientCAFile          string        `mapstructure:"TLS_CLIENT_CA_FILE"`
+	TLSClientAuth            string        `mapstructure:"TLS_CLIENT_AUTH"`
+	TLSClientIdentities      string        `mapstructure:"TLS_CLIENT_IDENTITIES"`
+	GatewayMode              string        `mapstructure:"GATEWAY_MODE"`
+	GatewayServeGrpc         bool          `mapstructure:"GATEWAY_SERVE_GRPC"`
+	TrustedProxies           string        `mapstructure:"TRUSTED_PROXIES"`
 }
 
 func (c Config) IsEnvProduction() bool {
 	return c.Environment == "production"
 }
 
+// IsTLSEnabled reports whether the gRPC server and gateway serve TLS.
+func (c Config) IsTLSEnabled() bool {
+	return c.TLSCertFile != ""
+}
+
+// TrustedProxyList returns the comma separated TrustedProxies, the addresses
+// or CIDRs whose X-Forwarded-For the REST API believes.
+func (c Config) TrustedProxyList() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+func (c Config) IsGatewayProxy() bool {
+	return c.GatewayMode == GatewayModeProxy
+}
+
 func LoadConfig(path string) (config Config, err error) {
 	viper.SetConfigFile(path)
 	viper.SetConfigType("env")
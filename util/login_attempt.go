@@ -0,0 +1,29 @@
+package util
+
+import "time"
+
+const maxLoginLockoutDuration = 24 * time.Hour
+
+func LoginAttemptUserKey(username string) string {
+	return "user:" + username
+}
+
+func LoginAttemptIPKey(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+// LoginLockoutDuration returns how long a key stays locked after failedCount
+// failures, doubling the base duration for every failure past maxAttempts.
+func LoginLockoutDuration(failedCount, maxAttempts int32, base time.Duration) time.Duration {
+	if maxAttempts <= 0 || failedCount < maxAttempts {
+		return 0
+	}
+	duration := base
+	for i := maxAttempts; i < failedCount; i++ {
+		duration *= 2
+		if duration >= maxLoginLockoutDuration {
+			return maxLoginLockoutDuration
+		}
+	}
+	return duration
+}
@@ -0,0 +1,25 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginLockoutDuration(t *testing.T) {
+	base := time.Minute
+
+	require.Zero(t, LoginLockoutDuration(0, 5, base))
+	require.Zero(t, LoginLockoutDuration(4, 5, base))
+	require.Equal(t, base, LoginLockoutDuration(5, 5, base))
+	require.Equal(t, 2*base, LoginLockoutDuration(6, 5, base))
+	require.Equal(t, 8*base, LoginLockoutDuration(8, 5, base))
+	require.Equal(t, maxLoginLockoutDuration, LoginLockoutDuration(100, 5, base))
+	require.Zero(t, LoginLockoutDuration(100, 0, base))
+}
+
+func TestLoginAttemptKey(t *testing.T) {
+	require.Equal(t, "user:alice", LoginAttemptUserKey("alice"))
+	require.Equal(t, "ip:127.0.0.1", LoginAttemptIPKey("127.0.0.1"))
+}
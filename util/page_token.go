@@ -0,0 +1,79 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// PageCursor is the (created_at, id) position of the last row on a page.
+type PageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+}
+
+type pageTokenPayload struct {
+	Scope string `json:"s"`
+	PageCursor
+}
+
+// PageTokenMaker signs page cursors so clients can't forge or tamper with
+// them. The scope ties a token to the list it was issued for.
+type PageTokenMaker struct {
+	key []byte
+}
+
+func NewPageTokenMaker(secret string) *PageTokenMaker {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("page_token"))
+	return &PageTokenMaker{key: mac.Sum(nil)}
+}
+
+func (maker *PageTokenMaker) Encode(scope string, cursor PageCursor) (string, error) {
+	payload, err := json.Marshal(pageTokenPayload{Scope: scope, PageCursor: cursor})
+	if err != nil {
+		return "", err
+	}
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(maker.sign(payload)), nil
+}
+
+func (maker *PageTokenMaker) Decode(scope string, token string) (PageCursor, error) {
+	encoding := base64.RawURLEncoding
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return PageCursor{}, ErrInvalidPageToken
+	}
+	payload, err := encoding.DecodeString(encodedPayload)
+	if err != nil {
+		return PageCursor{}, ErrInvalidPageToken
+	}
+	signature, err := encoding.DecodeString(encodedSignature)
+	if err != nil {
+		return PageCursor{}, ErrInvalidPageToken
+	}
+	if !hmac.Equal(signature, maker.sign(payload)) {
+		return PageCursor{}, ErrInvalidPageToken
+	}
+
+	var decoded pageTokenPayload
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return PageCursor{}, ErrInvalidPageToken
+	}
+	if decoded.Scope != scope {
+		return PageCursor{}, ErrInvalidPageToken
+	}
+	return decoded.PageCursor, nil
+}
+
+func (maker *PageTokenMaker) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, maker.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
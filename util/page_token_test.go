@@ -0,0 +1,49 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageToken(t *testing.T) {
+	maker := NewPageTokenMaker(RandomString(32))
+	cursor := PageCursor{
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		ID:        RandomInt(1, 1000),
+	}
+
+	token, err := maker.Encode("accounts:alice", cursor)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	decoded, err := maker.Decode("accounts:alice", token)
+	require.NoError(t, err)
+	require.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	require.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestPageTokenInvalid(t *testing.T) {
+	maker := NewPageTokenMaker(RandomString(32))
+	cursor := PageCursor{CreatedAt: time.Now(), ID: 1}
+
+	token, err := maker.Encode("accounts:alice", cursor)
+	require.NoError(t, err)
+
+	_, err = maker.Decode("accounts:bob", token)
+	require.ErrorIs(t, err, ErrInvalidPageToken)
+
+	otherMaker := NewPageTokenMaker(RandomString(32))
+	_, err = otherMaker.Decode("accounts:alice", token)
+	require.ErrorIs(t, err, ErrInvalidPageToken)
+
+	tampered := "x" + token[1:]
+	_, err = maker.Decode("accounts:alice", tampered)
+	require.ErrorIs(t, err, ErrInvalidPageToken)
+
+	for _, token := range []string{"", "abc", "abc.def", "..."} {
+		_, err = maker.Decode("accounts:alice", token)
+		require.ErrorIs(t, err, ErrInvalidPageToken)
+	}
+}
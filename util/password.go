@@ -1,11 +1,217 @@
 package util
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/sync/semaphore"
 )
 
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+
+	argon2idPrefix = "$argon2id$"
+)
+
+var (
+	ErrMismatchedPassword      = errors.New("password does not match hashed password")
+	ErrInvalidPasswordHash     = errors.New("hashed password has invalid format")
+	ErrUnsupportedPasswordHash = errors.New("unsupported password hash algorithm")
+)
+
+// PasswordHasher hashes new passwords and checks existing hashes in any
+// supported format, reporting when a stored hash should be upgraded.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Check(password string, hashedPassword string) error
+	NeedsRehash(hashedPassword string) bool
+}
+
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams is the OWASP minimum for argon2id. Every concurrent
+// hash allocates Memory KiB, so raising it needs more memory for the pod.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// DefaultArgon2MemoryBudget caps the memory of concurrent argon2id hashes,
+// in KiB. It lets two hashes with the default params run at once, which
+// fits the 64Mi limit of the api pod.
+const DefaultArgon2MemoryBudget = 2 * 19 * 1024
+
+// NewPasswordHasher builds the hasher selected by config, defaulting to
+// bcrypt so existing deployments keep their behaviour.
+func NewPasswordHasher(config Config) (PasswordHasher, error) {
+	switch config.PasswordHashAlgorithm {
+	case "", PasswordHashBcrypt:
+		cost := config.BcryptCost
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+		return NewBcryptHasher(cost)
+	case PasswordHashArgon2id:
+		params := DefaultArgon2idParams
+		if config.Argon2Memory > 0 {
+			params.Memory = config.Argon2Memory
+		}
+		if config.Argon2Iterations > 0 {
+			params.Iterations = config.Argon2Iterations
+		}
+		if config.Argon2Parallelism > 0 {
+			params.Parallelism = config.Argon2Parallelism
+		}
+		memoryBudget := uint32(DefaultArgon2MemoryBudget)
+		if config.Argon2MemoryBudget > 0 {
+			memoryBudget = config.Argon2MemoryBudget
+		}
+		return newArgon2idHasher(params, memoryBudget), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedPasswordHash, config.PasswordHashAlgorithm)
+}
+
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) (PasswordHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost %d", cost)
+	}
+	return &BcryptHasher{cost: cost}, nil
+}
+
+func (hasher *BcryptHasher) Hash(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), hasher.cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hashedPassword), nil
+}
+
+func (hasher *BcryptHasher) Check(password string, hashedPassword string) error {
+	return CheckPassword(password, hashedPassword)
+}
+
+func (hasher *BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	if err != nil {
+		return true
+	}
+	return cost != hasher.cost
+}
+
+// Argon2idHasher waits for room in its memory budget before every hash, so
+// concurrent logins can't allocate more than the budget between them.
+type Argon2idHasher struct {
+	params       Argon2idParams
+	memory       *semaphore.Weighted
+	memoryBudget uint32
+}
+
+// NewArgon2idHasher limits hashes to DefaultArgon2MemoryBudget.
+func NewArgon2idHasher(params Argon2idParams) PasswordHasher {
+	return newArgon2idHasher(params, DefaultArgon2MemoryBudget)
+}
+
+func newArgon2idHasher(params Argon2idParams, memoryBudget uint32) *Argon2idHasher {
+	return &Argon2idHasher{
+		params:       params,
+		memory:       argon2MemoryBudget(memoryBudget),
+		memoryBudget: memoryBudget,
+	}
+}
+
+var (
+	argon2MemoryBudgetsMu sync.Mutex
+	argon2MemoryBudgets   = map[uint32]*semaphore.Weighted{}
+)
+
+// argon2MemoryBudget returns the semaphore of budget KiB, shared by every
+// hasher of the process, since the gRPC and REST servers build their own.
+func argon2MemoryBudget(budget uint32) *semaphore.Weighted {
+	argon2MemoryBudgetsMu.Lock()
+	defer argon2MemoryBudgetsMu.Unlock()
+
+	memory, ok := argon2MemoryBudgets[budget]
+	if !ok {
+		memory = semaphore.NewWeighted(int64(budget))
+		argon2MemoryBudgets[budget] = memory
+	}
+	return memory
+}
+
+// reserve blocks until memory KiB of the budget are free and returns the
+// function giving them back. A hash larger than the whole budget runs alone.
+func (hasher *Argon2idHasher) reserve(memory uint32) func() {
+	if memory > hasher.memoryBudget {
+		memory = hasher.memoryBudget
+	}
+	// the background context never cancels, so Acquire can't fail
+	_ = hasher.memory.Acquire(context.Background(), int64(memory))
+	return func() {
+		hasher.memory.Release(int64(memory))
+	}
+}
+
+func (hasher *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, hasher.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	p := hasher.params
+	release := hasher.reserve(p.Memory)
+	defer release()
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (hasher *Argon2idHasher) Check(password string, hashedPassword string) error {
+	if params, _, _, err := decodeArgon2idHash(hashedPassword); err == nil {
+		release := hasher.reserve(params.Memory)
+		defer release()
+	}
+	return CheckPassword(password, hashedPassword)
+}
+
+func (hasher *Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	params, salt, key, err := decodeArgon2idHash(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return params.Memory != hasher.params.Memory ||
+		params.Iterations != hasher.params.Iterations ||
+		params.Parallelism != hasher.params.Parallelism ||
+		uint32(len(salt)) != hasher.params.SaltLength ||
+		uint32(len(key)) != hasher.params.KeyLength
+}
+
+// HashPassword hashes with bcrypt at the default cost.
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -14,6 +220,51 @@ func HashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
+// CheckPassword verifies a password against a bcrypt or argon2id hash.
 func CheckPassword(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if !strings.HasPrefix(hashedPassword, argon2idPrefix) {
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	}
+
+	params, salt, key, err := decodeArgon2idHash(hashedPassword)
+	if err != nil {
+		return err
+	}
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+func decodeArgon2idHash(hashedPassword string) (params Argon2idParams, salt, key []byte, err error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: argon2 version %d", ErrUnsupportedPasswordHash, version)
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
 }
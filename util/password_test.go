@@ -1,7 +1,9 @@
 package util
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
@@ -24,3 +26,117 @@ func TestPassword(t *testing.T) {
 	require.NotEmpty(t, differentHashedPassword)
 	require.NotEqual(t, hashedPassword, differentHashedPassword)
 }
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := NewArgon2idHasher(Argon2idParams{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+
+	password := RandomString(8)
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	require.NoError(t, hasher.Check(password, hashedPassword))
+	require.NoError(t, CheckPassword(password, hashedPassword))
+	require.ErrorIs(t, hasher.Check(RandomString(6), hashedPassword), ErrMismatchedPassword)
+	require.ErrorIs(t, hasher.Check(password, "$argon2id$invalid"), ErrInvalidPasswordHash)
+
+	differentHashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.NotEqual(t, hashedPassword, differentHashedPassword)
+
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	stronger := NewArgon2idHasher(Argon2idParams{
+		Memory:      2048,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	require.True(t, stronger.NeedsRehash(hashedPassword))
+
+	bcryptHashedPassword, err := HashPassword(password)
+	require.NoError(t, err)
+	require.NoError(t, hasher.Check(password, bcryptHashedPassword))
+	require.True(t, hasher.NeedsRehash(bcryptHashedPassword))
+}
+
+func TestArgon2idHasherMemoryBudget(t *testing.T) {
+	params := Argon2idParams{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+	hasher := newArgon2idHasher(params, 1024)
+	// hashers of the same budget share it
+	other := newArgon2idHasher(params, 1024)
+	require.Same(t, hasher.memory, other.memory)
+
+	release := other.reserve(params.Memory)
+	hashed := make(chan error)
+	go func() {
+		_, err := hasher.Hash(RandomString(8))
+		hashed <- err
+	}()
+
+	select {
+	case <-hashed:
+		t.Fatal("hash ran beyond the memory budget")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case err := <-hashed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("hash did not run once the budget was free")
+	}
+
+	// a hash larger than the budget still runs, alone
+	release = newArgon2idHasher(params, 512).reserve(params.Memory)
+	release()
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+
+	password := RandomString(8)
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.NoError(t, hasher.Check(password, hashedPassword))
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	defaultHashedPassword, err := HashPassword(password)
+	require.NoError(t, err)
+	require.True(t, hasher.NeedsRehash(defaultHashedPassword))
+
+	argon2idHashedPassword, err := NewArgon2idHasher(DefaultArgon2idParams).Hash(password)
+	require.NoError(t, err)
+	require.NoError(t, hasher.Check(password, argon2idHashedPassword))
+	require.True(t, hasher.NeedsRehash(argon2idHashedPassword))
+
+	_, err = NewBcryptHasher(bcrypt.MaxCost + 1)
+	require.Error(t, err)
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(Config{})
+	require.NoError(t, err)
+	require.IsType(t, &BcryptHasher{}, hasher)
+
+	hasher, err = NewPasswordHasher(Config{PasswordHashAlgorithm: PasswordHashArgon2id, Argon2Memory: 1024})
+	require.NoError(t, err)
+	require.IsType(t, &Argon2idHasher{}, hasher)
+
+	_, err = NewPasswordHasher(Config{PasswordHashAlgorithm: "md5"})
+	require.ErrorIs(t, err, ErrUnsupportedPasswordHash)
+}
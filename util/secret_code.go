@@ -0,0 +1,19 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+const secretCodeBytes = 32
+
+// GenerateSecretCode returns a random code for links sent by email, such
+// as password resets, which must not be guessable.
+func GenerateSecretCode() (string, error) {
+	code := make([]byte, secretCodeBytes)
+	if _, err := rand.Read(code); err != nil {
+		return "", fmt.Errorf("failed to generate secret code: %w", err)
+	}
+	return hex.EncodeToString(code), nil
+}
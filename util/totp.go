@@ -0,0 +1,84 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	DefaultTOTPIssuer  = "Simplebank"
+	RecoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	recoveryCodeChars  = "abcdefghjkmnpqrstuvwxyz23456789"
+	totpPeriod         = 30
+)
+
+// GenerateTOTP creates a new TOTP secret for the account and returns it
+// with the otpauth:// URI that authenticator apps can import.
+func GenerateTOTP(issuer, accountName string) (secret string, url string, err error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: accountName,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate totp key: %w", err)
+	}
+	return key.Secret(), key.URL(), nil
+}
+
+// CheckTOTP validates code against the current time step and the ones next to
+// it, like totp.Validate, and returns the time step it matched. Callers store
+// the step and reject codes at or below it so a code can't be replayed.
+func CheckTOTP(code, secret string) (int64, bool) {
+	return checkTOTPAt(code, secret, time.Now())
+}
+
+func checkTOTPAt(code, secret string, t time.Time) (int64, bool) {
+	opts := totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+	current := t.Unix() / totpPeriod
+	for step := current - 1; step <= current+1; step++ {
+		valid, err := totp.ValidateCustom(code, secret, time.Unix(step*totpPeriod, 0).UTC(), opts)
+		if err == nil && valid {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns n single-use codes formatted as xxxxx-xxxxx.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		var sb strings.Builder
+		for j := 0; j < recoveryCodeLength; j++ {
+			if j == recoveryCodeLength/2 {
+				sb.WriteByte('-')
+			}
+			idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeChars))))
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+			}
+			sb.WriteByte(recoveryCodeChars[idx.Int64()])
+		}
+		codes = append(codes, sb.String())
+	}
+	return codes, nil
+}
+
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.TrimSpace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
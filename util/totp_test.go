@@ -0,0 +1,56 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTP(t *testing.T) {
+	accountName := RandomOwner()
+	secret, url, err := GenerateTOTP(DefaultTOTPIssuer, accountName)
+	require.NoError(t, err)
+	require.NotEmpty(t, secret)
+	require.Contains(t, url, "otpauth://totp/")
+	require.Contains(t, url, accountName)
+	require.Contains(t, url, secret)
+
+	now := time.Now()
+	code, err := totp.GenerateCode(secret, now)
+	require.NoError(t, err)
+	step, ok := checkTOTPAt(code, secret, now)
+	require.True(t, ok)
+	require.Equal(t, now.Unix()/totpPeriod, step)
+
+	step, ok = checkTOTPAt(code, secret, now.Add(totpPeriod*time.Second))
+	require.True(t, ok)
+	require.Equal(t, now.Unix()/totpPeriod, step)
+
+	_, ok = checkTOTPAt(code, secret, now.Add(2*totpPeriod*time.Second))
+	require.False(t, ok)
+
+	otherSecret, _, err := GenerateTOTP(DefaultTOTPIssuer, accountName)
+	require.NoError(t, err)
+	_, ok = CheckTOTP(code, otherSecret)
+	require.False(t, ok)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+
+	existed := make(map[string]bool)
+	for _, code := range codes {
+		require.Len(t, code, recoveryCodeLength+1)
+		require.NoError(t, ValidateRecoveryCode(code))
+		require.NotContains(t, existed, code)
+		existed[code] = true
+
+		hashed := HashRecoveryCode(code)
+		require.Len(t, hashed, 64)
+		require.Equal(t, hashed, HashRecoveryCode(" "+code+" "))
+	}
+}
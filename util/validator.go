@@ -9,6 +9,7 @@ import (
 var (
 	isValidUsername  = regexp.MustCompile(`^[a-z0-9_]+$`).MatchString
 	isValidUFullName = regexp.MustCompile(`^[a-zA-Z\s]+$`).MatchString
+	isValidTOTPCode  = regexp.MustCompile(`^[0-9]{6}$`).MatchString
 )
 
 func ValidateString(value string, min, max int) error {
@@ -77,3 +78,40 @@ func ValidateCurrency(currency string) error {
 func ValidateSecretCode(value string) error {
 	return ValidateString(value, 32, 128)
 }
+
+func ValidateTOTPCode(value string) error {
+	if !isValidTOTPCode(value) {
+		return fmt.Errorf("must contain exactly 6 digits")
+	}
+	return nil
+}
+
+func ValidateRecoveryCode(value string) error {
+	return ValidateString(value, 11, 11)
+}
+
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("must contain at least one scope")
+	}
+	for _, scope := range scopes {
+		if !IsSupportedScope(scope) {
+			return fmt.Errorf("unsupported scope %q", scope)
+		}
+	}
+	return nil
+}
+
+func ValidateAccountStatus(status string) error {
+	if !IsSupportedAccountStatus(status) {
+		return ErrInvalidAccountStatus
+	}
+	return nil
+}
+
+func ValidateDirection(direction string) error {
+	if !IsSupportedDirection(direction) {
+		return fmt.Errorf("must be either %q or %q", DirectionIn, DirectionOut)
+	}
+	return nil
+}
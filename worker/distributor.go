@@ -6,6 +6,12 @@ import (
 	"github.com/hibiken/asynq"
 )
 
+// Backends of the task queue.
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
 //go:generate go run go.uber.org/mock/mockgen -source=distributor.go -destination=./mock/distributor.go
 type TaskDistributor interface {
 	DistributeTaskVerifyEmail(
@@ -13,15 +19,31 @@ type TaskDistributor interface {
 		payload *PayloadSendVerifyEmail,
 		opts ...asynq.Option,
 	) error
+	DistributeTaskSendResetPassword(
+		ctx context.Context,
+		payload *PayloadSendResetPassword,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskSendLockoutEmail(
+		ctx context.Context,
+		payload *PayloadSendLockoutEmail,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskSendEmailChangeNotice(
+		ctx context.Context,
+		payload *PayloadSendEmailChangeNotice,
+		opts ...asynq.Option,
+	) error
 }
 
-type RedisTaskDistributor struct {
-	client *asynq.Client
+// AsynqTaskDistributor enqueues asynq tasks to Redis or to an InMemoryQueue.
+type AsynqTaskDistributor struct {
+	client taskEnqueuer
 }
 
 func NewRedisTaskDistributor(redisOpt asynq.RedisClientOpt) TaskDistributor {
 	client := asynq.NewClient(redisOpt)
-	return &RedisTaskDistributor{
+	return &AsynqTaskDistributor{
 		client: client,
 	}
 }
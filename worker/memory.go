@@ -0,0 +1,295 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/mail"
+)
+
+const (
+	// defaultMaxRetry is asynq's retry limit of tasks enqueued without MaxRetry.
+	defaultMaxRetry = 25
+	// pollInterval bounds how late a scheduled or retried task runs.
+	pollInterval = time.Second
+)
+
+// InMemoryQueue keeps tasks in process memory, for local runs without Redis
+// and for tests. It honors the MaxRetry, ProcessIn, ProcessAt, Queue and
+// TaskID options like asynq does. Completed and archived tasks are dropped
+// unless the queue keeps a history. Queued tasks are lost on shutdown.
+type InMemoryQueue struct {
+	mu sync.Mutex
+	// tasks are the waiting and active tasks, oldest first.
+	tasks []*queuedTask
+	// history holds the last historySize finished tasks, oldest first.
+	history     []*queuedTask
+	historySize int
+	wake        chan struct{}
+	now         func() time.Time
+}
+
+type queuedTask struct {
+	task *asynq.Task
+	info asynq.TaskInfo
+}
+
+// taskEnqueuer is the part of *asynq.Client the distributor uses, so that an
+// InMemoryQueue can stand in for Redis.
+type taskEnqueuer interface {
+	EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+}
+
+func NewInMemoryQueue() *InMemoryQueue {
+	return NewInMemoryQueueWithHistory(0)
+}
+
+// NewInMemoryQueueWithHistory keeps the last size finished tasks, so that
+// tests can assert on how they ended.
+func NewInMemoryQueueWithHistory(size int) *InMemoryQueue {
+	return &InMemoryQueue{
+		historySize: size,
+		wake:        make(chan struct{}, 1),
+		now:         time.Now,
+	}
+}
+
+func NewInMemoryTaskDistributor(queue *InMemoryQueue) TaskDistributor {
+	return &AsynqTaskDistributor{
+		client: queue,
+	}
+}
+
+// EnqueueContext queues task like (*asynq.Client).EnqueueContext does.
+func (queue *InMemoryQueue) EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	now := queue.now()
+	info := asynq.TaskInfo{
+		ID:            uuid.NewString(),
+		Queue:         QueueDefault,
+		Type:          task.Type(),
+		Payload:       task.Payload(),
+		State:         asynq.TaskStatePending,
+		MaxRetry:      defaultMaxRetry,
+		NextProcessAt: now,
+	}
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.MaxRetryOpt:
+			info.MaxRetry = opt.Value().(int)
+			if info.MaxRetry < 0 {
+				info.MaxRetry = 0
+			}
+		case asynq.QueueOpt:
+			info.Queue = opt.Value().(string)
+		case asynq.TaskIDOpt:
+			info.ID = opt.Value().(string)
+		case asynq.ProcessAtOpt:
+			info.NextProcessAt = opt.Value().(time.Time)
+		case asynq.ProcessInOpt:
+			info.NextProcessAt = now.Add(opt.Value().(time.Duration))
+		}
+	}
+	if info.NextProcessAt.After(now) {
+		info.State = asynq.TaskStateScheduled
+	}
+	// finished tasks are gone, so their ids can be reused like asynq allows
+	// for completed tasks
+	for _, queued := range queue.tasks {
+		if queued.info.ID == info.ID {
+			return nil, asynq.ErrTaskIDConflict
+		}
+	}
+
+	queue.tasks = append(queue.tasks, &queuedTask{task: task, info: info})
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+	return &info, nil
+}
+
+// Tasks returns the finished tasks the queue keeps followed by the ones it
+// still holds, each oldest first, with their payload as the distributor
+// marshalled it.
+func (queue *InMemoryQueue) Tasks() []*asynq.TaskInfo {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	tasks := make([]*asynq.TaskInfo, 0, len(queue.history)+len(queue.tasks))
+	for _, list := range [][]*queuedTask{queue.history, queue.tasks} {
+		for _, queued := range list {
+			info := queued.info
+			_, task := unwrapTaskPayload(context.Background(), queued.task)
+			info.Payload = task.Payload()
+			tasks = append(tasks, &info)
+		}
+	}
+	return tasks
+}
+
+// next marks the due task of the highest priority queue active and returns
+// it, or nil when no task is due. Unlike asynq, which only favors the
+// higher priority queues, it never runs a task while one of a higher
+// priority queue is due.
+func (queue *InMemoryQueue) next(priorities map[string]int) *queuedTask {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	now := queue.now()
+	var found *queuedTask
+	for _, queued := range queue.tasks {
+		info := queued.info
+		if !isWaiting(info.State) || info.NextProcessAt.After(now) {
+			continue
+		}
+		priority, ok := priorities[info.Queue]
+		if !ok {
+			continue
+		}
+		if found == nil || priority > priorities[found.info.Queue] ||
+			(priority == priorities[found.info.Queue] && info.NextProcessAt.Before(found.info.NextProcessAt)) {
+			found = queued
+		}
+	}
+	if found != nil {
+		found.info.State = asynq.TaskStateActive
+	}
+	return found
+}
+
+func isWaiting(state asynq.TaskState) bool {
+	return state == asynq.TaskStatePending || state == asynq.TaskStateScheduled || state == asynq.TaskStateRetry
+}
+
+// finish records the outcome of an active task. A failed task is retried
+// after retryDelay until it runs out of retries or fails with
+// asynq.SkipRetry, and is then archived. Completed and archived tasks leave
+// the queue.
+func (queue *InMemoryQueue) finish(queued *queuedTask, err error, retryDelay asynq.RetryDelayFunc) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	now := queue.now()
+	info := &queued.info
+	if err == nil {
+		info.State = asynq.TaskStateCompleted
+		info.CompletedAt = now
+		queue.remove(queued)
+		return
+	}
+
+	info.LastErr = err.Error()
+	info.LastFailedAt = now
+	if info.Retried >= info.MaxRetry || errors.Is(err, asynq.SkipRetry) {
+		info.State = asynq.TaskStateArchived
+		queue.remove(queued)
+		return
+	}
+	info.State = asynq.TaskStateRetry
+	info.NextProcessAt = now.Add(retryDelay(info.Retried, err, queued.task))
+	info.Retried++
+}
+
+// remove moves a finished task from the queue into the history, dropping
+// the oldest one when the history is full.
+func (queue *InMemoryQueue) remove(finished *queuedTask) {
+	for i, queued := range queue.tasks {
+		if queued == finished {
+			queue.tasks = append(queue.tasks[:i], queue.tasks[i+1:]...)
+			break
+		}
+	}
+	if queue.historySize <= 0 {
+		return
+	}
+	queue.history = append(queue.history, finished)
+	if len(queue.history) > queue.historySize {
+		queue.history = queue.history[len(queue.history)-queue.historySize:]
+	}
+}
+
+// InMemoryTaskProcessor runs the tasks of an InMemoryQueue one at a time.
+type InMemoryTaskProcessor struct {
+	taskHandlers
+	queue        *InMemoryQueue
+	retryDelay   asynq.RetryDelayFunc
+	pollInterval time.Duration
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+func NewInMemoryTaskProcessor(queue *InMemoryQueue, store db.Store, mailer mail.EmailSender, passwordResetURL string) TaskProcessor {
+	return &InMemoryTaskProcessor{
+		taskHandlers: taskHandlers{
+			store:            store,
+			mailer:           mailer,
+			passwordResetURL: passwordResetURL,
+		},
+		queue:        queue,
+		retryDelay:   asynq.DefaultRetryDelayFunc,
+		pollInterval: pollInterval,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+func (processor *InMemoryTaskProcessor) Start() error {
+	go processor.run(processor.newServeMux())
+	return nil
+}
+
+// Shutdown stops pulling new tasks and waits for the active one to finish.
+func (processor *InMemoryTaskProcessor) Shutdown() {
+	close(processor.stop)
+	<-processor.done
+}
+
+func (processor *InMemoryTaskProcessor) run(handler asynq.Handler) {
+	defer close(processor.done)
+
+	ticker := time.NewTicker(processor.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for queued := processor.queue.next(queuePriorities); queued != nil; queued = processor.queue.next(queuePriorities) {
+			ctx := context.Background()
+			err := processTask(ctx, handler, queued.task)
+			if err != nil {
+				logTaskFailure(ctx, queued.task, err)
+			}
+			processor.queue.finish(queued, err, processor.retryDelay)
+
+			select {
+			case <-processor.stop:
+				return
+			default:
+			}
+		}
+
+		select {
+		case <-processor.stop:
+			return
+		case <-processor.queue.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processTask turns a panicking handler into a failed task, like asynq.
+func processTask(ctx context.Context, handler asynq.Handler, task *asynq.Task) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("panic: %v", x)
+		}
+	}()
+	return handler.ProcessTask(ctx, task)
+}
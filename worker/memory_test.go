@@ -0,0 +1,189 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/labasubagia/simplebank/db/memstore"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	mock_mail "github.com/labasubagia/simplebank/mail/mock"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestQueue() (*InMemoryQueue, *time.Time) {
+	now := time.Now()
+	queue := NewInMemoryQueue()
+	queue.now = func() time.Time { return now }
+	return queue, &now
+}
+
+func TestInMemoryQueueOptions(t *testing.T) {
+	queue, now := newTestQueue()
+	distributor := NewInMemoryTaskDistributor(queue)
+
+	payload := &PayloadSendVerifyEmail{Username: util.RandomOwner()}
+	err := distributor.DistributeTaskVerifyEmail(context.Background(), payload,
+		asynq.MaxRetry(10),
+		asynq.ProcessIn(10*time.Second),
+		asynq.Queue(QueueCritical),
+	)
+	require.NoError(t, err)
+	err = distributor.DistributeTaskSendLockoutEmail(context.Background(), &PayloadSendLockoutEmail{})
+	require.NoError(t, err)
+
+	tasks := queue.Tasks()
+	require.Len(t, tasks, 2)
+
+	require.Equal(t, TaskSendVerifyEmail, tasks[0].Type)
+	require.Equal(t, QueueCritical, tasks[0].Queue)
+	require.Equal(t, 10, tasks[0].MaxRetry)
+	require.Equal(t, asynq.TaskStateScheduled, tasks[0].State)
+	require.Equal(t, now.Add(10*time.Second), tasks[0].NextProcessAt)
+	expectedPayload, err := json.Marshal(payload)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedPayload), string(tasks[0].Payload))
+
+	require.Equal(t, QueueDefault, tasks[1].Queue)
+	require.Equal(t, defaultMaxRetry, tasks[1].MaxRetry)
+	require.Equal(t, asynq.TaskStatePending, tasks[1].State)
+
+	task := asynq.NewTask(TaskSendLockoutEmail, nil)
+	_, err = queue.EnqueueContext(context.Background(), task, asynq.TaskID("lockout"))
+	require.NoError(t, err)
+	_, err = queue.EnqueueContext(context.Background(), task, asynq.TaskID("lockout"))
+	require.ErrorIs(t, err, asynq.ErrTaskIDConflict)
+}
+
+func TestInMemoryQueuePriority(t *testing.T) {
+	queue, now := newTestQueue()
+	enqueue := func(opts ...asynq.Option) *asynq.TaskInfo {
+		info, err := queue.EnqueueContext(context.Background(), asynq.NewTask(TaskSendLockoutEmail, nil), opts...)
+		require.NoError(t, err)
+		return info
+	}
+
+	defaultTask := enqueue(asynq.Queue(QueueDefault))
+	scheduledTask := enqueue(asynq.Queue(QueueCritical), asynq.ProcessIn(time.Minute))
+	criticalTask := enqueue(asynq.Queue(QueueCritical))
+	enqueue(asynq.Queue("unknown"))
+
+	require.Equal(t, criticalTask.ID, queue.next(queuePriorities).info.ID)
+	require.Equal(t, defaultTask.ID, queue.next(queuePriorities).info.ID)
+	require.Nil(t, queue.next(queuePriorities))
+
+	*now = now.Add(time.Minute)
+	require.Equal(t, scheduledTask.ID, queue.next(queuePriorities).info.ID)
+	require.Nil(t, queue.next(queuePriorities))
+}
+
+func TestInMemoryQueueRetry(t *testing.T) {
+	queue, now := newTestQueue()
+	retryDelay := func(n int, err error, task *asynq.Task) time.Duration {
+		return time.Duration(n+1) * time.Minute
+	}
+	errFailed := errors.New("failed")
+
+	_, err := queue.EnqueueContext(context.Background(), asynq.NewTask(TaskSendLockoutEmail, nil), asynq.MaxRetry(1))
+	require.NoError(t, err)
+
+	queued := queue.next(queuePriorities)
+	queue.finish(queued, errFailed, retryDelay)
+	require.Equal(t, asynq.TaskStateRetry, queued.info.State)
+	require.Equal(t, 1, queued.info.Retried)
+	require.Equal(t, now.Add(time.Minute), queued.info.NextProcessAt)
+	require.Equal(t, errFailed.Error(), queued.info.LastErr)
+	require.Nil(t, queue.next(queuePriorities))
+
+	*now = now.Add(time.Minute)
+	queued = queue.next(queuePriorities)
+	require.NotNil(t, queued)
+	queue.finish(queued, errFailed, retryDelay)
+	require.Equal(t, asynq.TaskStateArchived, queued.info.State)
+
+	_, err = queue.EnqueueContext(context.Background(), asynq.NewTask(TaskSendLockoutEmail, nil))
+	require.NoError(t, err)
+	queued = queue.next(queuePriorities)
+	queue.finish(queued, fmt.Errorf("bad payload: %w", asynq.SkipRetry), retryDelay)
+	require.Equal(t, asynq.TaskStateArchived, queued.info.State)
+	require.Zero(t, queued.info.Retried)
+}
+
+func TestInMemoryQueueHistory(t *testing.T) {
+	enqueueAndFinish := func(queue *InMemoryQueue, id string, err error) {
+		_, enqueueErr := queue.EnqueueContext(context.Background(), asynq.NewTask(TaskSendLockoutEmail, nil), asynq.TaskID(id), asynq.MaxRetry(0))
+		require.NoError(t, enqueueErr)
+		queue.finish(queue.next(queuePriorities), err, asynq.DefaultRetryDelayFunc)
+	}
+
+	queue, _ := newTestQueue()
+	enqueueAndFinish(queue, "first", nil)
+	enqueueAndFinish(queue, "second", errors.New("failed"))
+	require.Empty(t, queue.tasks)
+	require.Empty(t, queue.Tasks())
+
+	// ids of finished tasks can be used again
+	enqueueAndFinish(queue, "first", nil)
+
+	queue = NewInMemoryQueueWithHistory(2)
+	enqueueAndFinish(queue, "first", nil)
+	enqueueAndFinish(queue, "second", errors.New("failed"))
+	enqueueAndFinish(queue, "third", nil)
+	_, err := queue.EnqueueContext(context.Background(), asynq.NewTask(TaskSendLockoutEmail, nil), asynq.TaskID("fourth"))
+	require.NoError(t, err)
+
+	tasks := queue.Tasks()
+	require.Len(t, tasks, 3)
+	require.Equal(t, "second", tasks[0].ID)
+	require.Equal(t, asynq.TaskStateArchived, tasks[0].State)
+	require.Equal(t, "third", tasks[1].ID)
+	require.Equal(t, asynq.TaskStateCompleted, tasks[1].State)
+	require.Equal(t, "fourth", tasks[2].ID)
+	require.Equal(t, asynq.TaskStatePending, tasks[2].State)
+}
+
+func TestInMemoryTaskProcessor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mailer := mock_mail.NewMockEmailSender(ctrl)
+
+	store := memstore.NewStore()
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: util.RandomString(32),
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	mailer.EXPECT().
+		SendEmail(gomock.Any(), gomock.Any(), []string{user.Email}, gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+
+	queue := NewInMemoryQueueWithHistory(1)
+	processor := NewInMemoryTaskProcessor(queue, store, mailer, "").(*InMemoryTaskProcessor)
+	processor.pollInterval = 10 * time.Millisecond
+	require.NoError(t, processor.Start())
+	defer processor.Shutdown()
+
+	err = NewInMemoryTaskDistributor(queue).DistributeTaskVerifyEmail(
+		context.Background(),
+		&PayloadSendVerifyEmail{Username: user.Username},
+		asynq.Queue(QueueCritical),
+	)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return queue.Tasks()[0].State == asynq.TaskStateCompleted
+	}, time.Second, 10*time.Millisecond)
+
+	verifyEmail, err := store.GetLatestVerifyEmail(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.Email, verifyEmail.Email)
+}
@@ -0,0 +1,19 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/labasubagia/simplebank/metrics"
+)
+
+// metricsMiddleware records the outcome and duration of every processed task.
+func metricsMiddleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		startTime := time.Now()
+		err := next.ProcessTask(ctx, task)
+		metrics.ObserveTask(task.Type(), err, time.Since(startTime))
+		return err
+	})
+}
@@ -36,6 +36,63 @@ func (m *MockTaskDistributor) EXPECT() *MockTaskDistributorMockRecorder {
 	return m.recorder
 }
 
+// DistributeTaskSendEmailChangeNotice mocks base method.
+func (m *MockTaskDistributor) DistributeTaskSendEmailChangeNotice(ctx context.Context, payload *worker.PayloadSendEmailChangeNotice, opts ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, payload}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendEmailChangeNotice", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendEmailChangeNotice indicates an expected call of DistributeTaskSendEmailChangeNotice.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendEmailChangeNotice(ctx, payload interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, payload}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendEmailChangeNotice", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendEmailChangeNotice), varargs...)
+}
+
+// DistributeTaskSendLockoutEmail mocks base method.
+func (m *MockTaskDistributor) DistributeTaskSendLockoutEmail(ctx context.Context, payload *worker.PayloadSendLockoutEmail, opts ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, payload}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendLockoutEmail", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendLockoutEmail indicates an expected call of DistributeTaskSendLockoutEmail.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendLockoutEmail(ctx, payload interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, payload}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendLockoutEmail", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendLockoutEmail), varargs...)
+}
+
+// DistributeTaskSendResetPassword mocks base method.
+func (m *MockTaskDistributor) DistributeTaskSendResetPassword(ctx context.Context, payload *worker.PayloadSendResetPassword, opts ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, payload}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendResetPassword", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendResetPassword indicates an expected call of DistributeTaskSendResetPassword.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendResetPassword(ctx, payload interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, payload}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendResetPassword", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendResetPassword), varargs...)
+}
+
 // DistributeTaskVerifyEmail mocks base method.
 func (m *MockTaskDistributor) DistributeTaskVerifyEmail(ctx context.Context, payload *worker.PayloadSendVerifyEmail, opts ...asynq.Option) error {
 	m.ctrl.T.Helper()
@@ -35,6 +35,48 @@ func (m *MockTaskProcessor) EXPECT() *MockTaskProcessorMockRecorder {
 	return m.recorder
 }
 
+// ProcessTaskSendEmailChangeNotice mocks base method.
+func (m *MockTaskProcessor) ProcessTaskSendEmailChangeNotice(ctx context.Context, task *asynq.Task) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessTaskSendEmailChangeNotice", ctx, task)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProcessTaskSendEmailChangeNotice indicates an expected call of ProcessTaskSendEmailChangeNotice.
+func (mr *MockTaskProcessorMockRecorder) ProcessTaskSendEmailChangeNotice(ctx, task interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessTaskSendEmailChangeNotice", reflect.TypeOf((*MockTaskProcessor)(nil).ProcessTaskSendEmailChangeNotice), ctx, task)
+}
+
+// ProcessTaskSendLockoutEmail mocks base method.
+func (m *MockTaskProcessor) ProcessTaskSendLockoutEmail(ctx context.Context, task *asynq.Task) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessTaskSendLockoutEmail", ctx, task)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProcessTaskSendLockoutEmail indicates an expected call of ProcessTaskSendLockoutEmail.
+func (mr *MockTaskProcessorMockRecorder) ProcessTaskSendLockoutEmail(ctx, task interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessTaskSendLockoutEmail", reflect.TypeOf((*MockTaskProcessor)(nil).ProcessTaskSendLockoutEmail), ctx, task)
+}
+
+// ProcessTaskSendResetPassword mocks base method.
+func (m *MockTaskProcessor) ProcessTaskSendResetPassword(ctx context.Context, task *asynq.Task) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessTaskSendResetPassword", ctx, task)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProcessTaskSendResetPassword indicates an expected call of ProcessTaskSendResetPassword.
+func (mr *MockTaskProcessorMockRecorder) ProcessTaskSendResetPassword(ctx, task interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessTaskSendResetPassword", reflect.TypeOf((*MockTaskProcessor)(nil).ProcessTaskSendResetPassword), ctx, task)
+}
+
 // ProcessTaskSendVerifyEmail mocks base method.
 func (m *MockTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error {
 	m.ctrl.T.Helper()
@@ -49,6 +91,18 @@ func (mr *MockTaskProcessorMockRecorder) ProcessTaskSendVerifyEmail(ctx, task in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessTaskSendVerifyEmail", reflect.TypeOf((*MockTaskProcessor)(nil).ProcessTaskSendVerifyEmail), ctx, task)
 }
 
+// Shutdown mocks base method.
+func (m *MockTaskProcessor) Shutdown() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Shutdown")
+}
+
+// Shutdown indicates an expected call of Shutdown.
+func (mr *MockTaskProcessorMockRecorder) Shutdown() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shutdown", reflect.TypeOf((*MockTaskProcessor)(nil).Shutdown))
+}
+
 // Start mocks base method.
 func (m *MockTaskProcessor) Start() error {
 	m.ctrl.T.Helper()
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"time"
 
 	"github.com/hibiken/asynq"
 	db "github.com/labasubagia/simplebank/db/sqlc"
@@ -18,40 +19,77 @@ const (
 //go:generate go run go.uber.org/mock/mockgen -source=processor.go -destination=./mock/processor.go
 type TaskProcessor interface {
 	Start() error
+	Shutdown()
 	ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendResetPassword(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendLockoutEmail(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendEmailChangeNotice(ctx context.Context, task *asynq.Task) error
 }
 
-type RedisTaskProcessor struct {
-	server *asynq.Server
+// queuePriorities weighs the queues a processor pulls tasks from. Tasks in
+// any other queue are never processed.
+var queuePriorities = map[string]int{
+	QueueCritical: 10,
+	QueueDefault:  5,
+}
+
+// taskHandlers processes every task type, whichever processor runs it.
+type taskHandlers struct {
 	store  db.Store
 	mailer mail.EmailSender
+	// passwordResetURL is the page reset password emails link to.
+	passwordResetURL string
+}
+
+// newServeMux routes every task type to its handler.
+func (processor *taskHandlers) newServeMux() *asynq.ServeMux {
+	mux := asynq.NewServeMux()
+	mux.Use(tracingMiddleware, metricsMiddleware)
+	mux.HandleFunc(TaskSendVerifyEmail, processor.ProcessTaskSendVerifyEmail)
+	mux.HandleFunc(TaskSendResetPassword, processor.ProcessTaskSendResetPassword)
+	mux.HandleFunc(TaskSendLockoutEmail, processor.ProcessTaskSendLockoutEmail)
+	mux.HandleFunc(TaskSendEmailChangeNotice, processor.ProcessTaskSendEmailChangeNotice)
+	return mux
+}
+
+// logTaskFailure is the error handler of every processor.
+func logTaskFailure(ctx context.Context, task *asynq.Task, err error) {
+	log.Error().Str("type", task.Type()).Bytes("payload", task.Payload()).Msg("process task failed")
 }
 
-func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, store db.Store, mailer mail.EmailSender) TaskProcessor {
+type RedisTaskProcessor struct {
+	taskHandlers
+	server *asynq.Server
+}
+
+func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, store db.Store, mailer mail.EmailSender, passwordResetURL string, shutdownTimeout time.Duration) TaskProcessor {
 	logger := NewLogger()
 	redis.SetLogger(logger)
 	server := asynq.NewServer(
 		redisOpt,
 		asynq.Config{
-			Queues: map[string]int{
-				QueueCritical: 10,
-				QueueDefault:  5,
-			},
-			ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
-				log.Error().Str("type", task.Type()).Bytes("payload", task.Payload()).Msg("process task failed")
-			}),
-			Logger: logger,
+			Queues:          queuePriorities,
+			ErrorHandler:    asynq.ErrorHandlerFunc(logTaskFailure),
+			Logger:          logger,
+			ShutdownTimeout: shutdownTimeout,
 		},
 	)
 	return &RedisTaskProcessor{
+		taskHandlers: taskHandlers{
+			store:            store,
+			mailer:           mailer,
+			passwordResetURL: passwordResetURL,
+		},
 		server: server,
-		store:  store,
-		mailer: mailer,
 	}
 }
 
 func (processor *RedisTaskProcessor) Start() error {
-	mux := asynq.NewServeMux()
-	mux.HandleFunc(TaskSendVerifyEmail, processor.ProcessTaskSendVerifyEmail)
-	return processor.server.Start(mux)
+	return processor.server.Start(processor.newServeMux())
+}
+
+// Shutdown stops pulling new tasks and waits for active ones to finish,
+// up to the configured shutdown timeout.
+func (processor *RedisTaskProcessor) Shutdown() {
+	processor.server.Shutdown()
 }
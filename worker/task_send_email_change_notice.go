@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskSendEmailChangeNotice = "task:send_email_change_notice"
+
+type PayloadSendEmailChangeNotice struct {
+	Username string `json:"username"`
+	OldEmail string `json:"old_email"`
+	NewEmail string `json:"new_email"`
+}
+
+func (distributor *AsynqTaskDistributor) DistributeTaskSendEmailChangeNotice(
+	ctx context.Context,
+	payload *PayloadSendEmailChangeNotice,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshall task payload: %w", err)
+	}
+
+	info, err := distributor.enqueueTask(ctx, TaskSendEmailChangeNotice, jsonPayload, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().
+		Str("type", TaskSendEmailChangeNotice).
+		Bytes("payload", jsonPayload).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("enqueued task")
+	return nil
+}
+
+func (processor *taskHandlers) ProcessTaskSendEmailChangeNotice(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendEmailChangeNotice
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+	user, err := processor.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Sent to the address captured when the change was requested, which is
+	// still the one the account owner controls.
+	subject := "Your Simple Bank email address is being changed"
+	content := fmt.Sprintf(`Hello %s<br/>
+	We received a request to change your email address to %s.<br/>
+	The change takes effect once the new address is confirmed.<br/>
+	If this wasn't you, reset your password and contact support.<br/>
+	`, user.FullName, payload.NewEmail)
+	to := []string{payload.OldEmail}
+
+	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	log.Info().
+		Str("task", task.Type()).
+		Bytes("payload", task.Payload()).
+		Str("email", payload.OldEmail).
+		Msg("processed task")
+
+	return nil
+}
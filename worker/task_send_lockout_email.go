@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskSendLockoutEmail = "task:send_lockout_email"
+
+type PayloadSendLockoutEmail struct {
+	Username    string    `json:"username"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func (distributor *AsynqTaskDistributor) DistributeTaskSendLockoutEmail(
+	ctx context.Context,
+	payload *PayloadSendLockoutEmail,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshall task payload: %w", err)
+	}
+
+	info, err := distributor.enqueueTask(ctx, TaskSendLockoutEmail, jsonPayload, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().
+		Str("type", TaskSendLockoutEmail).
+		Bytes("payload", jsonPayload).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("enqueued task")
+	return nil
+}
+
+func (processor *taskHandlers) ProcessTaskSendLockoutEmail(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendLockoutEmail
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+	user, err := processor.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	subject := "Your Simple Bank account is temporarily locked"
+	content := fmt.Sprintf(`Hello %s<br/>
+	We detected too many failed login attempts on your account.<br/>
+	Login is locked until %s.<br/>
+	If this wasn't you, we recommend resetting your password.<br/>
+	`, user.FullName, payload.LockedUntil.UTC().Format(time.RFC1123))
+	to := []string{user.Email}
+
+	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	log.Info().
+		Str("task", task.Type()).
+		Bytes("payload", task.Payload()).
+		Str("email", user.Email).
+		Msg("processed task")
+
+	return nil
+}
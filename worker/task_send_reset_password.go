@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskSendResetPassword = "task:send_reset_password"
+
+type PayloadSendResetPassword struct {
+	Username string `json:"username"`
+}
+
+func (distributor *AsynqTaskDistributor) DistributeTaskSendResetPassword(
+	ctx context.Context,
+	payload *PayloadSendResetPassword,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshall task payload: %w", err)
+	}
+
+	info, err := distributor.enqueueTask(ctx, TaskSendResetPassword, jsonPayload, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().
+		Str("type", TaskSendResetPassword).
+		Bytes("payload", jsonPayload).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("enqueued task")
+	return nil
+}
+
+func (processor *taskHandlers) ProcessTaskSendResetPassword(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendResetPassword
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+	user, err := processor.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	secretCode, err := util.GenerateSecretCode()
+	if err != nil {
+		return err
+	}
+
+	// only the code in the latest email is usable
+	if err := processor.store.InvalidateResetPasswords(ctx, user.Username); err != nil {
+		return fmt.Errorf("failed to invalidate reset passwords: %w", err)
+	}
+	resetPassword, err := processor.store.CreateResetPassword(ctx, db.CreateResetPasswordParams{
+		Username:   user.Username,
+		SecretCode: secretCode,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create reset password: %w", err)
+	}
+
+	subject := "Reset your Simple Bank password"
+	resetUrl := fmt.Sprintf("%s?reset_id=%d&secret_code=%s", processor.passwordResetURL, resetPassword.ID, resetPassword.SecretCode)
+	content := fmt.Sprintf(`Hello %s<br/>
+	We received a request to reset your password.<br/>
+	Please <a href="%s">click here </a> to choose a new password. The link expires in 15 minutes.<br/>
+	If you did not request this, you can ignore this email.<br/>
+	`, user.FullName, resetUrl)
+	to := []string{user.Email}
+
+	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	log.Info().
+		Str("task", task.Type()).
+		Bytes("payload", task.Payload()).
+		Str("email", user.Email).
+		Msg("processed task")
+
+	return nil
+}
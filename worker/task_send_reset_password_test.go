@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/labasubagia/simplebank/db/memstore"
+	db "github.com/labasubagia/simplebank/db/sqlc"
+	mock_mail "github.com/labasubagia/simplebank/mail/mock"
+	"github.com/labasubagia/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestProcessTaskSendResetPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mailer := mock_mail.NewMockEmailSender(ctrl)
+
+	store := memstore.NewStore()
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: util.RandomString(32),
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	old, err := store.CreateResetPassword(context.Background(), db.CreateResetPasswordParams{
+		Username:   user.Username,
+		SecretCode: util.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	passwordResetURL := "https://bank.example.com/reset_password"
+	var content string
+	mailer.EXPECT().
+		SendEmail(gomock.Any(), gomock.Any(), []string{user.Email}, gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ string, c string, _ []string, _ []string, _ []string, _ []string) error {
+			content = c
+			return nil
+		})
+
+	payload, err := json.Marshal(PayloadSendResetPassword{Username: user.Username})
+	require.NoError(t, err)
+	processor := &taskHandlers{store: store, mailer: mailer, passwordResetURL: passwordResetURL}
+	err = processor.ProcessTaskSendResetPassword(context.Background(), asynq.NewTask(TaskSendResetPassword, payload))
+	require.NoError(t, err)
+
+	_, err = store.UpdateResetPassword(context.Background(), db.UpdateResetPasswordParams{
+		ID:         old.ID,
+		SecretCode: old.SecretCode,
+	})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	require.Contains(t, content, fmt.Sprintf("%s?reset_id=%d&secret_code=", passwordResetURL, old.ID+1))
+}
@@ -15,9 +15,12 @@ const TaskSendVerifyEmail = "task:send_verify_email"
 
 type PayloadSendVerifyEmail struct {
 	Username string `json:"string"`
+	// Email is set when confirming a pending email change instead of the
+	// address the user signed up with.
+	Email string `json:"email,omitempty"`
 }
 
-func (distributor *RedisTaskDistributor) DistributeTaskVerifyEmail(
+func (distributor *AsynqTaskDistributor) DistributeTaskVerifyEmail(
 	ctx context.Context,
 	payload *PayloadSendVerifyEmail,
 	opts ...asynq.Option,
@@ -27,22 +30,21 @@ func (distributor *RedisTaskDistributor) DistributeTaskVerifyEmail(
 		return fmt.Errorf("failed to marshall task payload: %w", err)
 	}
 
-	task := asynq.NewTask(TaskSendVerifyEmail, jsonPayload, opts...)
-	info, err := distributor.client.EnqueueContext(ctx, task)
+	info, err := distributor.enqueueTask(ctx, TaskSendVerifyEmail, jsonPayload, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to enqueue task: %w", err)
 	}
 
 	log.Info().
-		Str("type", task.Type()).
-		Bytes("payload", task.Payload()).
+		Str("type", TaskSendVerifyEmail).
+		Bytes("payload", jsonPayload).
 		Str("queue", info.Queue).
 		Int("max_retry", info.MaxRetry).
 		Msg("enqueued task")
 	return nil
 }
 
-func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error {
+func (processor *taskHandlers) ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error {
 	var payload PayloadSendVerifyEmail
 	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
 		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
@@ -56,9 +58,17 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 
+	email := user.Email
+	if payload.Email != "" {
+		if user.PendingEmail.String != payload.Email {
+			return fmt.Errorf("pending email has changed: %w", asynq.SkipRetry)
+		}
+		email = payload.Email
+	}
+
 	verifyEmail, err := processor.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
 		Username:   user.Username,
-		Email:      user.Email,
+		Email:      email,
 		SecretCode: util.RandomString(32),
 	})
 	if err != nil {
@@ -71,7 +81,14 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 	Thank you for registering with us!<br/>
 	Please <a href="%s">click here </a> to verify email address.<br/>
 	`, user.FullName, verifyUrl)
-	to := []string{user.Email}
+	if payload.Email != "" {
+		subject = "Confirm your new Simple Bank email address"
+		content = fmt.Sprintf(`Hello %s<br/>
+	We received a request to change your email address to this one.<br/>
+	Please <a href="%s">click here </a> to confirm the change.<br/>
+	`, user.FullName, verifyUrl)
+	}
+	to := []string{email}
 
 	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
 	if err != nil {
@@ -81,7 +98,7 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 	log.Info().
 		Str("task", task.Type()).
 		Bytes("payload", task.Payload()).
-		Str("email", user.Email).
+		Str("email", email).
 		Msg("processed task")
 
 	return nil
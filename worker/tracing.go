@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/labasubagia/simplebank/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const messagingSystem = "asynq"
+
+// taskEnvelope carries the trace context of the enqueuing request next to
+// the task payload, since asynq v0.24 tasks have no headers.
+type taskEnvelope struct {
+	TraceContext map[string]string `json:"trace_context"`
+	Payload      json.RawMessage   `json:"payload"`
+}
+
+// enqueueTask enqueues payload inside a taskEnvelope, under a producer span
+// that becomes the parent of the span processing the task.
+func (distributor *AsynqTaskDistributor) enqueueTask(
+	ctx context.Context,
+	taskType string,
+	payload []byte,
+	opts ...asynq.Option,
+) (*asynq.TaskInfo, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "enqueue "+taskType,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem(messagingSystem),
+			semconv.MessagingOperationPublish,
+		),
+	)
+	defer span.End()
+
+	envelope, err := wrapTaskPayload(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := distributor.client.EnqueueContext(ctx, asynq.NewTask(taskType, envelope), opts...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(
+		semconv.MessagingDestinationName(info.Queue),
+		semconv.MessagingMessageID(info.ID),
+	)
+	return info, nil
+}
+
+func wrapTaskPayload(ctx context.Context, payload []byte) ([]byte, error) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	envelope, err := json.Marshal(taskEnvelope{TraceContext: carrier, Payload: payload})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshall task envelope: %w", err)
+	}
+	return envelope, nil
+}
+
+// unwrapTaskPayload returns the context carrying the enqueuer's trace and
+// the task with its original payload. Tasks enqueued before envelopes
+// existed are returned unchanged.
+func unwrapTaskPayload(ctx context.Context, task *asynq.Task) (context.Context, *asynq.Task) {
+	var envelope taskEnvelope
+	if err := json.Unmarshal(task.Payload(), &envelope); err != nil || envelope.Payload == nil {
+		return ctx, task
+	}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(envelope.TraceContext))
+	return ctx, asynq.NewTask(task.Type(), envelope.Payload)
+}
+
+// tracingMiddleware processes every task under a consumer span joined to
+// the trace of the request that enqueued it.
+func tracingMiddleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		ctx, task = unwrapTaskPayload(ctx, task)
+
+		attributes := []attribute.KeyValue{
+			semconv.MessagingSystem(messagingSystem),
+			semconv.MessagingOperationProcess,
+		}
+		if queue, ok := asynq.GetQueueName(ctx); ok {
+			attributes = append(attributes, semconv.MessagingSourceName(queue))
+		}
+		if taskID, ok := asynq.GetTaskID(ctx); ok {
+			attributes = append(attributes, semconv.MessagingMessageID(taskID))
+		}
+
+		ctx, span := tracing.Tracer().Start(ctx, "process "+task.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attributes...),
+		)
+		defer span.End()
+
+		err := next.ProcessTask(ctx, task)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	})
+}
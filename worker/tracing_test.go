@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	ctx, enqueueSpan := otel.Tracer("test").Start(context.Background(), "enqueue")
+	payload := []byte(`{"username":"alice"}`)
+	envelope, err := wrapTaskPayload(ctx, payload)
+	require.NoError(t, err)
+	enqueueSpan.End()
+
+	var processed *asynq.Task
+	var processedSpan trace.SpanContext
+	handler := tracingMiddleware(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		processed = task
+		processedSpan = trace.SpanContextFromContext(ctx)
+		return nil
+	}))
+
+	err = handler.ProcessTask(context.Background(), asynq.NewTask(TaskSendLockoutEmail, envelope))
+	require.NoError(t, err)
+	require.Equal(t, TaskSendLockoutEmail, processed.Type())
+	require.JSONEq(t, string(payload), string(processed.Payload()))
+	require.Equal(t, enqueueSpan.SpanContext().TraceID(), processedSpan.TraceID())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "process "+TaskSendLockoutEmail, spans[1].Name())
+	require.Equal(t, trace.SpanKindConsumer, spans[1].SpanKind())
+	require.Equal(t, enqueueSpan.SpanContext().SpanID(), spans[1].Parent().SpanID())
+
+	// tasks enqueued before the envelope existed are processed as they are
+	err = handler.ProcessTask(context.Background(), asynq.NewTask(TaskSendLockoutEmail, payload))
+	require.NoError(t, err)
+	require.Equal(t, payload, processed.Payload())
+}